@@ -1,12 +1,11 @@
 package main
 
 import (
-	"github.com/RozmiDan/url_shortener/internal/app"
-	"github.com/RozmiDan/url_shortener/internal/config"
+	"os"
+
+	"github.com/RozmiDan/url_shortener/internal/cli"
 )
 
 func main() {
-	cnfg := config.MustLoad()
-
-	app.Run(cnfg)
+	os.Exit(cli.Run(cli.DefaultEnv(), os.Args[1:]))
 }
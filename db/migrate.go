@@ -1,28 +1,248 @@
 package db
 
 import (
+	"context"
 	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/jackc/pgx"
-	"github.com/jackc/pgx/stdlib"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
 )
 
 //go:embed migrations/*.sql
 var embedMigrations embed.FS
 
-func SetupPostgres(conn pgx.ConnConfig, logger *slog.Logger) {
-	goose.SetBaseFS(embedMigrations)
-	if err := goose.SetDialect("postgres"); err != nil {
-		logger.Error("can't set dialect in goose", slog.Any("err", err))
-		os.Exit(1)
+// MigrationsDir - каталог миграций в репозитории, в нём migrate create
+// создаёт новые файлы.
+const MigrationsDir = "db/migrations"
+
+// Mode - что делать с непримененными миграциями при запуске сервера.
+type Mode string
+
+const (
+	// ModeAuto применяет миграции при запуске.
+	ModeAuto Mode = "auto"
+	// ModeCheck отказывается запускаться, если есть непримененные миграции:
+	// их применяет отдельный шаг развёртывания через migrate up.
+	ModeCheck Mode = "check"
+	// ModeIgnore запускает сервер, не глядя на миграции.
+	ModeIgnore Mode = "ignore"
+)
+
+var ErrPendingMigrations = errors.New("database has pending migrations")
+
+func ParseMode(s string) (Mode, error) {
+	switch mode := Mode(s); mode {
+	case ModeAuto, ModeCheck, ModeIgnore:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown migrations mode %q, expected auto, check or ignore", s)
+}
+
+// Migrator применяет встроенные в бинарник миграции. Изменяющие команды
+// берут advisory lock в Postgres, поэтому несколько экземпляров сервиса,
+// запущенных одновременно, применяют миграции по очереди, а не вместе.
+type Migrator struct {
+	provider *goose.Provider
+}
+
+// NewMigrator открывает соединение по тому же DSN, что использует хранилище.
+// lockTimeout - сколько ждать advisory lock, пока миграции применяет другой
+// экземпляр, 0 - значение goose по умолчанию.
+func NewMigrator(dsn string, lockTimeout time.Duration) (*Migrator, error) {
+	const op = "db.NewMigrator"
+
+	connConfig, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var lockOpts []lock.SessionLockerOption
+	if lockTimeout > 0 {
+		// Блокировка проверяется раз в секунду.
+		lockOpts = append(lockOpts, lock.WithLockTimeout(1, uint64(max(lockTimeout/time.Second, 1))))
+	}
+
+	locker, err := lock.NewPostgresSessionLocker(lockOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	migrations, err := fs.Sub(embedMigrations, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	db := stdlib.OpenDB(*connConfig)
+
+	provider, err := goose.NewProvider(goose.DialectPostgres, db, migrations,
+		goose.WithSessionLocker(locker),
+	)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Migrator{provider: provider}, nil
+}
+
+func (m *Migrator) Close() error {
+	return m.provider.Close()
+}
+
+// Up применяет все миграции, если version = 0, иначе - до version включительно.
+func (m *Migrator) Up(ctx context.Context, version int64) ([]*goose.MigrationResult, error) {
+	if version == 0 {
+		return m.provider.Up(ctx)
+	}
+	return m.provider.UpTo(ctx, version)
+}
+
+// Down откатывает последнюю миграцию, если version = 0, иначе - все
+// миграции новее version.
+func (m *Migrator) Down(ctx context.Context, version int64) ([]*goose.MigrationResult, error) {
+	if version == 0 {
+		res, err := m.provider.Down(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return []*goose.MigrationResult{res}, nil
+	}
+	return m.provider.DownTo(ctx, version)
+}
+
+// Redo откатывает и заново применяет последнюю миграцию.
+func (m *Migrator) Redo(ctx context.Context) ([]*goose.MigrationResult, error) {
+	down, err := m.provider.Down(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	up, err := m.provider.ApplyVersion(ctx, down.Source.Version, true)
+	if err != nil {
+		return []*goose.MigrationResult{down}, err
+	}
+
+	return []*goose.MigrationResult{down, up}, nil
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]*goose.MigrationStatus, error) {
+	return m.provider.Status(ctx)
+}
+
+func (m *Migrator) Version(ctx context.Context) (int64, error) {
+	return m.provider.GetDBVersion(ctx)
+}
+
+func (m *Migrator) HasPending(ctx context.Context) (bool, error) {
+	return m.provider.HasPending(ctx)
+}
+
+// Check возвращает ErrPendingMigrations, если в базе применены не все
+// миграции бинарника. Advisory lock не берёт.
+func (m *Migrator) Check(ctx context.Context) error {
+	pending, err := m.HasPending(ctx)
+	if err != nil {
+		return err
+	}
+	if pending {
+		return ErrPendingMigrations
+	}
+	return nil
+}
+
+// SetupPostgres выполняет то, что требует mode, при запуске сервера.
+func SetupPostgres(ctx context.Context, logger *slog.Logger, dsn string, mode Mode, lockTimeout time.Duration) error {
+	const op = "db.SetupPostgres"
+
+	if mode == ModeIgnore {
+		logger.Info("migrations are not checked at startup")
+		return nil
+	}
+
+	m, err := NewMigrator(dsn, lockTimeout)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer m.Close()
+
+	if mode == ModeCheck {
+		err := m.Check(ctx)
+		if errors.Is(err, ErrPendingMigrations) {
+			return fmt.Errorf("%s: %w, run \"migrate up\" first", op, err)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		return nil
 	}
 
-	db := stdlib.OpenDB(conn)
-	if err := goose.Up(db, "migrations"); err != nil {
-		logger.Error("can't setup migrations", slog.Any("err", err))
-		os.Exit(1)
+	results, err := m.Up(ctx, 0)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
 	}
+	for _, res := range results {
+		logger.Info("migration applied",
+			slog.String("migration", res.Source.Path),
+			slog.Duration("duration", res.Duration),
+		)
+	}
+
+	return nil
+}
+
+var migrationName = regexp.MustCompile(`^(\d+)_.*\.sql$`)
+
+// Create создаёт в dir пустую SQL-миграцию со следующим по порядку номером
+// и возвращает путь к ней.
+func Create(dir, name string) (string, error) {
+	const op = "db.Create"
+
+	name = strings.ToLower(strings.Join(strings.Fields(name), "_"))
+	if name == "" {
+		return "", fmt.Errorf("%s: empty migration name", op)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	var last int64
+	for _, e := range entries {
+		match := migrationName.FindStringSubmatch(e.Name())
+		if match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err == nil && version > last {
+			last = version
+		}
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%03d_%s.sql", last+1, name))
+	body := "-- +goose Up\n\n-- +goose Down\n"
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(body); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return path, nil
 }
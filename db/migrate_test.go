@@ -0,0 +1,40 @@
+package db_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/RozmiDan/url_shortener/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMode(t *testing.T) {
+	for _, s := range []string{"auto", "check", "ignore"} {
+		mode, err := db.ParseMode(s)
+		require.NoError(t, err)
+		assert.Equal(t, db.Mode(s), mode)
+	}
+
+	_, err := db.ParseMode("always")
+	assert.Error(t, err)
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "009_first.sql"), nil, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), nil, 0o644))
+
+	path, err := db.Create(dir, "Add Link Owner")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "010_add_link_owner.sql"), path)
+
+	body, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "-- +goose Up")
+	assert.Contains(t, string(body), "-- +goose Down")
+
+	_, err = db.Create(dir, "  ")
+	assert.Error(t, err)
+}
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE url
+    ADD COLUMN IF NOT EXISTS max_clicks BIGINT,
+    ADD COLUMN IF NOT EXISTS clicks_left BIGINT;
+
+-- +goose Down
+ALTER TABLE url
+    DROP COLUMN IF EXISTS clicks_left,
+    DROP COLUMN IF EXISTS max_clicks;
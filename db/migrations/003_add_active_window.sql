@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE url
+    ADD COLUMN IF NOT EXISTS active_from TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS active_until TIMESTAMPTZ;
+
+-- +goose Down
+ALTER TABLE url
+    DROP COLUMN IF EXISTS active_until,
+    DROP COLUMN IF EXISTS active_from;
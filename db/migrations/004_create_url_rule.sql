@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS url_rule(
+    id SERIAL PRIMARY KEY,
+    url_id INTEGER NOT NULL REFERENCES url(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    conditions JSONB NOT NULL DEFAULT '{}',
+    target_url TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS url_rule_url_id_position_idx ON url_rule(url_id, position);
+
+-- +goose Down
+DROP INDEX IF EXISTS url_rule_url_id_position_idx;
+DROP TABLE IF EXISTS url_rule;
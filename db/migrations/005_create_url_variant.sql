@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS url_variant(
+    id SERIAL PRIMARY KEY,
+    url_id INTEGER NOT NULL REFERENCES url(id) ON DELETE CASCADE,
+    target_url TEXT NOT NULL,
+    weight INTEGER NOT NULL CHECK (weight > 0),
+    clicks BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS url_variant_url_id_idx ON url_variant(url_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS url_variant_url_id_idx;
+DROP TABLE IF EXISTS url_variant;
@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE url
+    ADD COLUMN IF NOT EXISTS query_mode TEXT NOT NULL DEFAULT 'drop',
+    ADD COLUMN IF NOT EXISTS utm JSONB,
+    ADD COLUMN IF NOT EXISTS forward_path BOOLEAN NOT NULL DEFAULT false;
+
+-- +goose Down
+ALTER TABLE url
+    DROP COLUMN IF EXISTS forward_path,
+    DROP COLUMN IF EXISTS utm,
+    DROP COLUMN IF EXISTS query_mode;
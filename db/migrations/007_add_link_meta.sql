@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE url
+    ADD COLUMN IF NOT EXISTS title TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS image_url TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS preview BOOLEAN NOT NULL DEFAULT false;
+
+-- +goose Down
+ALTER TABLE url
+    DROP COLUMN IF EXISTS preview,
+    DROP COLUMN IF EXISTS image_url,
+    DROP COLUMN IF EXISTS description,
+    DROP COLUMN IF EXISTS title;
@@ -0,0 +1,16 @@
+-- +goose Up
+ALTER TABLE url
+    ADD COLUMN IF NOT EXISTS last_status INTEGER,
+    ADD COLUMN IF NOT EXISTS last_latency_ms BIGINT,
+    ADD COLUMN IF NOT EXISTS last_error TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS checked_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS url_checked_at_idx ON url(checked_at NULLS FIRST);
+
+-- +goose Down
+DROP INDEX IF EXISTS url_checked_at_idx;
+ALTER TABLE url
+    DROP COLUMN IF EXISTS checked_at,
+    DROP COLUMN IF EXISTS last_error,
+    DROP COLUMN IF EXISTS last_latency_ms,
+    DROP COLUMN IF EXISTS last_status;
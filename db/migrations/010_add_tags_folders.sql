@@ -0,0 +1,30 @@
+-- +goose Up
+ALTER TABLE url
+    ADD COLUMN IF NOT EXISTS folder TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS metadata JSONB NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS url_folder_idx ON url(folder) WHERE folder <> '';
+CREATE INDEX IF NOT EXISTS url_metadata_idx ON url USING GIN(metadata jsonb_path_ops);
+
+CREATE TABLE IF NOT EXISTS tag(
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS url_tag(
+    url_id INTEGER NOT NULL REFERENCES url(id) ON DELETE CASCADE,
+    tag_id INTEGER NOT NULL REFERENCES tag(id) ON DELETE CASCADE,
+    PRIMARY KEY (url_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS url_tag_tag_id_idx ON url_tag(tag_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS url_tag_tag_id_idx;
+DROP TABLE IF EXISTS url_tag;
+DROP TABLE IF EXISTS tag;
+DROP INDEX IF EXISTS url_metadata_idx;
+DROP INDEX IF EXISTS url_folder_idx;
+ALTER TABLE url
+    DROP COLUMN IF EXISTS metadata,
+    DROP COLUMN IF EXISTS folder;
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE url
+    ADD COLUMN IF NOT EXISTS redirect_code SMALLINT NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE url
+    DROP COLUMN IF EXISTS redirect_code;
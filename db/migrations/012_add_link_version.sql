@@ -0,0 +1,9 @@
+-- +goose Up
+-- version увеличивается при каждом изменении ссылки через API и служит
+-- для оптимистической блокировки (ETag / If-Match).
+ALTER TABLE url
+    ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+
+-- +goose Down
+ALTER TABLE url
+    DROP COLUMN IF EXISTS version;
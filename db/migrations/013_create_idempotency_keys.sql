@@ -0,0 +1,21 @@
+-- +goose Up
+-- Ответы на запросы с заголовком Idempotency-Key. status = 0 означает, что
+-- запрос ещё выполняется; locked_until ограничивает такую блокировку, если
+-- экземпляр сервиса упал, не дописав ответ.
+CREATE TABLE IF NOT EXISTS idempotency_key(
+    key TEXT PRIMARY KEY,
+    request_hash TEXT NOT NULL,
+    status INTEGER NOT NULL DEFAULT 0,
+    content_type TEXT NOT NULL DEFAULT '',
+    body BYTEA,
+    locked_until TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_key_expires_idx
+    ON idempotency_key(expires_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idempotency_key_expires_idx;
+DROP TABLE IF EXISTS idempotency_key;
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE url
+    ADD COLUMN IF NOT EXISTS checking_until TIMESTAMPTZ;
+
+-- +goose Down
+ALTER TABLE url
+    DROP COLUMN IF EXISTS checking_until;
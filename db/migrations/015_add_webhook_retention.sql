@@ -0,0 +1,7 @@
+-- +goose Up
+CREATE INDEX IF NOT EXISTS webhook_delivery_event_idx ON webhook_delivery(event_id);
+CREATE INDEX IF NOT EXISTS webhook_outbox_created_at_idx ON webhook_outbox(created_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS webhook_outbox_created_at_idx;
+DROP INDEX IF EXISTS webhook_delivery_event_idx;
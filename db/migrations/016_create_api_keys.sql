@@ -0,0 +1,14 @@
+-- +goose Up
+-- Ключи доступа к API. Хранится только sha256 ключа; prefix - первые
+-- символы ключа, чтобы его можно было опознать в списке.
+CREATE TABLE IF NOT EXISTS api_key(
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL,
+    key_hash TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    revoked_at TIMESTAMPTZ
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS api_key;
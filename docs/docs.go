@@ -15,9 +15,214 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/url": {
+        "/api/v1/tags": {
+            "get": {
+                "description": "Return all tags with the number of links carrying each of them",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tags"
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/tags_handler.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "internal",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/tags/merge": {
+            "post": {
+                "description": "Replace source tags with the target tag on all links at once and delete the source tags",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tags"
+                ],
+                "parameters": [
+                    {
+                        "description": "Source and target tags",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tags_handler.MergeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/tags_handler.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "bad_request or validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "tag_not_found: none of the source tags exist",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/tags/{tag}/rename": {
+            "post": {
+                "description": "Rename a tag on all links at once",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tags"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Current tag name",
+                        "name": "tag",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New tag name",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tags_handler.RenameRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/tags_handler.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "bad_request or validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "tag_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "tag_exists: use merge",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/url": {
+            "get": {
+                "description": "Return links filtered by tags (all must match), folder, metadata and a substring of alias or URL.\nMetadata filters are passed as meta.\u003ckey\u003e=\u003cvalue\u003e and match string values.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "url"
+                ],
+                "parameters": [
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Tag, may be repeated",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Folder",
+                        "name": "folder",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Substring of alias or URL",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size, 50 by default, at most 500",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page offset",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/list_handler.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "bad_request: invalid query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    }
+                }
+            },
             "post": {
-                "description": "Creates a short URL. If alias is not specified, a random string of 6 characters is generated.",
+                "description": "Creates a short URL. If alias is not specified, a random string of 6 characters is generated.\nAn alias that is already taken is rejected with 409.\nIf max_clicks is set, the link stops working after that many redirects.\nactive_from and active_until limit the time window in which the link works.\nvariants split traffic between several URLs by weight (A/B test).\nquery_mode (drop, merge, override) controls incoming query parameters on redirect,\nutm parameters are appended to every redirect, forward_path enables /{alias}/rest/of/path.\ntitle, description and image are shown on the preview page and in Open Graph tags,\npreview makes /{alias} always return the preview page. Alias must not contain \"+\".\ntags, folder and free-form metadata organize links and are used as list filters.\nredirect_code (301, 302, 307, 308) makes /{alias} answer with a real HTTP redirect.\nWith Idempotency-Key a retried request gets the stored response instead of creating\nanother link; reusing the key with a different body is rejected with 422.",
                 "consumes": [
                     "application/json"
                 ],
@@ -29,6 +234,12 @@ const docTemplate = `{
                 ],
                 "summary": "Creates a short URL",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Client-generated key that makes retries safe",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
                     {
                         "description": "URL Saving Parameters",
                         "name": "Request",
@@ -47,29 +258,98 @@ const docTemplate = `{
                         }
                     },
                     "400": {
-                        "description": "invalid request parameters",
+                        "description": "bad_request or validation_failed with the invalid fields",
                         "schema": {
-                            "$ref": "#/definitions/save_handler.Response"
+                            "$ref": "#/definitions/apierr.Problem"
                         }
                     },
                     "409": {
-                        "description": "URL already exists",
+                        "description": "alias_exists or request_in_progress",
                         "schema": {
-                            "$ref": "#/definitions/save_handler.Response"
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "idempotency_key_reused",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
                         }
                     },
                     "500": {
-                        "description": "Internal server error",
+                        "description": "internal",
                         "schema": {
-                            "$ref": "#/definitions/save_handler.Response"
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/url/broken": {
+            "get": {
+                "description": "Return links whose target URL failed the last health check",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "url"
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/broken_handler.Response"
+                        }
+                    },
+                    "500": {
+                        "description": "internal",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
                         }
                     }
                 }
             }
         },
-        "/url/{alias}": {
+        "/api/v1/url/{alias}": {
+            "get": {
+                "description": "Return all mutable fields of the link. The ETag header is used with If-Match in PATCH.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "url"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL alias",
+                        "name": "alias",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/update_handler.LinkResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "link_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    }
+                }
+            },
             "put": {
-                "description": "Update existing short URL alias",
+                "description": "Update existing short URL alias, its activation window, tags, folder and metadata.\nDeprecated: use PATCH /url/{alias}, which can change every field of the link.",
                 "consumes": [
                     "application/json"
                 ],
@@ -79,6 +359,7 @@ const docTemplate = `{
                 "tags": [
                     "url"
                 ],
+                "deprecated": true,
                 "parameters": [
                     {
                         "type": "string",
@@ -87,6 +368,12 @@ const docTemplate = `{
                         "in": "path",
                         "required": true
                     },
+                    {
+                        "type": "string",
+                        "description": "ETag of the link version being updated",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
                     {
                         "description": "New alias data",
                         "name": "input",
@@ -105,33 +392,39 @@ const docTemplate = `{
                         }
                     },
                     "400": {
-                        "description": "Invalid input data",
+                        "description": "bad_request or validation_failed",
                         "schema": {
-                            "$ref": "#/definitions/update_handler.Response"
+                            "$ref": "#/definitions/apierr.Problem"
                         }
                     },
                     "404": {
-                        "description": "Alias not found",
+                        "description": "link_not_found",
                         "schema": {
-                            "$ref": "#/definitions/update_handler.Response"
+                            "$ref": "#/definitions/apierr.Problem"
                         }
                     },
                     "409": {
-                        "description": "New alias already exists",
+                        "description": "alias_exists",
                         "schema": {
-                            "$ref": "#/definitions/update_handler.Response"
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "version_conflict",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
                         }
                     },
                     "500": {
-                        "description": "Internal server error",
+                        "description": "internal",
                         "schema": {
-                            "$ref": "#/definitions/update_handler.Response"
+                            "$ref": "#/definitions/apierr.Problem"
                         }
                     }
                 }
             },
             "delete": {
-                "description": "Delete existing short URL",
+                "description": "Delete existing short URL. If-Match or expected_version makes the deletion fail with 412\nif the link was changed since that version.",
                 "consumes": [
                     "application/json"
                 ],
@@ -148,6 +441,18 @@ const docTemplate = `{
                         "name": "alias",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the link version being deleted",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Version of the link being deleted",
+                        "name": "expected_version",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -157,24 +462,34 @@ const docTemplate = `{
                             "$ref": "#/definitions/delete_handler.Response"
                         }
                     },
+                    "400": {
+                        "description": "bad_request: invalid expected_version",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    },
                     "404": {
-                        "description": "Alias not found",
+                        "description": "link_not_found",
                         "schema": {
-                            "$ref": "#/definitions/delete_handler.Response"
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "version_conflict",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
                         }
                     },
                     "500": {
-                        "description": "Internal server error",
+                        "description": "internal",
                         "schema": {
-                            "$ref": "#/definitions/delete_handler.Response"
+                            "$ref": "#/definitions/apierr.Problem"
                         }
                     }
                 }
-            }
-        },
-        "/{alias}": {
-            "get": {
-                "description": "Return URL for redirect to original by short alias",
+            },
+            "patch": {
+                "description": "Apply a JSON Merge Patch (RFC 7396) to the link: url, alias, max_clicks, active_from, active_until,\nquery_mode, utm, forward_path, title, description, image, preview, tags, folder, metadata, redirect_code.\nnull removes the value. The result is validated by the same rules as POST /url and saved in one transaction.\nIf-Match with the ETag from GET /url/{alias} (or \"expected_version\" in the patch) makes the update fail\nwith 412 if the link was changed meanwhile.",
                 "consumes": [
                     "application/json"
                 ],
@@ -182,7 +497,7 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "redirect"
+                    "url"
                 ],
                 "parameters": [
                     {
@@ -191,36 +506,1088 @@ const docTemplate = `{
                         "name": "alias",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the link version being patched",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Merge patch",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/save_handler.Request"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Redirect to original URL",
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/update_handler.LinkResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "bad_request or validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
                         }
                     },
                     "404": {
-                        "description": "Alias not found",
+                        "description": "link_not_found",
                         "schema": {
-                            "$ref": "#/definitions/redirect_handler.Response"
+                            "$ref": "#/definitions/apierr.Problem"
                         }
                     },
-                    "500": {
-                        "description": "Internal server error",
+                    "409": {
+                        "description": "alias_exists",
                         "schema": {
-                            "$ref": "#/definitions/redirect_handler.Response"
+                            "$ref": "#/definitions/apierr.Problem"
                         }
+                    },
+                    "412": {
+                        "description": "version_conflict",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/url/{alias}/rules": {
+            "get": {
+                "description": "Return ordered smart redirect rules of the link.\nThe ETag header is the link version, used with If-Match when changing the rules.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rules"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL alias",
+                        "name": "alias",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/rules_handler.ListResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "link_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Replace all smart redirect rules of the link with a new ordered list.\nIf-Match (or \"expected_version\") makes the change fail with 412 if the link was changed meanwhile.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rules"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL alias",
+                        "name": "alias",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the link version being changed",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Ordered rules",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/rules_handler.ReplaceRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/rules_handler.ListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "bad_request or validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "link_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "version_conflict",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Append a smart redirect rule to the end of the link rules.\nIf-Match (or \"expected_version\") makes the change fail with 412 if the link was changed meanwhile.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rules"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL alias",
+                        "name": "alias",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the link version being changed",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Rule",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/rules_handler.Request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/rules_handler.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "bad_request or validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "link_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "version_conflict",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/url/{alias}/rules/{id}": {
+            "put": {
+                "description": "Update conditions and target of a smart redirect rule.\nIf-Match (or \"expected_version\") makes the change fail with 412 if the link was changed meanwhile.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rules"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL alias",
+                        "name": "alias",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Rule id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the link version being changed",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Rule",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/rules_handler.Request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/rules_handler.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "bad_request or validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "link_not_found or rule_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "version_conflict",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a smart redirect rule.\nIf-Match makes the change fail with 412 if the link was changed meanwhile.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rules"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL alias",
+                        "name": "alias",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Rule id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the link version being changed",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/rules_handler.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "bad_request: invalid rule id",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "link_not_found or rule_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "version_conflict",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/url/{alias}/variants": {
+            "get": {
+                "description": "Return A/B variants of the link with the number of redirects served by each",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "url"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL alias",
+                        "name": "alias",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/variants_handler.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "link_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/webhooks": {
+            "get": {
+                "description": "Return all webhook subscriptions",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/webhooks_handler.ListResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "internal",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Subscribe a URL to link lifecycle and click events. At least one event is required\nThe URL must use http or https and point to a public address",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "parameters": [
+                    {
+                        "description": "Webhook subscription",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/webhooks_handler.Request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/webhooks_handler.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "bad_request or validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/webhooks/dead-letters": {
+            "get": {
+                "description": "Return webhook deliveries that failed after all retry attempts",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/webhooks_handler.DeadLetterResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "internal",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/webhooks/dead-letters/{id}/retry": {
+            "post": {
+                "description": "Put a failed webhook delivery back into the delivery queue",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Delivery id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/webhooks_handler.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "bad_request: invalid delivery id",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "delivery_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/webhooks/{id}": {
+            "get": {
+                "description": "Return a webhook subscription",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/webhooks_handler.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "bad_request: invalid webhook id",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "webhook_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Replace URL, event filter and state of a webhook subscription. Empty secret keeps the current one\nThe URL must use http or https and point to a public address",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Webhook subscription",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/webhooks_handler.Request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/webhooks_handler.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "bad_request or validation_failed",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "webhook_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a webhook subscription together with its pending deliveries",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/webhooks_handler.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "bad_request: invalid webhook id",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "webhook_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/{alias}": {
+            "get": {
+                "description": "Return URL for redirect to original by short alias.\nLink rules are evaluated in order, the first matching rule overrides the URL.\nOtherwise, if the link has A/B variants, one is picked by weight and kept per visitor.\nQuery parameters, UTM tags and /{alias}/rest/of/path suffix are applied per link options.\n/{alias}+ or the link preview flag returns an HTML preview page with Open Graph tags.\nA preview does not count as a click and does not pick an A/B variant.\nThe preview Continue button leads to /{alias}?go=1, which counts the click and redirects.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "text/html"
+                ],
+                "tags": [
+                    "redirect"
+                ],
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Short URL alias",
+                        "name": "alias",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "1 - follow the link from its preview page",
+                        "name": "go",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Redirect to original URL",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "301": {
+                        "description": "Redirect with the link redirect code (301, 302, 307 or 308)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "bad_request: invalid path suffix",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "link_not_active (status is configurable)",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "link_not_found",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    },
+                    "410": {
+                        "description": "link_exhausted or link_expired",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal",
+                        "schema": {
+                            "$ref": "#/definitions/apierr.Problem"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "apierr.Code": {
+            "type": "string",
+            "enum": [
+                "bad_request",
+                "request_too_large",
+                "not_found",
+                "method_not_allowed",
+                "not_acceptable",
+                "validation_failed",
+                "link_not_found",
+                "alias_exists",
+                "aliases_exhausted",
+                "version_conflict",
+                "link_not_active",
+                "link_expired",
+                "link_exhausted",
+                "rule_not_found",
+                "tag_not_found",
+                "tag_exists",
+                "webhook_not_found",
+                "delivery_not_found",
+                "request_in_progress",
+                "idempotency_key_reused",
+                "timeout",
+                "canceled",
+                "internal"
+            ],
+            "x-enum-varnames": [
+                "CodeBadRequest",
+                "CodeRequestTooLarge",
+                "CodeNotFound",
+                "CodeMethodNotAllowed",
+                "CodeNotAcceptable",
+                "CodeValidationFailed",
+                "CodeLinkNotFound",
+                "CodeAliasExists",
+                "CodeAliasesExhausted",
+                "CodeVersionConflict",
+                "CodeLinkNotActive",
+                "CodeLinkExpired",
+                "CodeLinkExhausted",
+                "CodeRuleNotFound",
+                "CodeTagNotFound",
+                "CodeTagExists",
+                "CodeWebhookNotFound",
+                "CodeDeliveryNotFound",
+                "CodeRequestInProgress",
+                "CodeIdempotencyKeyReused",
+                "CodeTimeout",
+                "CodeCanceled",
+                "CodeInternal"
+            ]
+        },
+        "apierr.FieldError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string",
+                    "example": "url"
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "must be a valid URL"
+                }
+            }
+        },
+        "apierr.Problem": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/apierr.Code"
+                        }
+                    ],
+                    "example": "link_not_found"
+                },
+                "detail": {
+                    "type": "string",
+                    "example": "alias not found"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/apierr.FieldError"
+                    }
+                },
+                "instance": {
+                    "description": "Instance - request id запроса, он же в логах сервера.",
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 404
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Link not found"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "urn:url-shortener:problem:link_not_found"
+                }
+            }
+        },
+        "broken_handler.Response": {
+            "type": "object",
+            "properties": {
+                "links": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/storage.BrokenLink"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "delete_handler.Response": {
+            "type": "object",
+            "properties": {
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "list_handler.Response": {
+            "type": "object",
+            "properties": {
+                "links": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/storage.LinkInfo"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "rules_handler.ListResponse": {
+            "type": "object",
+            "properties": {
+                "rules": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/storage.Rule"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "rules_handler.ReplaceRequest": {
+            "type": "object",
+            "properties": {
+                "expected_version": {
+                    "description": "ExpectedVersion - то же, что в Request.",
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "rules": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/rules_handler.Rule"
+                    }
+                }
+            }
+        },
+        "rules_handler.Request": {
+            "type": "object",
+            "required": [
+                "target_url"
+            ],
+            "properties": {
+                "conditions": {
+                    "$ref": "#/definitions/storage.RuleConditions"
+                },
+                "expected_version": {
+                    "description": "ExpectedVersion - версия ссылки, которую клиент собирается изменить,\nто же, что If-Match. 0 - без проверки.",
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "target_url": {
+                    "type": "string"
+                }
+            }
+        },
+        "rules_handler.Response": {
+            "type": "object",
+            "properties": {
+                "rule": {
+                    "$ref": "#/definitions/storage.Rule"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "rules_handler.Rule": {
+            "type": "object",
+            "required": [
+                "target_url"
+            ],
+            "properties": {
+                "conditions": {
+                    "$ref": "#/definitions/storage.RuleConditions"
+                },
+                "target_url": {
+                    "type": "string"
+                }
+            }
+        },
+        "save_handler.Request": {
+            "type": "object",
+            "required": [
+                "url"
+            ],
+            "properties": {
+                "active_from": {
+                    "type": "string"
+                },
+                "active_until": {
+                    "type": "string"
+                },
+                "alias": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string",
+                    "maxLength": 1000
+                },
+                "folder": {
+                    "type": "string",
+                    "maxLength": 100
+                },
+                "forward_path": {
+                    "type": "boolean"
+                },
+                "image": {
+                    "type": "string"
+                },
+                "max_clicks": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "metadata": {
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "preview": {
+                    "type": "boolean"
+                },
+                "query_mode": {
+                    "type": "string",
+                    "enum": [
+                        "drop",
+                        "merge",
+                        "override"
+                    ]
+                },
+                "redirect_code": {
+                    "type": "integer",
+                    "enum": [
+                        301,
+                        302,
+                        307,
+                        308
+                    ]
+                },
+                "tags": {
+                    "type": "array",
+                    "maxItems": 20,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "title": {
+                    "type": "string",
+                    "maxLength": 200
+                },
+                "url": {
+                    "type": "string"
+                },
+                "utm": {
+                    "$ref": "#/definitions/save_handler.UTM"
+                },
+                "variants": {
+                    "type": "array",
+                    "maxItems": 10,
+                    "items": {
+                        "$ref": "#/definitions/save_handler.Variant"
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "delete_handler.Response": {
+        },
+        "save_handler.Response": {
             "type": "object",
             "properties": {
-                "error": {
+                "alias": {
                     "type": "string"
                 },
                 "status": {
@@ -228,64 +1595,436 @@ const docTemplate = `{
                 }
             }
         },
-        "redirect_handler.Response": {
+        "save_handler.UTM": {
             "type": "object",
             "properties": {
-                "error": {
+                "campaign": {
                     "type": "string"
                 },
-                "status": {
+                "content": {
                     "type": "string"
                 },
-                "url": {
+                "medium": {
+                    "type": "string"
+                },
+                "source": {
+                    "type": "string"
+                },
+                "term": {
                     "type": "string"
                 }
             }
         },
-        "save_handler.Request": {
+        "save_handler.Variant": {
             "type": "object",
             "required": [
                 "url"
             ],
+            "properties": {
+                "url": {
+                    "type": "string"
+                },
+                "weight": {
+                    "type": "integer",
+                    "minimum": 1
+                }
+            }
+        },
+        "storage.BrokenLink": {
+            "type": "object",
             "properties": {
                 "alias": {
                     "type": "string"
                 },
+                "checked_at": {
+                    "type": "string"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "last_status": {
+                    "type": "integer"
+                },
+                "latency_ms": {
+                    "type": "integer"
+                },
                 "url": {
                     "type": "string"
                 }
             }
         },
-        "save_handler.Response": {
+        "storage.Delivery": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "event_id": {
+                    "type": "integer"
+                },
+                "event_type": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "last_status": {
+                    "type": "integer"
+                },
+                "payload": {
+                    "type": "object"
+                },
+                "state": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                },
+                "webhook_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "storage.LinkInfo": {
             "type": "object",
             "properties": {
                 "alias": {
                     "type": "string"
                 },
-                "error": {
+                "folder": {
+                    "type": "string"
+                },
+                "metadata": {
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "storage.Rule": {
+            "type": "object",
+            "properties": {
+                "conditions": {
+                    "$ref": "#/definitions/storage.RuleConditions"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "target_url": {
+                    "type": "string"
+                }
+            }
+        },
+        "storage.RuleConditions": {
+            "type": "object",
+            "properties": {
+                "countries": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "devices": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "languages": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "os": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "query": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "time_from": {
+                    "description": "TimeFrom и TimeTo - ежедневный интервал в формате \"15:04\" по UTC.",
+                    "type": "string"
+                },
+                "time_to": {
+                    "type": "string"
+                }
+            }
+        },
+        "storage.Tag": {
+            "type": "object",
+            "properties": {
+                "links": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "storage.Variant": {
+            "type": "object",
+            "properties": {
+                "clicks": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "url": {
+                    "type": "string"
+                },
+                "weight": {
+                    "type": "integer"
+                }
+            }
+        },
+        "storage.Webhook": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "tags_handler.MergeRequest": {
+            "type": "object",
+            "required": [
+                "sources",
+                "target"
+            ],
+            "properties": {
+                "sources": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "target": {
+                    "type": "string",
+                    "maxLength": 50
+                }
+            }
+        },
+        "tags_handler.RenameRequest": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "maxLength": 50
+                }
+            }
+        },
+        "tags_handler.Response": {
+            "type": "object",
+            "properties": {
+                "status": {
                     "type": "string"
                 },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/storage.Tag"
+                    }
+                }
+            }
+        },
+        "update_handler.LinkResponse": {
+            "type": "object",
+            "properties": {
+                "link": {
+                    "$ref": "#/definitions/save_handler.Request"
+                },
                 "status": {
                     "type": "string"
+                },
+                "version": {
+                    "type": "integer"
                 }
             }
         },
         "update_handler.Request": {
             "type": "object",
             "properties": {
+                "expected_version": {
+                    "description": "ExpectedVersion - версия ссылки, которую клиент собирается изменить,\nто же, что If-Match. 0 - без проверки.",
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "folder": {
+                    "type": "string",
+                    "maxLength": 100
+                },
+                "metadata": {
+                    "type": "object",
+                    "additionalProperties": {}
+                },
                 "newAlias": {
                     "type": "string"
+                },
+                "tags": {
+                    "description": "Tags, Folder и Metadata заменяют значения целиком, отсутствующее\nполе не меняется.",
+                    "type": "array",
+                    "maxItems": 20,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "window": {
+                    "$ref": "#/definitions/update_handler.Window"
                 }
             }
         },
         "update_handler.Response": {
             "type": "object",
             "properties": {
-                "error": {
+                "status": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "update_handler.Window": {
+            "type": "object",
+            "properties": {
+                "active_from": {
+                    "type": "string"
+                },
+                "active_until": {
+                    "type": "string"
+                }
+            }
+        },
+        "variants_handler.Response": {
+            "type": "object",
+            "properties": {
+                "status": {
+                    "type": "string"
+                },
+                "variants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/storage.Variant"
+                    }
+                }
+            }
+        },
+        "webhooks_handler.DeadLetterResponse": {
+            "type": "object",
+            "properties": {
+                "deliveries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/storage.Delivery"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "webhooks_handler.ListResponse": {
+            "type": "object",
+            "properties": {
+                "status": {
+                    "type": "string"
+                },
+                "webhooks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/storage.Webhook"
+                    }
+                }
+            }
+        },
+        "webhooks_handler.Request": {
+            "type": "object",
+            "required": [
+                "events",
+                "url"
+            ],
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "events": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "secret": {
+                    "description": "Secret - ключ подписи HMAC, если не задан при создании, генерируется.",
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "webhooks_handler.Response": {
+            "type": "object",
+            "properties": {
+                "secret": {
+                    "description": "Secret возвращается только при создании подписки.",
                     "type": "string"
                 },
                 "status": {
                     "type": "string"
+                },
+                "webhook": {
+                    "$ref": "#/definitions/storage.Webhook"
                 }
             }
         }
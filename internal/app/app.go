@@ -2,8 +2,10 @@ package app
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
-	"net/http"
+	"net"
 	"os"
 	"os/signal"
 	"syscall"
@@ -12,69 +14,291 @@ import (
 	"github.com/RozmiDan/url_shortener/db"
 
 	"github.com/RozmiDan/url_shortener/internal/config"
+	"github.com/RozmiDan/url_shortener/internal/health"
+	middleware_idempotency "github.com/RozmiDan/url_shortener/internal/http-server/middleware/idempotency"
 	"github.com/RozmiDan/url_shortener/internal/http-server/server"
+	"github.com/RozmiDan/url_shortener/internal/lifecycle"
 	"github.com/RozmiDan/url_shortener/internal/metrics"
 	"github.com/RozmiDan/url_shortener/internal/storage/postgre"
+	"github.com/RozmiDan/url_shortener/internal/usecase/checker"
+	"github.com/RozmiDan/url_shortener/internal/usecase/webhook"
 	"github.com/RozmiDan/url_shortener/pkg/logger"
-	"github.com/jackc/pgx"
 )
 
-func Run(cnfg *config.Config) {
+// Storage - хранилище, с которым работают сервер и фоновые процессы.
+type Storage interface {
+	server.DataBase
+	checker.Storage
+	webhook.Storage
+	Ping(ctx context.Context) error
+}
+
+// Options - зависимости App, которые можно подменить, например в тестах.
+type Options struct {
+	// Logger, nil - логгер для cnfg.Env.
+	Logger *slog.Logger
+	// Storage, nil - Postgres из конфигурации с миграциями по
+	// migrations.mode. Переданное хранилище App не закрывает.
+	Storage Storage
+	// Listener и AdminListener, nil - адреса http.port и admin.port.
+	Listener      net.Listener
+	AdminListener net.Listener
+}
+
+// App - сервер вместе с фоновыми процессами.
+type App struct {
+	logger     *slog.Logger
+	components *lifecycle.Manager
+}
+
+// New подключается к хранилищу и собирает компоненты сервиса, но ничего
+// не запускает.
+func New(ctx context.Context, cnfg *config.Config, opts Options) (*App, error) {
+	const op = "app.New"
+
+	log := opts.Logger
+	if log == nil {
+		log = logger.NewLogger(cnfg.Env)
+	}
+
+	log.Info("url-shortner started")
+	log.Debug("debug mode")
+
+	components := lifecycle.New(log)
+	readiness := health.New(cnfg.Health.CheckTimeout)
+
+	metrics.RegisterMetrics()
+
+	storage := opts.Storage
+	if storage == nil {
+		pg, err := setupPostgres(ctx, cnfg, log, components, readiness)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		storage = pg
+	} else {
+		// Точка отсчёта зависимостей, закрывает хранилище вызывающий.
+		components.Add(lifecycle.Component{Name: "storage"})
+		readiness.Register("storage", storage.Ping)
+	}
+
+	components.Add(components.Server("admin", server.InitAdminServer(cnfg, log, readiness), opts.AdminListener, "storage"))
+
+	registerWorker := readiness.RegisterInfo
+	if cnfg.Health.WorkersAffectReadiness {
+		registerWorker = readiness.Register
+	}
 
-	logger := logger.NewLogger(cnfg.Env)
+	if cnfg.Checker.Enabled {
+		heartbeat := health.NewHeartbeat(cnfg.Health.WorkerStaleAfter)
+		registerWorker("checker", heartbeat.Check)
 
-	logger.Info("url-shortner started")
-	logger.Debug("debug mode")
+		linkChecker := checker.New(log, storage, checker.Config{
+			Interval:        cnfg.Checker.Interval,
+			RecheckAfter:    cnfg.Checker.RecheckAfter,
+			Timeout:         cnfg.Checker.Timeout,
+			PerHostInterval: cnfg.Checker.PerHostInterval,
+			Workers:         cnfg.Checker.Workers,
+			BatchSize:       cnfg.Checker.BatchSize,
+			AllowPrivate:    cnfg.Checker.AllowPrivate,
+			Heartbeat:       heartbeat.Beat,
+		})
+		components.Add(components.Worker("checker", linkChecker.Run, "storage"))
+	}
+
+	if cnfg.Webhooks.Enabled {
+		heartbeat := health.NewHeartbeat(cnfg.Health.WorkerStaleAfter)
+		registerWorker("webhooks", heartbeat.Check)
+
+		dispatcher := webhook.New(log, storage, webhook.Config{
+			Interval:     cnfg.Webhooks.Interval,
+			Timeout:      cnfg.Webhooks.Timeout,
+			MaxAttempts:  cnfg.Webhooks.MaxAttempts,
+			BaseBackoff:  cnfg.Webhooks.BaseBackoff,
+			MaxBackoff:   cnfg.Webhooks.MaxBackoff,
+			BatchSize:    cnfg.Webhooks.BatchSize,
+			AllowPrivate: cnfg.Webhooks.AllowPrivate,
+			Heartbeat:    heartbeat.Beat,
+		})
+		components.Add(components.Worker("webhooks", dispatcher.Run, "storage"))
+	}
 
-	pgxConf := pgx.ConnConfig{
-		Host:     cnfg.PostgreURL.Host,
-		Port:     cnfg.PostgreURL.Port,
-		Database: cnfg.PostgreURL.Database,
-		User:     cnfg.PostgreURL.User,
-		Password: cnfg.PostgreURL.Password,
+	if cnfg.Webhooks.PurgeInterval > 0 && cnfg.Webhooks.Retention > 0 {
+		components.Add(components.Worker("webhooks-purge", func(ctx context.Context) {
+			webhook.RunPurge(ctx, log, storage, cnfg.Webhooks.PurgeInterval, cnfg.Webhooks.Retention)
+		}, "storage"))
 	}
 
-	db.SetupPostgres(pgxConf, logger)
-	logger.Info("Migrations completed successfully\n")
+	if cnfg.Idempotency.PurgeInterval > 0 {
+		components.Add(components.Worker("idempotency-purge", func(ctx context.Context) {
+			middleware_idempotency.RunPurge(ctx, log, storage, cnfg.Idempotency.PurgeInterval)
+		}, "storage"))
+	}
+
+	components.Add(components.Server("public", server.InitServer(cnfg, log, storage), opts.Listener, "storage"))
+
+	// Останавливается первым: /readyz сразу отвечает 503, а запросы
+	// обслуживаются ещё DrainDelay, пока балансировщик не исключит экземпляр.
+	components.Add(lifecycle.Component{
+		Name:      "drain",
+		DependsOn: []string{"public", "admin"},
+		Stop: func(ctx context.Context) error {
+			readiness.Drain()
+			log.Info("Draining", slog.Duration("delay", cnfg.Health.DrainDelay))
+
+			select {
+			case <-time.After(cnfg.Health.DrainDelay):
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	})
+
+	return &App{logger: log, components: components}, nil
+}
+
+// setupPostgres подключается к Postgres, проверяет или применяет миграции
+// и добавляет в components закрытие подключений.
+func setupPostgres(
+	ctx context.Context,
+	cnfg *config.Config,
+	logger *slog.Logger,
+	components *lifecycle.Manager,
+	readiness *health.Health,
+) (*postgre.Storage, error) {
+	migrationsMode, err := db.ParseMode(cnfg.Migrations.Mode)
+	if err != nil {
+		return nil, err
+	}
 
-	storage, err := postgre.New(cnfg.PostgreURL.URL)
+	storage, err := NewStorage(ctx, cnfg)
 	if err != nil {
-		logger.Error("Cant open database", slog.Any("err", err))
-		os.Exit(1)
+		return nil, fmt.Errorf("can't open database: %w", err)
 	}
-	defer storage.Close()
 
 	logger.Info("Connected postgres\n")
 
-	metrics.RegisterMetrics()
+	if err := db.SetupPostgres(ctx, logger, cnfg.PostgreURL.URL, migrationsMode, cnfg.Migrations.LockTimeout); err != nil {
+		storage.Close()
+		return nil, err
+	}
+	logger.Info("Migrations checked\n", slog.String("mode", string(migrationsMode)))
+
+	metrics.RegisterPool("primary", storage)
+	for i, replica := range storage.ReplicaPools() {
+		metrics.RegisterPool(fmt.Sprintf("replica-%d", i), replica)
+	}
+
+	components.Add(lifecycle.Component{
+		Name: "storage",
+		// pgxpool.Close ждёт возврата всех соединений, поэтому ожидание
+		// ограничено дедлайном остановки.
+		Stop: func(ctx context.Context) error {
+			closed := make(chan struct{})
+			go func() {
+				defer close(closed)
+				storage.Close()
+			}()
+
+			select {
+			case <-closed:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	})
+	readiness.Register("storage", storage.Ping)
+
+	if migrationsMode != db.ModeIgnore {
+		migrator, err := db.NewMigrator(cnfg.PostgreURL.URL, cnfg.Migrations.LockTimeout)
+		if err != nil {
+			storage.Close()
+			return nil, err
+		}
+		readiness.Register("migrations", migrator.Check)
+
+		components.Add(lifecycle.Component{
+			Name:      "migrator",
+			DependsOn: []string{"storage"},
+			Stop:      func(context.Context) error { return migrator.Close() },
+		})
+	}
+
+	return storage, nil
+}
+
+// Start запускает серверы и фоновые процессы. Stop нужно вызвать и после
+// ошибки Start: он остановит то, что успело запуститься, и закроет базу.
+func (a *App) Start(ctx context.Context) error {
+	return a.components.Start(ctx)
+}
+
+// Stop останавливает компоненты в обратном порядке до дедлайна ctx.
+func (a *App) Stop(ctx context.Context) error {
+	a.logger.Info("Shutting down...")
+
+	if err := a.components.Stop(ctx); err != nil {
+		return err
+	}
 
-	logger.Info("Metrics was registered\n")
+	a.logger.Info("All components stopped")
+	return nil
+}
 
-	server := server.InitServer(cnfg, logger, storage)
+// Failed возвращает ошибку сервера, завершившегося после запуска.
+func (a *App) Failed() <-chan error {
+	return a.components.Failed()
+}
+
+// Run запускает сервис и останавливает его по SIGINT или SIGTERM.
+func Run(cnfg *config.Config) error {
+	a, err := New(context.Background(), cnfg, Options{})
+	if err != nil {
+		return err
+	}
 
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
 
-	go func() {
-		logger.Info("starting server", slog.String("port", cnfg.HttpInfo.Port))
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.Error("Server error", slog.Any("err", err))
-			os.Exit(1)
+	runErr := a.Start(context.Background())
+	if runErr == nil {
+		select {
+		case <-stop:
+		case runErr = <-a.Failed():
 		}
-	}()
-
-	<-stop
-	logger.Info("Shutting down server...")
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cnfg.Lifecycle.ShutdownTimeout)
 	defer cancel()
 
-	// Завершаем работу сервера
-	if err := server.Shutdown(ctx); err != nil {
-		logger.Error("Server shutdown error", slog.Any("err", err))
-	} else {
-		logger.Info("Server gracefully stopped")
-	}
+	return errors.Join(runErr, a.Stop(ctx))
+}
+
+// NewStorage подключается к Postgres по настройкам из конфигурации.
+func NewStorage(ctx context.Context, cnfg *config.Config) (*postgre.Storage, error) {
+	pgCnfg := cnfg.PostgreURL
 
-	logger.Info("Finishing programm")
+	return postgre.New(ctx, postgre.Config{
+		URL:          pgCnfg.URL,
+		ReadTimeout:  pgCnfg.ReadTimeout,
+		WriteTimeout: pgCnfg.WriteTimeout,
+		Pool: postgre.PoolConfig{
+			MaxConns:           pgCnfg.MaxConns,
+			MinConns:           pgCnfg.MinConns,
+			MaxConnLifetime:    pgCnfg.MaxConnLifetime,
+			MaxConnIdleTime:    pgCnfg.MaxConnIdleTime,
+			HealthCheckPeriod:  pgCnfg.HealthCheckPeriod,
+			StatementCacheMode: pgCnfg.StatementCacheMode,
+			ConnectAttempts:    pgCnfg.ConnectAttempts,
+			ConnectBackoff:     pgCnfg.ConnectBackoff,
+			ConnectMaxBackoff:  pgCnfg.ConnectMaxBackoff,
+		},
+		Replicas: postgre.ReplicaConfig{
+			URLs:              pgCnfg.Replicas,
+			HealthCheckPeriod: pgCnfg.ReplicaHealthCheckPeriod,
+		},
+	})
 }
@@ -0,0 +1,254 @@
+// Package cli - команды бинарника для обслуживания сервиса: запуск сервера,
+// миграции, работа со ссылками и ключами API напрямую через хранилище, без HTTP.
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/RozmiDan/url_shortener/internal/app"
+	"github.com/RozmiDan/url_shortener/internal/config"
+	"github.com/RozmiDan/url_shortener/internal/http-server/server"
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/RozmiDan/url_shortener/internal/usecase/links"
+	"github.com/RozmiDan/url_shortener/internal/usecase/rules"
+)
+
+// Коды выхода.
+const (
+	ExitOK       = 0
+	ExitError    = 1
+	ExitUsage    = 2
+	ExitNotFound = 3
+	ExitConflict = 4
+)
+
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+)
+
+// Storage - методы хранилища, которые используют команды.
+type Storage interface {
+	links.Repository
+	rules.Repository
+	CreateAPIKey(ctx context.Context, key storage.APIKey) (storage.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id int64) (storage.APIKey, error)
+}
+
+// newService - сервис ссылок с теми же правилами, что и у HTTP API.
+func newService(st Storage) *links.Service {
+	return links.New(st, links.Config{ReservedAliases: server.ReservedAliases})
+}
+
+// Env - окружение, в котором выполняются команды.
+type Env struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// OpenStorage подключается к хранилищу из конфигурации. Возвращаемая
+	// функция закрывает подключение.
+	OpenStorage func(ctx context.Context, cnfg *config.Config) (Storage, func(), error)
+	// Serve запускает сервер и возвращается после его остановки.
+	Serve func(cnfg *config.Config) error
+
+	configPath string
+	output     string
+}
+
+// DefaultEnv - стандартные потоки процесса и Postgres из конфигурации.
+func DefaultEnv() *Env {
+	return &Env{
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+		OpenStorage: func(ctx context.Context, cnfg *config.Config) (Storage, func(), error) {
+			st, err := app.NewStorage(ctx, cnfg)
+			if err != nil {
+				return nil, nil, err
+			}
+			return st, st.Close, nil
+		},
+		Serve: app.Run,
+	}
+}
+
+type command struct {
+	usage string
+	run   func(env *Env, args []string) int
+}
+
+var commands = map[string]command{
+	"serve":   {usage: "start the HTTP server (default)", run: runServe},
+	"migrate": {usage: "manage database migrations", run: runMigrate},
+	"links":   {usage: "create, get, delete, rename and list links", run: runLinks},
+	"keys":    {usage: "create and revoke API keys", run: runKeys},
+	"export":  {usage: "write all links as JSON lines", run: runExport},
+	"import":  {usage: "create links from JSON lines written by export", run: runImport},
+}
+
+// Run выполняет команду из args (без имени программы) и возвращает код
+// выхода. Без команды запускается сервер.
+func Run(env *Env, args []string) int {
+	flags := env.flagSet("url_shortener")
+	flags.Usage = func() { env.usage(flags) }
+
+	if err := flags.Parse(args); err != nil {
+		return ExitUsage
+	}
+
+	if flags.NArg() == 0 {
+		return runServe(env, nil)
+	}
+
+	name := flags.Arg(0)
+	if name == "help" {
+		env.usage(flags)
+		return ExitOK
+	}
+
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(env.Stderr, "unknown command %q\n\n", name)
+		env.usage(flags)
+		return ExitUsage
+	}
+
+	return cmd.run(env, flags.Args()[1:])
+}
+
+func (env *Env) usage(flags *flag.FlagSet) {
+	fmt.Fprintln(env.Stderr, "Usage: url_shortener [flags] <command> [args]")
+	fmt.Fprintln(env.Stderr, "\nCommands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(env.Stderr, 0, 0, 2, ' ', 0)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s\t%s\n", name, commands[name].usage)
+	}
+	w.Flush()
+
+	fmt.Fprintln(env.Stderr, "\nFlags:")
+	flags.PrintDefaults()
+}
+
+// flagSet создаёт набор флагов команды с общими флагами --config и
+// --output, поэтому их можно указывать и до, и после имени команды.
+func (env *Env) flagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(env.Stderr)
+
+	if env.output == "" {
+		env.output = OutputTable
+	}
+
+	flags.StringVar(&env.configPath, "config", env.configPath, "config file, overrides CONFIG_PATH")
+	flags.Func("output", "output format: table or json", func(s string) error {
+		if s != OutputTable && s != OutputJSON {
+			return fmt.Errorf("unknown output format %q", s)
+		}
+		env.output = s
+		return nil
+	})
+	return flags
+}
+
+func (env *Env) loadConfig() (*config.Config, error) {
+	path := env.configPath
+	if path == "" {
+		path = os.Getenv("CONFIG_PATH")
+	}
+	if path == "" {
+		return nil, errors.New("config is not set: use --config or CONFIG_PATH")
+	}
+	return config.Load(path)
+}
+
+// withStorage загружает конфигурацию, подключается к хранилищу и выполняет fn.
+func (env *Env) withStorage(fn func(ctx context.Context, st Storage) int) int {
+	cnfg, err := env.loadConfig()
+	if err != nil {
+		fmt.Fprintln(env.Stderr, err)
+		return ExitError
+	}
+
+	ctx := context.Background()
+
+	st, closeStorage, err := env.OpenStorage(ctx, cnfg)
+	if err != nil {
+		fmt.Fprintln(env.Stderr, "cant open storage:", err)
+		return ExitError
+	}
+	defer closeStorage()
+
+	return fn(ctx, st)
+}
+
+// fail печатает ошибку и возвращает код выхода, соответствующий ей.
+func (env *Env) fail(err error) int {
+	fmt.Fprintln(env.Stderr, err)
+
+	switch {
+	case errors.Is(err, links.ErrInvalid):
+		return ExitUsage
+	case errors.Is(err, storage.ErrAliasNotFound), errors.Is(err, storage.ErrURLNotFound),
+		errors.Is(err, storage.ErrAPIKeyNotFound):
+		return ExitNotFound
+	case errors.Is(err, storage.ErrAliasExists), errors.Is(err, storage.ErrVersionConflict):
+		return ExitConflict
+	}
+	return ExitError
+}
+
+// print выводит v как JSON или, в табличном режиме, вызывает table.
+func (env *Env) print(v any, table func(w io.Writer)) int {
+	if env.output == OutputJSON {
+		enc := json.NewEncoder(env.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(v); err != nil {
+			fmt.Fprintln(env.Stderr, err)
+			return ExitError
+		}
+		return ExitOK
+	}
+
+	w := tabwriter.NewWriter(env.Stdout, 0, 0, 2, ' ', 0)
+	table(w)
+	if err := w.Flush(); err != nil {
+		fmt.Fprintln(env.Stderr, err)
+		return ExitError
+	}
+	return ExitOK
+}
+
+func runServe(env *Env, args []string) int {
+	flags := env.flagSet("serve")
+	if err := flags.Parse(args); err != nil {
+		return ExitUsage
+	}
+
+	cnfg, err := env.loadConfig()
+	if err != nil {
+		fmt.Fprintln(env.Stderr, err)
+		return ExitError
+	}
+
+	if err := env.Serve(cnfg); err != nil {
+		fmt.Fprintln(env.Stderr, err)
+		return ExitError
+	}
+	return ExitOK
+}
@@ -0,0 +1,201 @@
+package cli_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/RozmiDan/url_shortener/internal/cli"
+	"github.com/RozmiDan/url_shortener/internal/config"
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/RozmiDan/url_shortener/internal/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type result struct {
+	code   int
+	stdout string
+	stderr string
+}
+
+func run(t *testing.T, st *memory.Storage, stdin string, args ...string) result {
+	t.Helper()
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(`
+app:
+  name: "url_shortener"
+  version: "test"
+postgres:
+  url: "postgres://localhost/test"
+`), 0o644))
+
+	var stdout, stderr bytes.Buffer
+	env := &cli.Env{
+		Stdin:  strings.NewReader(stdin),
+		Stdout: &stdout,
+		Stderr: &stderr,
+		OpenStorage: func(ctx context.Context, cnfg *config.Config) (cli.Storage, func(), error) {
+			return st, func() {}, nil
+		},
+	}
+
+	code := cli.Run(env, append([]string{"--config", configPath}, args...))
+	return result{code: code, stdout: stdout.String(), stderr: stderr.String()}
+}
+
+func TestLinks(t *testing.T) {
+	st := memory.New()
+
+	res := run(t, st, "", "links", "create", "--url", "https://example.com", "--alias", "abc",
+		"--tags", "b,a", "--folder", "docs", "--output", "json")
+	require.Equal(t, cli.ExitOK, res.code, res.stderr)
+
+	var link cli.Link
+	require.NoError(t, json.Unmarshal([]byte(res.stdout), &link))
+	assert.Equal(t, "abc", link.Alias)
+	assert.Equal(t, "https://example.com", link.URL)
+	assert.Equal(t, []string{"a", "b"}, link.Tags)
+	assert.EqualValues(t, 1, link.Version)
+
+	// Без --force существующая ссылка не перезаписывается.
+	res = run(t, st, "", "links", "create", "--url", "https://example.org", "--alias", "abc")
+	assert.Equal(t, cli.ExitConflict, res.code)
+
+	res = run(t, st, "", "links", "create", "--url", "not a url")
+	assert.Equal(t, cli.ExitUsage, res.code)
+
+	res = run(t, st, "", "links", "get", "abc")
+	require.Equal(t, cli.ExitOK, res.code, res.stderr)
+	assert.Contains(t, res.stdout, "https://example.com")
+	assert.Contains(t, res.stdout, "docs")
+
+	res = run(t, st, "", "links", "get", "missing")
+	assert.Equal(t, cli.ExitNotFound, res.code)
+
+	res = run(t, st, "", "links", "rename", "abc", "xyz")
+	require.Equal(t, cli.ExitOK, res.code, res.stderr)
+
+	res = run(t, st, "", "links", "list", "--tag", "a", "--output", "json")
+	require.Equal(t, cli.ExitOK, res.code, res.stderr)
+
+	var links []storage.LinkInfo
+	require.NoError(t, json.Unmarshal([]byte(res.stdout), &links))
+	require.Len(t, links, 1)
+	assert.Equal(t, "xyz", links[0].Alias)
+
+	res = run(t, st, "", "links", "delete", "xyz", "--version", "1")
+	assert.Equal(t, cli.ExitConflict, res.code)
+
+	res = run(t, st, "", "links", "delete", "xyz")
+	require.Equal(t, cli.ExitOK, res.code, res.stderr)
+
+	res = run(t, st, "", "links", "delete", "xyz")
+	assert.Equal(t, cli.ExitNotFound, res.code)
+}
+
+func TestExportImport(t *testing.T) {
+	src := memory.New()
+	ctx := context.Background()
+
+	_, err := src.SaveURL(ctx, "https://example.com", "abc", storage.URLOptions{
+		MaxClicks: 5,
+		Variants:  []storage.Variant{{URL: "https://a.example.com", Weight: 1}},
+		Organization: storage.Organization{
+			Tags:     []string{"t"},
+			Metadata: map[string]any{"owner": "team"},
+		},
+	})
+	require.NoError(t, err)
+	_, _, err = src.AddRule(ctx, "abc", storage.Rule{
+		Conditions: storage.RuleConditions{Countries: []string{"DE"}},
+		TargetURL:  "https://example.de",
+	}, 0)
+	require.NoError(t, err)
+	_, err = src.SaveURL(ctx, "https://example.org", "def", storage.URLOptions{})
+	require.NoError(t, err)
+
+	exported := run(t, src, "", "export")
+	require.Equal(t, cli.ExitOK, exported.code, exported.stderr)
+	assert.Equal(t, 2, strings.Count(exported.stdout, "\n"))
+
+	dst := memory.New()
+	_, err = dst.SaveURL(ctx, "https://old.example.com", "def", storage.URLOptions{})
+	require.NoError(t, err)
+
+	// Импорт останавливается на первой занятой ссылке.
+	res := run(t, dst, exported.stdout, "import")
+	assert.Equal(t, cli.ExitConflict, res.code)
+	assert.Contains(t, res.stderr, "imported 1 links, skipped 0")
+
+	res = run(t, dst, exported.stdout, "import", "--on-conflict", "skip")
+	require.Equal(t, cli.ExitOK, res.code, res.stderr)
+	assert.Contains(t, res.stderr, "imported 0 links, skipped 2")
+
+	state, err := dst.GetLinkState(ctx, "abc")
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, state.Options.MaxClicks)
+	assert.Equal(t, map[string]any{"owner": "team"}, state.Options.Organization.Metadata)
+
+	rules, _, err := dst.ListRules(ctx, "abc")
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	assert.Equal(t, "https://example.de", rules[0].TargetURL)
+
+	variants, err := dst.ListVariants(ctx, "abc")
+	require.NoError(t, err)
+	require.Len(t, variants, 1)
+
+	res = run(t, dst, exported.stdout, "import", "--on-conflict", "overwrite")
+	require.Equal(t, cli.ExitOK, res.code, res.stderr)
+
+	state, err = dst.GetLinkState(ctx, "def")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.org", state.URL)
+
+	res = run(t, dst, "{not json", "import")
+	assert.Equal(t, cli.ExitUsage, res.code)
+}
+
+func TestKeys(t *testing.T) {
+	ctx := context.Background()
+	st := memory.New()
+
+	res := run(t, st, "", "keys", "create", "--name", "crm", "--output", "json")
+	require.Equal(t, cli.ExitOK, res.code, res.stderr)
+
+	var created cli.CreatedKey
+	require.NoError(t, json.Unmarshal([]byte(res.stdout), &created))
+	assert.Equal(t, "crm", created.Name)
+	assert.True(t, strings.HasPrefix(created.Key, created.Prefix))
+
+	key, err := st.FindAPIKey(ctx, storage.HashAPIKey(created.Key))
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, key.ID)
+
+	res = run(t, st, "", "keys", "revoke", strconv.FormatInt(created.ID, 10))
+	require.Equal(t, cli.ExitOK, res.code, res.stderr)
+
+	_, err = st.FindAPIKey(ctx, storage.HashAPIKey(created.Key))
+	assert.ErrorIs(t, err, storage.ErrAPIKeyNotFound)
+
+	assert.Equal(t, cli.ExitNotFound, run(t, st, "", "keys", "revoke", "42").code)
+	assert.Equal(t, cli.ExitUsage, run(t, st, "", "keys", "revoke", "abc").code)
+	assert.Equal(t, cli.ExitUsage, run(t, st, "", "keys", "create").code)
+}
+
+func TestUsage(t *testing.T) {
+	st := memory.New()
+
+	assert.Equal(t, cli.ExitUsage, run(t, st, "", "unknown").code)
+	assert.Equal(t, cli.ExitUsage, run(t, st, "", "links").code)
+	assert.Equal(t, cli.ExitUsage, run(t, st, "", "links", "get").code)
+	assert.Equal(t, cli.ExitUsage, run(t, st, "", "--output", "xml", "links", "list").code)
+	assert.Equal(t, cli.ExitOK, run(t, st, "", "help").code)
+}
@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/RozmiDan/url_shortener/internal/storage"
+)
+
+const keysUsage = `Usage: url_shortener keys <command> [flags] [args]
+
+Commands:
+  create --name NAME   issue an API key, it is shown only once
+  revoke ID            revoke an API key
+`
+
+// CreatedKey - выпущенный ключ: описание и сам ключ, который больше
+// нигде не хранится.
+type CreatedKey struct {
+	storage.APIKey
+	Key string `json:"key"`
+}
+
+func runKeys(env *Env, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(env.Stderr, keysUsage)
+		return ExitUsage
+	}
+
+	sub, args := args[0], args[1:]
+	switch sub {
+	case "create":
+		return keysCreate(env, args)
+	case "revoke":
+		return keysRevoke(env, args)
+	}
+
+	fmt.Fprintf(env.Stderr, "keys: unknown command %q\n\n", sub)
+	fmt.Fprint(env.Stderr, keysUsage)
+	return ExitUsage
+}
+
+func keysCreate(env *Env, args []string) int {
+	flags := env.flagSet("keys create")
+	name := flags.String("name", "", "who or what the key is for (required)")
+
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return ExitUsage
+	}
+	if *name == "" || len(positional) != 0 {
+		fmt.Fprintln(env.Stderr, "keys create: --name is required")
+		return ExitUsage
+	}
+
+	key, apiKey, err := storage.NewAPIKey(*name)
+	if err != nil {
+		fmt.Fprintln(env.Stderr, "cant generate key:", err)
+		return ExitError
+	}
+
+	return env.withStorage(func(ctx context.Context, st Storage) int {
+		apiKey, err := st.CreateAPIKey(ctx, apiKey)
+		if err != nil {
+			return env.fail(err)
+		}
+
+		created := CreatedKey{APIKey: apiKey, Key: key}
+		return env.print(created, func(w io.Writer) {
+			fmt.Fprintf(w, "id\t%d\n", created.ID)
+			fmt.Fprintf(w, "name\t%s\n", created.Name)
+			fmt.Fprintf(w, "key\t%s\n", created.Key)
+			fmt.Fprintln(w, "\nThe key is shown only once, store it now.")
+		})
+	})
+}
+
+func keysRevoke(env *Env, args []string) int {
+	flags := env.flagSet("keys revoke")
+
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return ExitUsage
+	}
+	if len(positional) != 1 {
+		fmt.Fprintln(env.Stderr, "keys revoke: exactly one key id is required")
+		return ExitUsage
+	}
+
+	id, err := strconv.ParseInt(positional[0], 10, 64)
+	if err != nil || id <= 0 {
+		fmt.Fprintf(env.Stderr, "keys revoke: invalid key id %q\n", positional[0])
+		return ExitUsage
+	}
+
+	return env.withStorage(func(ctx context.Context, st Storage) int {
+		apiKey, err := st.RevokeAPIKey(ctx, id)
+		if err != nil {
+			return env.fail(fmt.Errorf("key %d: %w", id, err))
+		}
+
+		return env.print(apiKey, func(w io.Writer) {
+			fmt.Fprintf(w, "revoked\t%d (%s, %s...)\n", apiKey.ID, apiKey.Name, apiKey.Prefix)
+		})
+	})
+}
@@ -0,0 +1,273 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	save_handler "github.com/RozmiDan/url_shortener/internal/http-server/handlers/save"
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/RozmiDan/url_shortener/internal/usecase/links"
+)
+
+const linksUsage = `Usage: url_shortener links <command> [flags] [args]
+
+Commands:
+  create --url URL [--alias ALIAS] [flags]   create a link
+  get ALIAS                                  show a link
+  delete ALIAS [--version N]                 delete a link
+  rename ALIAS NEW_ALIAS [--version N]       change the alias of a link
+  list [--folder F] [--tag T] [--query Q]    list links
+`
+
+// Link - ссылка в выводе get и create: тот же документ, что принимает
+// POST /url, и текущая версия.
+type Link struct {
+	save_handler.Request
+	Version int64 `json:"version"`
+}
+
+func runLinks(env *Env, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(env.Stderr, linksUsage)
+		return ExitUsage
+	}
+
+	sub, args := args[0], args[1:]
+	switch sub {
+	case "create":
+		return linksCreate(env, args)
+	case "get":
+		return linksGet(env, args)
+	case "delete":
+		return linksDelete(env, args)
+	case "rename":
+		return linksRename(env, args)
+	case "list":
+		return linksList(env, args)
+	}
+
+	fmt.Fprintf(env.Stderr, "links: unknown command %q\n\n", sub)
+	fmt.Fprint(env.Stderr, linksUsage)
+	return ExitUsage
+}
+
+func linksCreate(env *Env, args []string) int {
+	flags := env.flagSet("links create")
+
+	var req save_handler.Request
+	flags.StringVar(&req.URL, "url", "", "target URL (required)")
+	flags.StringVar(&req.Alias, "alias", "", "alias, random if empty")
+	flags.Int64Var(&req.MaxClicks, "max-clicks", 0, "redirect limit, 0 - unlimited")
+	flags.StringVar(&req.Title, "title", "", "preview title")
+	flags.StringVar(&req.Folder, "folder", "", "folder")
+	flags.IntVar(&req.RedirectCode, "redirect-code", 0, "HTTP redirect code: 301, 302, 307 or 308")
+	tags := flags.String("tags", "", "comma-separated tags")
+	force := flags.Bool("force", false, "overwrite an existing link with the same alias")
+
+	if _, err := parseArgs(flags, args); err != nil {
+		return ExitUsage
+	}
+
+	if *tags != "" {
+		req.Tags = strings.Split(*tags, ",")
+	}
+
+	if err := req.Validate(); err != nil {
+		fmt.Fprintln(env.Stderr, "invalid link:", err)
+		return ExitUsage
+	}
+
+	conflict := links.ConflictFail
+	if *force {
+		conflict = links.ConflictOverwrite
+	}
+
+	return env.withStorage(func(ctx context.Context, st Storage) int {
+		alias, err := newService(st).Create(ctx, req.Draft(), conflict)
+		if err != nil {
+			if req.Alias != "" {
+				err = fmt.Errorf("link %q: %w", req.Alias, err)
+			}
+			return env.fail(err)
+		}
+
+		return printLink(ctx, env, st, alias)
+	})
+}
+
+func linksGet(env *Env, args []string) int {
+	flags := env.flagSet("links get")
+
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return ExitUsage
+	}
+	if len(positional) != 1 {
+		fmt.Fprintln(env.Stderr, "links get: exactly one alias is required")
+		return ExitUsage
+	}
+
+	return env.withStorage(func(ctx context.Context, st Storage) int {
+		return printLink(ctx, env, st, positional[0])
+	})
+}
+
+func linksDelete(env *Env, args []string) int {
+	flags := env.flagSet("links delete")
+	version := flags.Int64("version", 0, "delete only if the link has this version")
+
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return ExitUsage
+	}
+	if len(positional) != 1 {
+		fmt.Fprintln(env.Stderr, "links delete: exactly one alias is required")
+		return ExitUsage
+	}
+
+	return env.withStorage(func(ctx context.Context, st Storage) int {
+		if err := newService(st).Delete(ctx, positional[0], *version); err != nil {
+			return env.fail(err)
+		}
+		fmt.Fprintln(env.Stdout, "deleted", positional[0])
+		return ExitOK
+	})
+}
+
+func linksRename(env *Env, args []string) int {
+	flags := env.flagSet("links rename")
+	version := flags.Int64("version", 0, "rename only if the link has this version")
+
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return ExitUsage
+	}
+	if len(positional) != 2 {
+		fmt.Fprintln(env.Stderr, "links rename: alias and new alias are required")
+		return ExitUsage
+	}
+
+	alias, newAlias := positional[0], positional[1]
+
+	return env.withStorage(func(ctx context.Context, st Storage) int {
+		if _, err := newService(st).Rename(ctx, alias, newAlias, *version); err != nil {
+			return env.fail(err)
+		}
+		return printLink(ctx, env, st, newAlias)
+	})
+}
+
+func linksList(env *Env, args []string) int {
+	flags := env.flagSet("links list")
+
+	var filter storage.ListFilter
+	flags.StringVar(&filter.Folder, "folder", "", "only links in this folder")
+	flags.StringVar(&filter.Query, "query", "", "substring of alias or URL")
+	flags.IntVar(&filter.Limit, "limit", 0, "maximum number of links, 0 - all")
+	flags.IntVar(&filter.Offset, "offset", 0, "number of links to skip")
+	flags.Func("tag", "only links with this tag, can be repeated", func(s string) error {
+		filter.Tags = append(filter.Tags, s)
+		return nil
+	})
+
+	if _, err := parseArgs(flags, args); err != nil {
+		return ExitUsage
+	}
+
+	return env.withStorage(func(ctx context.Context, st Storage) int {
+		infos, err := newService(st).List(ctx, filter)
+		if err != nil {
+			return env.fail(err)
+		}
+		if infos == nil {
+			infos = []storage.LinkInfo{}
+		}
+
+		return env.print(infos, func(w io.Writer) {
+			fmt.Fprintln(w, "ALIAS\tURL\tFOLDER\tTAGS\tVERSION")
+			for _, l := range infos {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\n",
+					l.Alias, l.URL, l.Folder, strings.Join(l.Tags, ","), l.Version)
+			}
+		})
+	})
+}
+
+func printLink(ctx context.Context, env *Env, st Storage, alias string) int {
+	link, err := getLink(ctx, st, alias)
+	if err != nil {
+		return env.fail(err)
+	}
+
+	return env.print(link, func(w io.Writer) {
+		fmt.Fprintf(w, "alias\t%s\n", link.Alias)
+		fmt.Fprintf(w, "url\t%s\n", link.URL)
+		fmt.Fprintf(w, "version\t%d\n", link.Version)
+		if link.MaxClicks > 0 {
+			fmt.Fprintf(w, "max_clicks\t%d\n", link.MaxClicks)
+		}
+		if link.ActiveFrom != nil {
+			fmt.Fprintf(w, "active_from\t%s\n", link.ActiveFrom)
+		}
+		if link.ActiveUntil != nil {
+			fmt.Fprintf(w, "active_until\t%s\n", link.ActiveUntil)
+		}
+		if link.Title != "" {
+			fmt.Fprintf(w, "title\t%s\n", link.Title)
+		}
+		if link.Folder != "" {
+			fmt.Fprintf(w, "folder\t%s\n", link.Folder)
+		}
+		if len(link.Tags) > 0 {
+			fmt.Fprintf(w, "tags\t%s\n", strings.Join(link.Tags, ","))
+		}
+		if link.RedirectCode != 0 {
+			fmt.Fprintf(w, "redirect_code\t%d\n", link.RedirectCode)
+		}
+		for _, v := range link.Variants {
+			fmt.Fprintf(w, "variant\t%s (weight %d)\n", v.URL, v.Weight)
+		}
+	})
+}
+
+// getLink читает ссылку вместе с вариантами A/B-теста.
+func getLink(ctx context.Context, st Storage, alias string) (Link, error) {
+	svc := newService(st)
+
+	state, err := svc.Get(ctx, alias)
+	if err != nil {
+		return Link{}, fmt.Errorf("link %q: %w", alias, err)
+	}
+
+	variants, err := svc.Variants(ctx, alias)
+	if err != nil {
+		return Link{}, fmt.Errorf("link %q: %w", alias, err)
+	}
+
+	doc := save_handler.FromState(state)
+	for _, v := range variants {
+		doc.Variants = append(doc.Variants, save_handler.Variant{URL: v.URL, Weight: v.Weight})
+	}
+
+	return Link{Request: doc, Version: state.Version}, nil
+}
+
+// parseArgs разбирает флаги, стоящие и до, и после позиционных аргументов,
+// и возвращает позиционные.
+func parseArgs(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		args = flags.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
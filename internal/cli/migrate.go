@@ -0,0 +1,159 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/RozmiDan/url_shortener/db"
+	"github.com/pressly/goose/v3"
+)
+
+const migrateUsage = `Usage: url_shortener migrate <command> [args]
+
+Commands:
+  up [VERSION]     apply all pending migrations or up to VERSION
+  down [VERSION]   roll back the last migration or everything above VERSION
+  redo             roll back and re-apply the last migration
+  status           show applied and pending migrations
+  version          print the current database version
+  create NAME      create an empty migration in -dir
+
+The database is taken from the config file in --config or CONFIG_PATH.
+`
+
+// runMigrate выполняет подкоманду migrate.
+func runMigrate(env *Env, args []string) int {
+	stdout, stderr := env.Stdout, env.Stderr
+
+	flags := env.flagSet("migrate")
+	flags.Usage = func() {
+		fmt.Fprint(stderr, migrateUsage)
+		fmt.Fprintln(stderr, "\nFlags:")
+		flags.PrintDefaults()
+	}
+	dir := flags.String("dir", db.MigrationsDir, "directory for new migrations (create)")
+
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return ExitUsage
+	}
+	if len(positional) == 0 {
+		flags.Usage()
+		return ExitUsage
+	}
+
+	command, rest := positional[0], positional[1:]
+
+	// create работает с файлами и не требует ни конфигурации, ни базы.
+	if command == "create" {
+		if len(rest) == 0 {
+			fmt.Fprintln(stderr, "migrate create: migration name is required")
+			return ExitUsage
+		}
+		path, err := db.Create(*dir, rest[0])
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return ExitError
+		}
+		fmt.Fprintln(stdout, "created", path)
+		return ExitOK
+	}
+
+	var version int64
+	switch command {
+	case "up", "down":
+		if len(rest) > 0 {
+			v, err := strconv.ParseInt(rest[0], 10, 64)
+			if err != nil || v < 1 {
+				fmt.Fprintf(stderr, "migrate %s: invalid version %q\n", command, rest[0])
+				return ExitUsage
+			}
+			version = v
+		}
+	case "redo", "status", "version":
+	default:
+		fmt.Fprintf(stderr, "migrate: unknown command %q\n\n", command)
+		flags.Usage()
+		return ExitUsage
+	}
+
+	cnfg, err := env.loadConfig()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitError
+	}
+
+	m, err := db.NewMigrator(cnfg.PostgreURL.URL, cnfg.Migrations.LockTimeout)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitError
+	}
+	defer m.Close()
+
+	ctx := context.Background()
+
+	var results []*goose.MigrationResult
+	switch command {
+	case "up":
+		results, err = m.Up(ctx, version)
+		if errors.Is(err, goose.ErrNoNextVersion) {
+			fmt.Fprintln(stdout, "no migrations to apply")
+			return ExitOK
+		}
+	case "down":
+		results, err = m.Down(ctx, version)
+		if errors.Is(err, goose.ErrNoNextVersion) {
+			fmt.Fprintln(stdout, "no migrations to roll back")
+			return ExitOK
+		}
+	case "redo":
+		results, err = m.Redo(ctx)
+	case "status":
+		err = printStatus(ctx, stdout, m)
+	case "version":
+		var v int64
+		if v, err = m.Version(ctx); err == nil {
+			fmt.Fprintln(stdout, v)
+		}
+	}
+
+	for _, res := range results {
+		direction := "applied"
+		if res.Direction == "down" {
+			direction = "rolled back"
+		}
+		fmt.Fprintf(stdout, "%s %s (%s)\n", direction, res.Source.Path, res.Duration.Round(time.Millisecond))
+	}
+
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return ExitError
+	}
+	if (command == "up" || command == "down") && len(results) == 0 {
+		fmt.Fprintln(stdout, "nothing to do")
+	}
+	return ExitOK
+}
+
+func printStatus(ctx context.Context, out io.Writer, m *db.Migrator) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tMIGRATION\tAPPLIED AT")
+	for _, st := range statuses {
+		applied := "pending"
+		if st.State == goose.StateApplied {
+			applied = st.AppliedAt.Local().Format(time.DateTime)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", st.Source.Version, st.Source.Path, applied)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,203 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	save_handler "github.com/RozmiDan/url_shortener/internal/http-server/handlers/save"
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/RozmiDan/url_shortener/internal/usecase/links"
+	"github.com/RozmiDan/url_shortener/internal/usecase/rules"
+)
+
+// exportPageSize - по сколько ссылок читать при экспорте.
+const exportPageSize = 500
+
+// Record - строка файла экспорта: документ ссылки в формате POST /url
+// и её правила умного перехода.
+type Record struct {
+	save_handler.Request
+	Rules []storage.Rule `json:"rules,omitempty"`
+}
+
+const (
+	onConflictFail      = "fail"
+	onConflictSkip      = "skip"
+	onConflictOverwrite = "overwrite"
+)
+
+func runExport(env *Env, args []string) int {
+	flags := env.flagSet("export")
+	file := flags.String("file", "-", "output file, - for stdout")
+
+	if _, err := parseArgs(flags, args); err != nil {
+		return ExitUsage
+	}
+
+	return env.withStorage(func(ctx context.Context, st Storage) int {
+		out := env.Stdout
+		if *file != "-" {
+			f, err := os.Create(*file)
+			if err != nil {
+				return env.fail(err)
+			}
+			defer f.Close()
+			out = f
+		}
+
+		w := bufio.NewWriter(out)
+		enc := json.NewEncoder(w)
+
+		var count int
+		for offset := 0; ; offset += exportPageSize {
+			page, err := newService(st).List(ctx, storage.ListFilter{Limit: exportPageSize, Offset: offset})
+			if err != nil {
+				return env.fail(err)
+			}
+
+			for _, l := range page {
+				rec, err := exportRecord(ctx, st, l.Alias)
+				if errors.Is(err, storage.ErrAliasNotFound) {
+					// Ссылку удалили во время экспорта.
+					continue
+				}
+				if err != nil {
+					return env.fail(err)
+				}
+				if err := enc.Encode(rec); err != nil {
+					return env.fail(err)
+				}
+				count++
+			}
+
+			if len(page) < exportPageSize {
+				break
+			}
+		}
+
+		if err := w.Flush(); err != nil {
+			return env.fail(err)
+		}
+
+		fmt.Fprintf(env.Stderr, "exported %d links\n", count)
+		return ExitOK
+	})
+}
+
+func exportRecord(ctx context.Context, st Storage, alias string) (Record, error) {
+	link, err := getLink(ctx, st, alias)
+	if err != nil {
+		return Record{}, err
+	}
+
+	list, _, err := rules.New(st).List(ctx, alias)
+	if err != nil {
+		return Record{}, fmt.Errorf("link %q: %w", alias, err)
+	}
+
+	return Record{Request: link.Request, Rules: list}, nil
+}
+
+func runImport(env *Env, args []string) int {
+	flags := env.flagSet("import")
+	file := flags.String("file", "-", "input file, - for stdin")
+	onConflict := flags.String("on-conflict", onConflictFail, "existing alias: fail, skip or overwrite")
+
+	if _, err := parseArgs(flags, args); err != nil {
+		return ExitUsage
+	}
+
+	switch *onConflict {
+	case onConflictFail, onConflictSkip, onConflictOverwrite:
+	default:
+		fmt.Fprintf(env.Stderr, "import: unknown --on-conflict %q\n", *onConflict)
+		return ExitUsage
+	}
+
+	conflict := links.ConflictFail
+	if *onConflict == onConflictOverwrite {
+		conflict = links.ConflictOverwrite
+	}
+
+	return env.withStorage(func(ctx context.Context, st Storage) int {
+		svc := newService(st)
+		ruleSvc := rules.New(st)
+
+		in := env.Stdin
+		if *file != "-" {
+			f, err := os.Open(*file)
+			if err != nil {
+				return env.fail(err)
+			}
+			defer f.Close()
+			in = f
+		}
+
+		dec := json.NewDecoder(in)
+
+		var imported, skipped int
+		report := func() {
+			fmt.Fprintf(env.Stderr, "imported %d links, skipped %d\n", imported, skipped)
+		}
+
+		for line := 1; ; line++ {
+			var rec Record
+			err := dec.Decode(&rec)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				report()
+				fmt.Fprintf(env.Stderr, "record %d: %v\n", line, err)
+				return ExitUsage
+			}
+
+			if rec.Alias == "" {
+				report()
+				fmt.Fprintf(env.Stderr, "record %d: alias is required\n", line)
+				return ExitUsage
+			}
+			if err := rec.Validate(); err != nil {
+				report()
+				fmt.Fprintf(env.Stderr, "record %d (%s): %v\n", line, rec.Alias, err)
+				return ExitUsage
+			}
+
+			err = importRecord(ctx, svc, ruleSvc, rec, conflict)
+			if errors.Is(err, storage.ErrAliasExists) && *onConflict == onConflictSkip {
+				skipped++
+				continue
+			}
+			if err != nil {
+				report()
+				return env.fail(fmt.Errorf("record %d: %w", line, err))
+			}
+			imported++
+		}
+
+		report()
+		return ExitOK
+	})
+}
+
+func importRecord(ctx context.Context, svc *links.Service, ruleSvc *rules.Service, rec Record, conflict links.Conflict) error {
+	if err := rules.ValidateList(rec.Rules); err != nil {
+		return fmt.Errorf("link %q rules: %w", rec.Alias, err)
+	}
+
+	if _, err := svc.Create(ctx, rec.Draft(), conflict); err != nil {
+		return fmt.Errorf("link %q: %w", rec.Alias, err)
+	}
+
+	// Правила заменяются всегда, чтобы при перезаписи не остались старые.
+	if _, _, err := ruleSvc.Replace(ctx, rec.Alias, rec.Rules, 0); err != nil {
+		return fmt.Errorf("link %q rules: %w", rec.Alias, err)
+	}
+
+	return nil
+}
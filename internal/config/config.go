@@ -1,7 +1,7 @@
 package config
 
 import (
-	"log"
+	"fmt"
 	"os"
 	"time"
 
@@ -10,11 +10,19 @@ import (
 
 type (
 	Config struct {
-		Env         string     `yaml:"env" env:"ENV" env-default:"local"`
-		StoragePath string     `yaml:"storage_path" env-required:"true"`
-		PostgreURL  postgreURL `yaml:"postgres"`
-		AppInfo     appStruct  `yaml:"app"`
-		HttpInfo    httpStruct `yaml:"http"`
+		Env         string      `yaml:"env" env:"ENV" env-default:"local"`
+		PostgreURL  postgreURL  `yaml:"postgres"`
+		AppInfo     appStruct   `yaml:"app"`
+		HttpInfo    httpStruct  `yaml:"http"`
+		Admin       admin       `yaml:"admin"`
+		Health      health      `yaml:"health"`
+		Lifecycle   lifecycle   `yaml:"lifecycle"`
+		API         api         `yaml:"api"`
+		Redirect    redirect    `yaml:"redirect"`
+		Checker     checker     `yaml:"checker"`
+		Webhooks    webhooks    `yaml:"webhooks"`
+		Idempotency idempotency `yaml:"idempotency"`
+		Migrations  migrations  `yaml:"migrations"`
 	}
 
 	appStruct struct {
@@ -28,32 +36,150 @@ type (
 		IdleTimeout time.Duration `yaml:"idle_timeout" env-default:"10s"`
 	}
 
-	postgreURL struct {
-		URL      string `yaml:"url" env-required:"true"`
-		Host     string `yaml:"host" env-required:"true"`
-		Port     uint16 `yaml:"port" env-required:"true"`
-		Database string `yaml:"database" env-required:"true"`
-		User     string `yaml:"user" env-required:"true"`
-		Password string `yaml:"password" env-required:"true"`
+	// admin - служебный сервер с метриками, проверками состояния, pprof и
+	// swagger. Его адрес не должен быть доступен из интернета.
+	admin struct {
+		Port string `yaml:"port" env-default:":8081"`
+		// Timeout - таймаут записи ответа, больше 30s, чтобы успел
+		// собраться профиль /debug/pprof/profile.
+		Timeout time.Duration `yaml:"timeout" env-default:"60s"`
+	}
+
+	health struct {
+		// CheckTimeout ограничивает каждую проверку /readyz.
+		CheckTimeout time.Duration `yaml:"check_timeout" env-default:"2s"`
+		// DrainDelay - сколько после SIGTERM сервер продолжает принимать
+		// запросы с /readyz = 503, чтобы балансировщик успел его исключить.
+		// Должен быть больше, чем periodSeconds * failureThreshold
+		// readinessProbe.
+		DrainDelay time.Duration `yaml:"drain_delay" env-default:"8s"`
+		// WorkerStaleAfter - через сколько без завершённого прохода фоновый
+		// процесс считается зависшим.
+		WorkerStaleAfter time.Duration `yaml:"worker_stale_after" env-default:"10m"`
+		// WorkersAffectReadiness - зависший фоновый процесс делает сервис
+		// неготовым. По умолчанию он только виден в /readyz: редиректы и
+		// API от него не зависят.
+		WorkersAffectReadiness bool `yaml:"workers_affect_readiness" env-default:"false"`
+	}
+
+	lifecycle struct {
+		// ShutdownTimeout - общий дедлайн остановки после SIGTERM вместе с
+		// health.drain_delay, должен быть меньше terminationGracePeriodSeconds.
+		ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env-default:"25s"`
+	}
+
+	api struct {
+		// Legacy - обслуживать API и по старым путям без /api/v1
+		// (/url, /tags, /webhooks) с заголовками Deprecation и Sunset.
+		Legacy bool `yaml:"legacy" env-default:"true"`
+		// LegacyDeprecatedAt и LegacySunset - даты для этих заголовков,
+		// например 2026-10-19T00:00:00Z.
+		LegacyDeprecatedAt time.Time `yaml:"legacy_deprecated_at"`
+		LegacySunset       time.Time `yaml:"legacy_sunset"`
+		// RequireKey - пускать в API только с ключом из keys create,
+		// перенаправления по alias доступны без ключа.
+		RequireKey bool `yaml:"require_key" env:"API_REQUIRE_KEY" env-default:"false"`
+	}
+
+	redirect struct {
+		NotActiveStatus      int    `yaml:"not_active_status" env-default:"403"`
+		NotActiveMessage     string `yaml:"not_active_message" env-default:"URL is not active yet"`
+		NotActiveFallbackURL string `yaml:"not_active_fallback_url"`
+		CountryHeader        string `yaml:"country_header" env-default:"CF-IPCountry"`
+	}
+
+	checker struct {
+		Enabled         bool          `yaml:"enabled" env-default:"true"`
+		Interval        time.Duration `yaml:"interval" env-default:"1m"`
+		RecheckAfter    time.Duration `yaml:"recheck_after" env-default:"1h"`
+		Timeout         time.Duration `yaml:"timeout" env-default:"10s"`
+		PerHostInterval time.Duration `yaml:"per_host_interval" env-default:"1s"`
+		Workers         int           `yaml:"workers" env-default:"4"`
+		BatchSize       int           `yaml:"batch_size" env-default:"100"`
+		// AllowPrivate разрешает проверять адреса во внутренней сети -
+		// только для локальной разработки.
+		AllowPrivate bool `yaml:"allow_private" env-default:"false"`
 	}
-)
 
-func MustLoad() *Config {
-	configPath := os.Getenv("CONFIG_PATH")
-	if configPath == "" {
-		log.Fatal("CONFIG_PATH is not set")
+	webhooks struct {
+		Enabled     bool          `yaml:"enabled" env-default:"true"`
+		Interval    time.Duration `yaml:"interval" env-default:"5s"`
+		Timeout     time.Duration `yaml:"timeout" env-default:"10s"`
+		MaxAttempts int           `yaml:"max_attempts" env-default:"8"`
+		BaseBackoff time.Duration `yaml:"base_backoff" env-default:"10s"`
+		MaxBackoff  time.Duration `yaml:"max_backoff" env-default:"1h"`
+		BatchSize   int           `yaml:"batch_size" env-default:"100"`
+		// AllowPrivate разрешает доставку на адреса во внутренней сети -
+		// только для локальной разработки.
+		AllowPrivate bool `yaml:"allow_private" env-default:"false"`
+		// Retention - сколько хранить доставленные события, 0 - хранить всегда.
+		Retention time.Duration `yaml:"retention" env-default:"168h"`
+		// PurgeInterval - как часто удалять старые события, 0 - не удалять.
+		PurgeInterval time.Duration `yaml:"purge_interval" env-default:"1h"`
 	}
 
+	idempotency struct {
+		TTL         time.Duration `yaml:"ttl" env-default:"24h"`
+		LockTimeout time.Duration `yaml:"lock_timeout" env-default:"1m"`
+		// PurgeInterval - как часто удалять истёкшие ключи, 0 - не удалять.
+		PurgeInterval time.Duration `yaml:"purge_interval" env-default:"1h"`
+	}
+
+	migrations struct {
+		// Mode: auto - применять миграции при запуске, check - не запускаться,
+		// если есть непримененные, ignore - не проверять.
+		Mode string `yaml:"mode" env:"MIGRATIONS_MODE" env-default:"auto"`
+		// LockTimeout - сколько ждать, пока миграции применяет другой экземпляр.
+		LockTimeout time.Duration `yaml:"lock_timeout" env-default:"5m"`
+	}
+
+	postgreURL struct {
+		URL string `yaml:"url" env:"POSTGRES_URL" env-required:"true"`
+
+		// ReadTimeout и WriteTimeout - предельное время одной операции
+		// с базой, 0 - без ограничения.
+		ReadTimeout  time.Duration `yaml:"read_timeout" env-default:"2s"`
+		WriteTimeout time.Duration `yaml:"write_timeout" env-default:"5s"`
+
+		MaxConns          int32         `yaml:"max_conns" env-default:"50"`
+		MinConns          int32         `yaml:"min_conns" env-default:"10"`
+		MaxConnLifetime   time.Duration `yaml:"max_conn_lifetime" env-default:"1h"`
+		MaxConnIdleTime   time.Duration `yaml:"max_conn_idle_time" env-default:"30m"`
+		HealthCheckPeriod time.Duration `yaml:"health_check_period" env-default:"1m"`
+		// StatementCacheMode: cache_statement, cache_describe, describe_exec,
+		// exec или simple_protocol (для PgBouncer в режиме transaction).
+		StatementCacheMode string `yaml:"statement_cache_mode" env-default:"cache_statement"`
+
+		// Проверка соединения при запуске: ConnectAttempts попыток с паузой
+		// от ConnectBackoff, удваивающейся до ConnectMaxBackoff.
+		ConnectAttempts   int           `yaml:"connect_attempts" env-default:"10"`
+		ConnectBackoff    time.Duration `yaml:"connect_backoff" env-default:"500ms"`
+		ConnectMaxBackoff time.Duration `yaml:"connect_max_backoff" env-default:"10s"`
+
+		// Replicas - DSN реплик для чтения ссылок и списков.
+		Replicas                 []string      `yaml:"replicas" env:"POSTGRES_REPLICAS" env-separator:","`
+		ReplicaHealthCheckPeriod time.Duration `yaml:"replica_health_check_period" env-default:"5s"`
+		// ReadYourWritesWindow - сколько после изменения данных чтения того
+		// же клиента идут на основную базу, 0 - не переключать.
+		ReadYourWritesWindow time.Duration `yaml:"read_your_writes_window" env-default:"5s"`
+		// ReadYourWritesSecret - общий для всех экземпляров ключ подписи
+		// метки изменения, пустой - метку принимает только выдавший её.
+		ReadYourWritesSecret string `yaml:"read_your_writes_secret" env:"READ_YOUR_WRITES_SECRET"`
+	}
+)
+
+// Load читает конфигурацию из файла configPath и переменных окружения.
+func Load(configPath string) (*Config, error) {
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		log.Fatalf("config file does not exist: %s", configPath)
+		return nil, fmt.Errorf("config file does not exist: %s", configPath)
 	}
 
 	var config Config
 
 	err := cleanenv.ReadConfig(configPath, &config)
 	if err != nil {
-		log.Fatal("Cant read config", err)
+		return nil, fmt.Errorf("cant read config: %w", err)
 	}
 
-	return &config
+	return &config, nil
 }
@@ -0,0 +1,164 @@
+// Package health - проверки готовности сервиса для /readyz.
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusOK          = "ok"
+	StatusFailed      = "failed"
+	StatusUnavailable = "unavailable"
+	// StatusDraining - сервис получил SIGTERM и ждёт, пока балансировщик
+	// перестанет направлять на него запросы.
+	StatusDraining = "draining"
+)
+
+// Check возвращает ошибку, если зависимость не готова обслуживать запросы.
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+	// informational - результат попадает в отчёт, но не влияет на готовность.
+	informational bool
+}
+
+// CheckResult - результат одной проверки.
+type CheckResult struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Report - ответ /readyz. Checks содержит и информационные проверки,
+// которые на Status не влияют.
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+func (r Report) OK() bool {
+	return r.Status == StatusOK
+}
+
+// Health хранит проверки готовности и признак остановки сервиса.
+type Health struct {
+	timeout  time.Duration
+	draining atomic.Bool
+
+	mu     sync.RWMutex
+	checks []namedCheck
+}
+
+// New создаёт Health, timeout ограничивает каждую проверку, 0 - без
+// ограничения.
+func New(timeout time.Duration) *Health {
+	return &Health{timeout: timeout}
+}
+
+func (h *Health) Register(name string, check Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.checks = append(h.checks, namedCheck{name: name, check: check})
+}
+
+// RegisterInfo добавляет проверку, которая видна в отчёте, но не делает
+// сервис неготовым: например, зависший фоновый процесс не мешает
+// обслуживать запросы.
+func (h *Health) RegisterInfo(name string, check Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.checks = append(h.checks, namedCheck{name: name, check: check, informational: true})
+}
+
+// Drain переводит сервис в неготовое состояние до завершения процесса.
+func (h *Health) Drain() {
+	h.draining.Store(true)
+}
+
+func (h *Health) Draining() bool {
+	return h.draining.Load()
+}
+
+// Ready выполняет проверки параллельно. После Drain проверки не
+// выполняются: ответ сразу StatusDraining.
+func (h *Health) Ready(ctx context.Context) Report {
+	if h.Draining() {
+		return Report{Status: StatusDraining}
+	}
+
+	h.mu.RLock()
+	checks := h.checks
+	h.mu.RUnlock()
+
+	results := make([]CheckResult, len(checks))
+
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = h.run(ctx, c.check)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]CheckResult, len(checks))}
+	for i, c := range checks {
+		report.Checks[c.name] = results[i]
+		if results[i].Status != StatusOK && !c.informational {
+			report.Status = StatusUnavailable
+		}
+	}
+
+	return report
+}
+
+func (h *Health) run(ctx context.Context, check Check) CheckResult {
+	if h.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	err := check(ctx)
+	res := CheckResult{Status: StatusOK, Duration: time.Since(start).String()}
+	if err != nil {
+		res.Status = StatusFailed
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// Heartbeat - проверка фонового процесса: он вызывает Beat после каждого
+// прохода, и проверка не проходит, если последнего вызова не было дольше
+// maxAge. Отсчёт идёт с момента создания.
+type Heartbeat struct {
+	maxAge time.Duration
+	last   atomic.Int64
+}
+
+func NewHeartbeat(maxAge time.Duration) *Heartbeat {
+	hb := &Heartbeat{maxAge: maxAge}
+	hb.Beat()
+	return hb
+}
+
+func (hb *Heartbeat) Beat() {
+	hb.last.Store(time.Now().UnixNano())
+}
+
+func (hb *Heartbeat) Check(ctx context.Context) error {
+	if age := time.Since(time.Unix(0, hb.last.Load())); age > hb.maxAge {
+		return fmt.Errorf("no heartbeat for %s", age.Round(time.Second))
+	}
+	return nil
+}
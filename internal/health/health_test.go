@@ -0,0 +1,54 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RozmiDan/url_shortener/internal/health"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReady(t *testing.T) {
+	h := health.New(20 * time.Millisecond)
+	h.Register("storage", func(ctx context.Context) error { return nil })
+	h.Register("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	report := h.Ready(context.Background())
+	assert.False(t, report.OK())
+	assert.Equal(t, health.StatusUnavailable, report.Status)
+	assert.Equal(t, health.StatusOK, report.Checks["storage"].Status)
+	assert.Equal(t, health.StatusFailed, report.Checks["slow"].Status)
+	assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks["slow"].Error)
+
+	h.Drain()
+
+	report = h.Ready(context.Background())
+	assert.Equal(t, health.Report{Status: health.StatusDraining}, report)
+}
+
+func TestReadyInformational(t *testing.T) {
+	h := health.New(time.Second)
+	h.Register("storage", func(ctx context.Context) error { return nil })
+	h.RegisterInfo("checker", func(ctx context.Context) error { return errors.New("no heartbeat for 11m0s") })
+
+	report := h.Ready(context.Background())
+	assert.True(t, report.OK())
+	assert.Equal(t, health.StatusFailed, report.Checks["checker"].Status)
+	assert.Equal(t, "no heartbeat for 11m0s", report.Checks["checker"].Error)
+}
+
+func TestHeartbeat(t *testing.T) {
+	hb := health.NewHeartbeat(30 * time.Millisecond)
+	assert.NoError(t, hb.Check(context.Background()))
+
+	time.Sleep(40 * time.Millisecond)
+	assert.ErrorContains(t, hb.Check(context.Background()), "no heartbeat for")
+
+	hb.Beat()
+	assert.NoError(t, hb.Check(context.Background()))
+}
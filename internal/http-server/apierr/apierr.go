@@ -0,0 +1,196 @@
+// Package apierr - ошибки API в формате RFC 7807 (application/problem+json).
+// Клиент различает ошибки по полю code, title и detail - для человека.
+package apierr
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/RozmiDan/url_shortener/internal/http-server/ctxerr"
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/RozmiDan/url_shortener/internal/usecase/links"
+	"github.com/go-chi/chi/middleware"
+)
+
+const ContentType = "application/problem+json"
+
+// typePrefix + code - поле type ответа.
+const typePrefix = "urn:url-shortener:problem:"
+
+// Code - стабильный машиночитаемый код ошибки.
+type Code string
+
+const (
+	CodeBadRequest       Code = "bad_request"
+	CodeUnauthorized     Code = "unauthorized"
+	CodeRequestTooLarge  Code = "request_too_large"
+	CodeNotFound         Code = "not_found"
+	CodeMethodNotAllowed Code = "method_not_allowed"
+	CodeNotAcceptable    Code = "not_acceptable"
+	CodeValidationFailed Code = "validation_failed"
+	CodeLinkNotFound     Code = "link_not_found"
+	CodeAliasExists      Code = "alias_exists"
+	CodeAliasesExhausted Code = "aliases_exhausted"
+	CodeVersionConflict  Code = "version_conflict"
+	CodeLinkNotActive    Code = "link_not_active"
+	CodeLinkExpired      Code = "link_expired"
+	CodeLinkExhausted    Code = "link_exhausted"
+	CodeRuleNotFound     Code = "rule_not_found"
+	CodeTagNotFound      Code = "tag_not_found"
+	CodeTagExists        Code = "tag_exists"
+	CodeWebhookNotFound  Code = "webhook_not_found"
+	CodeDeliveryNotFound Code = "delivery_not_found"
+	// CodeRequestInProgress - запрос с тем же Idempotency-Key ещё выполняется.
+	CodeRequestInProgress Code = "request_in_progress"
+	// CodeIdempotencyKeyReused - Idempotency-Key уже использован с другим телом.
+	CodeIdempotencyKeyReused Code = "idempotency_key_reused"
+	CodeTimeout              Code = "timeout"
+	CodeCanceled             Code = "canceled"
+	CodeInternal             Code = "internal"
+)
+
+var titles = map[Code]string{
+	CodeBadRequest:           "Bad request",
+	CodeUnauthorized:         "Unauthorized",
+	CodeRequestTooLarge:      "Request body is too large",
+	CodeNotFound:             "Not found",
+	CodeMethodNotAllowed:     "Method not allowed",
+	CodeNotAcceptable:        "Not acceptable",
+	CodeValidationFailed:     "Validation failed",
+	CodeLinkNotFound:         "Link not found",
+	CodeAliasExists:          "Alias already exists",
+	CodeAliasesExhausted:     "No free alias",
+	CodeVersionConflict:      "Link was modified concurrently",
+	CodeLinkNotActive:        "Link is not active yet",
+	CodeLinkExpired:          "Link has expired",
+	CodeLinkExhausted:        "Clicks limit exhausted",
+	CodeRuleNotFound:         "Rule not found",
+	CodeTagNotFound:          "Tag not found",
+	CodeTagExists:            "Tag already exists",
+	CodeWebhookNotFound:      "Webhook not found",
+	CodeDeliveryNotFound:     "Delivery not found",
+	CodeRequestInProgress:    "Request in progress",
+	CodeIdempotencyKeyReused: "Idempotency key reused",
+	CodeTimeout:              "Storage timeout",
+	CodeCanceled:             "Request canceled",
+	CodeInternal:             "Internal error",
+}
+
+// FieldError - поле запроса, не прошедшее проверку.
+type FieldError struct {
+	Field  string `json:"field" example:"url"`
+	Reason string `json:"reason" example:"must be a valid URL"`
+}
+
+// Problem - тело ответа с ошибкой.
+type Problem struct {
+	Type   string `json:"type" example:"urn:url-shortener:problem:link_not_found"`
+	Title  string `json:"title" example:"Link not found"`
+	Status int    `json:"status" example:"404"`
+	Detail string `json:"detail,omitempty" example:"alias not found"`
+	// Instance - request id запроса, он же в логах сервера.
+	Instance string       `json:"instance,omitempty"`
+	Code     Code         `json:"code" example:"link_not_found"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+func New(status int, code Code, detail string) Problem {
+	return Problem{
+		Type:   typePrefix + string(code),
+		Title:  titles[code],
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// BadRequest - запрос не удалось разобрать: тело, параметр пути или запроса.
+func BadRequest(detail string) Problem {
+	return New(http.StatusBadRequest, CodeBadRequest, detail)
+}
+
+// FromError возвращает ответ для ошибки хранилища, сервиса ссылок или
+// контекста запроса. Неизвестная ошибка - 500 без подробностей.
+func FromError(err error) Problem {
+	var verr *links.ValidationError
+	if errors.As(err, &verr) {
+		p := New(http.StatusBadRequest, CodeValidationFailed, verr.Reason)
+		if verr.Field != "" {
+			p.Errors = []FieldError{{Field: verr.Field, Reason: verr.Reason}}
+		}
+		return p
+	}
+
+	switch {
+	case errors.Is(err, storage.ErrAliasNotFound), errors.Is(err, storage.ErrURLNotFound):
+		return New(http.StatusNotFound, CodeLinkNotFound, "alias not found")
+	case errors.Is(err, storage.ErrAliasExists):
+		return New(http.StatusConflict, CodeAliasExists, "alias already exists")
+	case errors.Is(err, links.ErrAliasesExhausted):
+		return New(http.StatusServiceUnavailable, CodeAliasesExhausted, "could not generate a free alias, retry the request")
+	case errors.Is(err, storage.ErrVersionConflict):
+		return New(http.StatusPreconditionFailed, CodeVersionConflict, "link was modified concurrently")
+	case errors.Is(err, storage.ErrURLNotActive):
+		return New(http.StatusForbidden, CodeLinkNotActive, "link is not active yet")
+	case errors.Is(err, storage.ErrURLExpired):
+		return New(http.StatusGone, CodeLinkExpired, "link has expired")
+	case errors.Is(err, storage.ErrURLExhausted):
+		return New(http.StatusGone, CodeLinkExhausted, "clicks limit exhausted")
+	case errors.Is(err, storage.ErrRuleNotFound):
+		return New(http.StatusNotFound, CodeRuleNotFound, "rule not found")
+	case errors.Is(err, storage.ErrTagNotFound):
+		return New(http.StatusNotFound, CodeTagNotFound, "tag not found")
+	case errors.Is(err, storage.ErrTagExists):
+		return New(http.StatusConflict, CodeTagExists, "tag already exists")
+	case errors.Is(err, storage.ErrWebhookNotFound):
+		return New(http.StatusNotFound, CodeWebhookNotFound, "webhook not found")
+	case errors.Is(err, storage.ErrDeliveryNotFound):
+		return New(http.StatusNotFound, CodeDeliveryNotFound, "delivery not found")
+	}
+
+	if status, msg, ok := ctxerr.Status(err); ok {
+		code := CodeCanceled
+		if status == http.StatusGatewayTimeout {
+			code = CodeTimeout
+		}
+		return New(status, code, msg)
+	}
+
+	return New(http.StatusInternalServerError, CodeInternal, "internal error")
+}
+
+// Write отправляет p, подставляя request id в instance.
+func Write(w http.ResponseWriter, r *http.Request, p Problem) {
+	if p.Instance == "" {
+		p.Instance = middleware.GetReqID(r.Context())
+	}
+
+	body, err := json.Marshal(p)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(p.Status)
+	_, _ = w.Write(append(body, '\n'))
+}
+
+// Render логирует err и отправляет ответ для неё: ожидаемые ошибки на
+// уровне Debug, ошибки контекста - Warn, остальные - Error.
+func Render(w http.ResponseWriter, r *http.Request, logger *slog.Logger, err error) {
+	p := FromError(err)
+
+	switch p.Code {
+	case CodeInternal, CodeAliasesExhausted:
+		logger.Error("request failed", slog.Any("err", err))
+	case CodeTimeout, CodeCanceled:
+		logger.Warn("request failed", slog.Any("err", err))
+	default:
+		logger.Debug("request failed", slog.Any("err", err))
+	}
+
+	Write(w, r, p)
+}
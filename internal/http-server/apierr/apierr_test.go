@@ -0,0 +1,107 @@
+package apierr_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/RozmiDan/url_shortener/internal/http-server/apierr"
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/RozmiDan/url_shortener/internal/usecase/links"
+	"github.com/go-chi/chi/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromError(t *testing.T) {
+	testCases := []struct {
+		err    error
+		status int
+		code   apierr.Code
+	}{
+		{storage.ErrAliasNotFound, http.StatusNotFound, apierr.CodeLinkNotFound},
+		{storage.ErrURLNotFound, http.StatusNotFound, apierr.CodeLinkNotFound},
+		{storage.ErrAliasExists, http.StatusConflict, apierr.CodeAliasExists},
+		{storage.ErrVersionConflict, http.StatusPreconditionFailed, apierr.CodeVersionConflict},
+		{storage.ErrURLExpired, http.StatusGone, apierr.CodeLinkExpired},
+		{storage.ErrURLExhausted, http.StatusGone, apierr.CodeLinkExhausted},
+		{storage.ErrTagExists, http.StatusConflict, apierr.CodeTagExists},
+		{storage.ErrWebhookNotFound, http.StatusNotFound, apierr.CodeWebhookNotFound},
+		{links.ErrAliasesExhausted, http.StatusServiceUnavailable, apierr.CodeAliasesExhausted},
+		{context.DeadlineExceeded, http.StatusGatewayTimeout, apierr.CodeTimeout},
+		{context.Canceled, http.StatusServiceUnavailable, apierr.CodeCanceled},
+		{errors.New("boom"), http.StatusInternalServerError, apierr.CodeInternal},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.code), func(t *testing.T) {
+			p := apierr.FromError(fmt.Errorf("storage.postgre.X: %w", tc.err))
+			assert.Equal(t, tc.status, p.Status)
+			assert.Equal(t, tc.code, p.Code)
+			assert.Equal(t, "urn:url-shortener:problem:"+string(tc.code), p.Type)
+			assert.NotEmpty(t, p.Title)
+		})
+	}
+
+	p := apierr.FromError(&links.ValidationError{Field: "url", Reason: "url must be an absolute URL"})
+	assert.Equal(t, apierr.CodeValidationFailed, p.Code)
+	assert.Equal(t, []apierr.FieldError{{Field: "url", Reason: "url must be an absolute URL"}}, p.Errors)
+}
+
+func TestInvalid(t *testing.T) {
+	type variant struct {
+		URL string `json:"url" validate:"required,url"`
+	}
+	type request struct {
+		Alias    string    `json:"alias,omitempty" validate:"omitempty,excludesall=+"`
+		Tags     []string  `json:"tags" validate:"max=2"`
+		Variants []variant `json:"variants" validate:"dive"`
+	}
+
+	err := apierr.Validate(request{
+		Alias:    "a+b",
+		Tags:     []string{"a", "b", "c"},
+		Variants: []variant{{URL: "https://example.com"}, {}},
+	})
+	require.Error(t, err)
+
+	p := apierr.Invalid(err)
+	assert.Equal(t, http.StatusBadRequest, p.Status)
+	assert.Equal(t, apierr.CodeValidationFailed, p.Code)
+	assert.Equal(t, []apierr.FieldError{
+		{Field: "alias", Reason: `must not contain any of "+"`},
+		{Field: "tags", Reason: "must contain at most 2 items"},
+		{Field: "variants[1].url", Reason: "is required"},
+	}, p.Errors)
+
+	p = apierr.Invalid(errors.New("unknown device \"tv\""))
+	assert.Equal(t, `unknown device "tv"`, p.Detail)
+	assert.Empty(t, p.Errors)
+}
+
+func TestRender(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+
+	handler := middleware.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apierr.Render(w, r, logger, storage.ErrAliasNotFound)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/url/missing", nil))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, apierr.ContentType, rec.Header().Get("Content-Type"))
+
+	var p apierr.Problem
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &p))
+	assert.Equal(t, apierr.CodeLinkNotFound, p.Code)
+	assert.Equal(t, "Link not found", p.Title)
+	assert.Equal(t, "alias not found", p.Detail)
+	assert.NotEmpty(t, p.Instance)
+}
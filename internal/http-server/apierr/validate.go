@@ -0,0 +1,87 @@
+package apierr
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator"
+)
+
+var validate = newValidator()
+
+// newValidator называет поля в ошибках так же, как в JSON запроса.
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(fld reflect.StructField) string {
+		name, _, _ := strings.Cut(fld.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	return v
+}
+
+// Validate проверяет теги validate структуры запроса. Ошибку
+// превращает в ответ Invalid.
+func Validate(req any) error {
+	return validate.Struct(req)
+}
+
+// Invalid возвращает ответ 400 со списком полей, не прошедших проверку.
+// Ошибка не от валидатора передаётся в detail как есть.
+func Invalid(err error) Problem {
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return New(http.StatusBadRequest, CodeValidationFailed, err.Error())
+	}
+
+	p := New(http.StatusBadRequest, CodeValidationFailed, "invalid request parameters")
+	for _, fe := range verrs {
+		p.Errors = append(p.Errors, FieldError{
+			Field:  fieldPath(fe.Namespace()),
+			Reason: reason(fe),
+		})
+	}
+	return p
+}
+
+// fieldPath убирает имя структуры запроса: "Request.variants[0].url" -
+// "variants[0].url".
+func fieldPath(namespace string) string {
+	_, path, ok := strings.Cut(namespace, ".")
+	if !ok {
+		return namespace
+	}
+	return path
+}
+
+func reason(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "url":
+		return "must be a valid URL"
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "excludesall":
+		return `must not contain any of "` + fe.Param() + `"`
+	case "min", "max":
+		bound := "at least "
+		if fe.Tag() == "max" {
+			bound = "at most "
+		}
+		switch fe.Kind() {
+		case reflect.String:
+			return "must be " + bound + fe.Param() + " characters long"
+		case reflect.Slice, reflect.Map, reflect.Array:
+			return "must contain " + bound + fe.Param() + " items"
+		default:
+			return "must be " + bound + fe.Param()
+		}
+	default:
+		return "failed the " + fe.Tag() + " check"
+	}
+}
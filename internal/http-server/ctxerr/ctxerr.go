@@ -0,0 +1,22 @@
+package ctxerr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// Status возвращает код ответа и сообщение для ошибки, вызванной
+// контекстом запроса: 504, если хранилище не уложилось в отведённое
+// время, и 503, если запрос отменён (клиент отключился или сервер
+// останавливается). ok = false для остальных ошибок.
+func Status(err error) (status int, msg string, ok bool) {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, "storage timeout", true
+	case errors.Is(err, context.Canceled):
+		return http.StatusServiceUnavailable, "request canceled", true
+	default:
+		return 0, "", false
+	}
+}
@@ -0,0 +1,25 @@
+package ctxerr_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/RozmiDan/url_shortener/internal/http-server/ctxerr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatus(t *testing.T) {
+	status, _, ok := ctxerr.Status(fmt.Errorf("storage.postgre.GetURL: %w", context.DeadlineExceeded))
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusGatewayTimeout, status)
+
+	status, _, ok = ctxerr.Status(fmt.Errorf("storage.postgre.GetURL: %w", context.Canceled))
+	assert.True(t, ok)
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+
+	_, _, ok = ctxerr.Status(errors.New("boom"))
+	assert.False(t, ok)
+}
@@ -0,0 +1,46 @@
+package etag
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/RozmiDan/url_shortener/internal/storage"
+)
+
+// Format возвращает ETag версии ссылки.
+func Format(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// Expected возвращает версию, которую клиент собирается изменить, по
+// заголовку If-Match и полю expected_version. 0 - версия не указана.
+// Если If-Match не может совпасть ни с одной версией или противоречит
+// expected_version, возвращается storage.ErrVersionConflict.
+func Expected(ifMatch string, expectedVersion int64) (int64, error) {
+	ifMatch = strings.TrimSpace(ifMatch)
+	if ifMatch == "" || ifMatch == "*" {
+		return expectedVersion, nil
+	}
+
+	// If-Match использует строгое сравнение, слабые ETag и списки
+	// тегов не совпадают ни с одной версией.
+	tag, ok := strings.CutPrefix(ifMatch, `"`)
+	if !ok {
+		return 0, storage.ErrVersionConflict
+	}
+	tag, ok = strings.CutSuffix(tag, `"`)
+	if !ok {
+		return 0, storage.ErrVersionConflict
+	}
+
+	version, err := strconv.ParseInt(tag, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, storage.ErrVersionConflict
+	}
+
+	if expectedVersion != 0 && expectedVersion != version {
+		return 0, storage.ErrVersionConflict
+	}
+
+	return version, nil
+}
@@ -0,0 +1,41 @@
+package etag_test
+
+import (
+	"testing"
+
+	"github.com/RozmiDan/url_shortener/internal/http-server/etag"
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpected(t *testing.T) {
+	testCases := []struct {
+		name            string
+		ifMatch         string
+		expectedVersion int64
+		want            int64
+		wantErr         error
+	}{
+		{name: "nothing", want: 0},
+		{name: "expected version only", expectedVersion: 3, want: 3},
+		{name: "any", ifMatch: "*", want: 0},
+		{name: "etag", ifMatch: etag.Format(7), want: 7},
+		{name: "etag and same version", ifMatch: `"7"`, expectedVersion: 7, want: 7},
+		{name: "etag and other version", ifMatch: `"7"`, expectedVersion: 6, wantErr: storage.ErrVersionConflict},
+		{name: "weak etag", ifMatch: `W/"7"`, wantErr: storage.ErrVersionConflict},
+		{name: "list", ifMatch: `"7", "8"`, wantErr: storage.ErrVersionConflict},
+		{name: "garbage", ifMatch: `"abc"`, wantErr: storage.ErrVersionConflict},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := etag.Expected(tc.ifMatch, tc.expectedVersion)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package broken_handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/RozmiDan/url_shortener/internal/http-server/apierr"
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+)
+
+type BrokenLister interface {
+	ListBroken(ctx context.Context) ([]storage.BrokenLink, error)
+}
+
+type Response struct {
+	Status string               `json:"status"`
+	Links  []storage.BrokenLink `json:"links"`
+}
+
+// @Title List broken links
+// @Description Return links whose target URL failed the last health check
+// @Tags url
+// @Produce json
+// @Success 200 {object} Response
+// @Failure 500 {object} apierr.Problem "internal"
+// @Router /api/v1/url/broken [get]
+func NewBrokenHandler(logger *slog.Logger, brokenLister BrokenLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.broken.NewBrokenHandler"
+
+		opLogger := logger.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		links, err := brokenLister.ListBroken(r.Context())
+		if err != nil {
+			apierr.Render(w, r, opLogger, err)
+			return
+		}
+
+		if links == nil {
+			links = []storage.BrokenLink{}
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{
+			Status: "OK",
+			Links:  links,
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package broken_handler_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	broken_handler "github.com/RozmiDan/url_shortener/internal/http-server/handlers/broken"
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/RozmiDan/url_shortener/internal/usecase/links"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockBrokenLister struct {
+	links.Repository
+	mock.Mock
+}
+
+func (m *MockBrokenLister) ListBroken(ctx context.Context) ([]storage.BrokenLink, error) {
+	args := m.Called()
+	return args.Get(0).([]storage.BrokenLink), args.Error(1)
+}
+
+func TestBrokenHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	checkedAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name             string
+		links            []storage.BrokenLink
+		mockErr          error
+		expectedStatus   int
+		expectedContains string
+	}{
+		{
+			name: "success",
+			links: []storage.BrokenLink{
+				{Alias: "gone", URL: "https://example.com/gone", LastStatus: 404, LatencyMs: 35, CheckedAt: checkedAt},
+			},
+			expectedStatus:   http.StatusOK,
+			expectedContains: `{"alias":"gone","url":"https://example.com/gone","last_status":404,"latency_ms":35,"checked_at":"2025-03-01T12:00:00Z"}`,
+		},
+		{
+			name:             "empty list",
+			links:            nil,
+			expectedStatus:   http.StatusOK,
+			expectedContains: `"links":[]`,
+		},
+		{
+			name:             "internal error",
+			links:            nil,
+			mockErr:          errors.New("some internal error"),
+			expectedStatus:   http.StatusInternalServerError,
+			expectedContains: `"detail":"internal error"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			lister := new(MockBrokenLister)
+			lister.On("ListBroken").Return(tc.links, tc.mockErr)
+
+			handler := broken_handler.NewBrokenHandler(logger, links.New(lister, links.Config{}))
+
+			req := httptest.NewRequest(http.MethodGet, "/url/broken", nil)
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			assert.Contains(t, rec.Body.String(), tc.expectedContains)
+		})
+	}
+}
@@ -1,36 +1,42 @@
 package delete_handler
 
 import (
-	"errors"
+	"context"
 	"log/slog"
 	"net/http"
+	"strconv"
 
-	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/RozmiDan/url_shortener/internal/http-server/apierr"
+	"github.com/RozmiDan/url_shortener/internal/http-server/etag"
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/render"
 )
 
-type URLDeleter interface {
-	DeleteURL(alias string) error
+type LinkDeleter interface {
+	Delete(ctx context.Context, alias string, expectedVersion int64) error
 }
 
 type Response struct {
 	Status string `json:"status"`
-	Error  string `json:"error,omitempty"`
 }
 
 // @Title Delete URL by alias
-// @Description Delete existing short URL
+// @Description Delete existing short URL. If-Match or expected_version makes the deletion fail with 412
+// @Description if the link was changed since that version.
 // @Tags url
 // @Accept  json
 // @Produce json
-// @Param   alias path string true "Short URL alias"
+// @Param   alias             path    string   true   "Short URL alias"
+// @Param   If-Match          header  string   false  "ETag of the link version being deleted"
+// @Param   expected_version  query   integer  false  "Version of the link being deleted"
 // @Success 200 {object} Response
-// @Failure 404 {object} Response "Alias not found"
-// @Failure 500 {object} Response "Internal server error"
-// @Router /url/{alias} [delete]
-func NewDeleteHandler(logger *slog.Logger, urlDeleter URLDeleter) http.HandlerFunc {
+// @Failure 400 {object} apierr.Problem "bad_request: invalid expected_version"
+// @Failure 404 {object} apierr.Problem "link_not_found"
+// @Failure 412 {object} apierr.Problem "version_conflict"
+// @Failure 500 {object} apierr.Problem "internal"
+// @Router /api/v1/url/{alias} [delete]
+func NewDeleteHandler(logger *slog.Logger, deleter LinkDeleter) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.delete.newsavehandler"
 
@@ -43,33 +49,28 @@ func NewDeleteHandler(logger *slog.Logger, urlDeleter URLDeleter) http.HandlerFu
 
 		if reqAlias == "" {
 			logger.Error("empty current alias")
-			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, Response{
-				Status: "Error",
-				Error:  "empty current alias",
-			})
+			apierr.Write(w, r, apierr.BadRequest("empty current alias"))
 			return
 		}
 
-		//logger.Info("request body decoded\n")
-
-		if err := urlDeleter.DeleteURL(reqAlias); err != nil {
-			if errors.Is(err, storage.ErrAliasNotFound) {
-				logger.Debug("Cant delete alias\n", slog.Any("err", err))
-				render.Status(r, http.StatusNotFound)
-				render.JSON(w, r, Response{
-					Status: "Error",
-					Error:  "alias not found",
-				})
+		var expectedVersion int64
+		if raw := r.URL.Query().Get("expected_version"); raw != "" {
+			v, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil || v <= 0 {
+				logger.Debug("invalid expected_version", slog.String("expected_version", raw))
+				apierr.Write(w, r, apierr.BadRequest("expected_version must be a positive integer"))
 				return
 			}
+			expectedVersion = v
+		}
+
+		expectedVersion, err := etag.Expected(r.Header.Get("If-Match"), expectedVersion)
+		if err == nil {
+			err = deleter.Delete(r.Context(), reqAlias, expectedVersion)
+		}
 
-			logger.Error("Cant delete alias\n", slog.Any("err", err))
-			render.Status(r, http.StatusInternalServerError)
-			render.JSON(w, r, Response{
-				Status: "Error",
-				Error:  "internal error",
-			})
+		if err != nil {
+			apierr.Render(w, r, logger, err)
 			return
 		}
 
@@ -2,6 +2,7 @@ package delete_handler_test
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -9,11 +10,13 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/RozmiDan/url_shortener/internal/http-server/apierr"
 	delete_handler "github.com/RozmiDan/url_shortener/internal/http-server/handlers/delete"
 	"github.com/RozmiDan/url_shortener/internal/storage"
 	"github.com/go-chi/chi"
 	"github.com/go-chi/render"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"gopkg.in/go-playground/assert.v1"
 )
 
@@ -21,8 +24,8 @@ type MockURLDeleter struct {
 	mock.Mock
 }
 
-func (m *MockURLDeleter) DeleteURL(alias string) error {
-	args := m.Called(alias)
+func (m *MockURLDeleter) Delete(ctx context.Context, alias string, expectedVersion int64) error {
+	args := m.Called(alias, expectedVersion)
 	return args.Error(0)
 }
 
@@ -30,44 +33,71 @@ func TestDeleteHandler(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(bytes.NewBuffer(nil), nil))
 
 	testCases := []struct {
-		name             string
-		alias            string
-		mockErr          error
-		expectedStatus   int
-		expectedResponse delete_handler.Response
-		expectCall       bool
+		name            string
+		alias           string
+		query           string
+		ifMatch         string
+		expectedVersion int64
+		mockErr         error
+		expectedStatus  int
+		expectedCode    apierr.Code
+		expectCall      bool
 	}{
 		{
 			name:           "success",
 			alias:          "valid-alias",
 			mockErr:        nil,
 			expectedStatus: http.StatusOK,
-			expectedResponse: delete_handler.Response{
-				Status: "OK",
-			},
-			expectCall: true,
+			expectCall:     true,
 		},
 		{
 			name:           "not found",
 			alias:          "non-existent",
 			mockErr:        storage.ErrAliasNotFound,
 			expectedStatus: http.StatusNotFound,
-			expectedResponse: delete_handler.Response{
-				Status: "Error",
-				Error:  "alias not found",
-			},
-			expectCall: true,
+			expectedCode:   apierr.CodeLinkNotFound,
+			expectCall:     true,
+		},
+		{
+			name:            "if-match",
+			alias:           "valid-alias",
+			ifMatch:         `"3"`,
+			expectedVersion: 3,
+			expectedStatus:  http.StatusOK,
+			expectCall:      true,
+		},
+		{
+			name:            "version conflict",
+			alias:           "valid-alias",
+			query:           "?expected_version=2",
+			expectedVersion: 2,
+			mockErr:         storage.ErrVersionConflict,
+			expectedStatus:  http.StatusPreconditionFailed,
+			expectedCode:    apierr.CodeVersionConflict,
+			expectCall:      true,
+		},
+		{
+			name:           "if-match and expected_version differ",
+			alias:          "valid-alias",
+			query:          "?expected_version=2",
+			ifMatch:        `"3"`,
+			expectedStatus: http.StatusPreconditionFailed,
+			expectedCode:   apierr.CodeVersionConflict,
+		},
+		{
+			name:           "invalid expected_version",
+			alias:          "valid-alias",
+			query:          "?expected_version=abc",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   apierr.CodeBadRequest,
 		},
 		{
 			name:           "internal error",
 			alias:          "error-alias",
 			mockErr:        errors.New("some internal error"),
 			expectedStatus: http.StatusInternalServerError,
-			expectedResponse: delete_handler.Response{
-				Status: "Error",
-				Error:  "internal error",
-			},
-			expectCall: true,
+			expectedCode:   apierr.CodeInternal,
+			expectCall:     true,
 		},
 	}
 
@@ -77,27 +107,37 @@ func TestDeleteHandler(t *testing.T) {
 			handler := delete_handler.NewDeleteHandler(logger, mockDeleter)
 
 			if tc.expectCall {
-				mockDeleter.On("DeleteURL", tc.alias).Return(tc.mockErr)
+				mockDeleter.On("Delete", tc.alias, tc.expectedVersion).Return(tc.mockErr)
 			}
 
 			r := chi.NewRouter()
 			r.Delete("/url/{alias}", handler)
 
-			req := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/url/%s", tc.alias), nil)
+			req := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/url/%s%s", tc.alias, tc.query), nil)
+			if tc.ifMatch != "" {
+				req.Header.Set("If-Match", tc.ifMatch)
+			}
 			rec := httptest.NewRecorder()
 
 			r.ServeHTTP(rec, req)
 
-			var response delete_handler.Response
-			render.DecodeJSON(rec.Body, &response)
-
 			assert.Equal(t, tc.expectedStatus, rec.Code)
-			assert.Equal(t, tc.expectedResponse, response)
+
+			if tc.expectedCode == "" {
+				var response delete_handler.Response
+				require.NoError(t, render.DecodeJSON(rec.Body, &response))
+				assert.Equal(t, "OK", response.Status)
+			} else {
+				var problem apierr.Problem
+				require.NoError(t, render.DecodeJSON(rec.Body, &problem))
+				assert.Equal(t, tc.expectedCode, problem.Code)
+				assert.Equal(t, tc.expectedStatus, problem.Status)
+			}
 
 			if tc.expectCall {
-				mockDeleter.AssertCalled(t, "DeleteURL", tc.alias)
+				mockDeleter.AssertCalled(t, "Delete", tc.alias, tc.expectedVersion)
 			} else {
-				mockDeleter.AssertNotCalled(t, "DeleteURL")
+				mockDeleter.AssertNotCalled(t, "Delete")
 			}
 		})
 	}
@@ -0,0 +1,118 @@
+package list_handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/RozmiDan/url_shortener/internal/http-server/apierr"
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 500
+
+	metadataPrefix = "meta."
+)
+
+type LinkLister interface {
+	List(ctx context.Context, filter storage.ListFilter) ([]storage.LinkInfo, error)
+}
+
+type Response struct {
+	Status string             `json:"status"`
+	Links  []storage.LinkInfo `json:"links"`
+}
+
+// @Title List links
+// @Description Return links filtered by tags (all must match), folder, metadata and a substring of alias or URL.
+// @Description Metadata filters are passed as meta.<key>=<value> and match string values.
+// @Tags url
+// @Produce json
+// @Param   tag     query  []string  false  "Tag, may be repeated"  collectionFormat(multi)
+// @Param   folder  query  string    false  "Folder"
+// @Param   q       query  string    false  "Substring of alias or URL"
+// @Param   limit   query  int       false  "Page size, 50 by default, at most 500"
+// @Param   offset  query  int       false  "Page offset"
+// @Success 200 {object} Response
+// @Failure 400 {object} apierr.Problem "bad_request: invalid query parameters"
+// @Failure 500 {object} apierr.Problem "internal"
+// @Router /api/v1/url [get]
+func NewListHandler(logger *slog.Logger, linkLister LinkLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.list.NewListHandler"
+
+		opLogger := logger.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		filter, err := parseFilter(r)
+		if err != nil {
+			opLogger.Debug("invalid query", slog.Any("err", err))
+			apierr.Write(w, r, apierr.BadRequest("invalid query parameters"))
+			return
+		}
+
+		links, err := linkLister.List(r.Context(), filter)
+		if err != nil {
+			apierr.Render(w, r, opLogger, err)
+			return
+		}
+
+		if links == nil {
+			links = []storage.LinkInfo{}
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{
+			Status: "OK",
+			Links:  links,
+		})
+	}
+}
+
+func parseFilter(r *http.Request) (storage.ListFilter, error) {
+	query := r.URL.Query()
+
+	filter := storage.ListFilter{
+		Tags:   query["tag"],
+		Folder: query.Get("folder"),
+		Query:  query.Get("q"),
+		Limit:  defaultLimit,
+	}
+
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 || limit > maxLimit {
+			return storage.ListFilter{}, strconv.ErrRange
+		}
+		filter.Limit = limit
+	}
+
+	if raw := query.Get("offset"); raw != "" {
+		offset, err := strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			return storage.ListFilter{}, strconv.ErrRange
+		}
+		filter.Offset = offset
+	}
+
+	for key, values := range query {
+		name, ok := strings.CutPrefix(key, metadataPrefix)
+		if !ok || name == "" {
+			continue
+		}
+		if filter.Metadata == nil {
+			filter.Metadata = make(map[string]string)
+		}
+		filter.Metadata[name] = values[0]
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,108 @@
+package list_handler_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	list_handler "github.com/RozmiDan/url_shortener/internal/http-server/handlers/list"
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/RozmiDan/url_shortener/internal/usecase/links"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockLinkLister struct {
+	links.Repository
+	mock.Mock
+}
+
+func (m *MockLinkLister) ListLinks(ctx context.Context, filter storage.ListFilter) ([]storage.LinkInfo, error) {
+	args := m.Called(filter)
+	return args.Get(0).([]storage.LinkInfo), args.Error(1)
+}
+
+func TestListHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+
+	testCases := []struct {
+		name             string
+		query            string
+		filter           storage.ListFilter
+		links            []storage.LinkInfo
+		mockErr          error
+		expectedStatus   int
+		expectedContains string
+	}{
+		{
+			name:  "filters parsed",
+			query: "?tag=Promo&tag=spring&folder=summer-sale&meta.owner=crm&q=shoes&limit=10&offset=20",
+			filter: storage.ListFilter{
+				Tags:     []string{"promo", "spring"},
+				Folder:   "summer-sale",
+				Metadata: map[string]string{"owner": "crm"},
+				Query:    "shoes",
+				Limit:    10,
+				Offset:   20,
+			},
+			links: []storage.LinkInfo{{
+				Alias:        "shoes",
+				URL:          "https://example.com/shoes",
+				Organization: storage.Organization{Tags: []string{"promo", "spring"}, Folder: "summer-sale"},
+			}},
+			expectedStatus:   http.StatusOK,
+			expectedContains: `"tags":["promo","spring"],"folder":"summer-sale"`,
+		},
+		{
+			name:             "default limit",
+			query:            "",
+			filter:           storage.ListFilter{Limit: 50},
+			expectedStatus:   http.StatusOK,
+			expectedContains: `"links":[]`,
+		},
+		{
+			name:             "limit too big",
+			query:            "?limit=100000",
+			expectedStatus:   http.StatusBadRequest,
+			expectedContains: `"detail":"invalid query parameters"`,
+		},
+		{
+			name:             "storage timeout",
+			query:            "",
+			filter:           storage.ListFilter{Limit: 50},
+			mockErr:          fmt.Errorf("storage.postgre.ListLinks: %w", context.DeadlineExceeded),
+			expectedStatus:   http.StatusGatewayTimeout,
+			expectedContains: `"detail":"storage timeout"`,
+		},
+		{
+			name:             "internal error",
+			query:            "",
+			filter:           storage.ListFilter{Limit: 50},
+			mockErr:          errors.New("some internal error"),
+			expectedStatus:   http.StatusInternalServerError,
+			expectedContains: `"detail":"internal error"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			lister := new(MockLinkLister)
+			lister.On("ListLinks", tc.filter).Return(tc.links, tc.mockErr).Maybe()
+
+			handler := list_handler.NewListHandler(logger, links.New(lister, links.Config{}))
+
+			req := httptest.NewRequest(http.MethodGet, "/url"+tc.query, nil)
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			assert.Contains(t, rec.Body.String(), tc.expectedContains)
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package redirect_handler
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"maps"
+	"net/http"
+	"net/url"
+
+	"github.com/RozmiDan/url_shortener/internal/storage"
+)
+
+//go:embed templates/*.html
+var embedTemplates embed.FS
+
+var previewTemplate = template.Must(template.ParseFS(embedTemplates, "templates/preview.html"))
+
+type previewData struct {
+	Title       string
+	Description string
+	ImageURL    string
+	URL         string
+	FollowURL   string
+}
+
+// renderPreview отдаёт HTML-страницу с адресом перехода, кнопкой
+// "Continue" и тегами Open Graph/Twitter для мессенджеров и соцсетей.
+// Кнопка ведёт на follow, а не на destination, чтобы переход засчитывался.
+func renderPreview(w http.ResponseWriter, destination, follow string, meta storage.LinkMeta) error {
+	data := previewData{
+		Title:       meta.Title,
+		Description: meta.Description,
+		ImageURL:    meta.ImageURL,
+		URL:         destination,
+		FollowURL:   follow,
+	}
+	if data.Title == "" {
+		data.Title = destination
+		if u, err := url.Parse(destination); err == nil && u.Host != "" {
+			data.Title = u.Host
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := previewTemplate.Execute(&buf, data); err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// followURL возвращает путь кнопки "Continue": /{alias}/suffix?...&go=1.
+func followURL(alias, suffix string, query url.Values) string {
+	path := "/" + url.PathEscape(alias)
+	if suffix != "" {
+		path += "/" + suffix
+	}
+
+	q := maps.Clone(query)
+	if q == nil {
+		q = url.Values{}
+	}
+	q.Set(followParam, "1")
+
+	return path + "?" + q.Encode()
+}
@@ -1,37 +1,86 @@
 package redirect_handler
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"log/slog"
+	"net"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/RozmiDan/url_shortener/internal/http-server/apierr"
+	metric "github.com/RozmiDan/url_shortener/internal/metrics"
 	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/RozmiDan/url_shortener/internal/usecase/rules"
+	"github.com/RozmiDan/url_shortener/internal/usecase/split"
+	"github.com/RozmiDan/url_shortener/internal/usecase/target"
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/render"
 )
 
-type URLGetter interface {
-	GetURL(alias string) (string, error)
+// visitorCookie закрепляет за посетителем вариант A/B-теста.
+const (
+	visitorCookie       = "url_shortener_vid"
+	visitorCookieMaxAge = 365 * 24 * time.Hour
+)
+
+// followParam - параметр запроса кнопки "Continue" страницы предпросмотра:
+// /{alias}?go=1 засчитывает переход и перенаправляет на адрес ссылки.
+const followParam = "go"
+
+type LinkResolver interface {
+	Resolve(ctx context.Context, alias string) (storage.Link, error)
+	Follow(ctx context.Context, alias string) (storage.Link, error)
+	Peek(ctx context.Context, alias string) (storage.Link, error)
+	RecordVariantServed(ctx context.Context, variantID int64) error
+}
+
+type Options struct {
+	NotActive NotActiveResponse
+	// CountryHeader - заголовок с кодом страны клиента для правил по стране.
+	CountryHeader string
+}
+
+// NotActiveResponse описывает ответ на переход по ссылке, окно активности
+// которой ещё не началось. Если задан FallbackURL, клиент получает его
+// вместо сообщения об ошибке.
+type NotActiveResponse struct {
+	Status      int
+	Message     string
+	FallbackURL string
 }
 
 type Response struct {
-	Error  string `json:"error,omitempty"`
 	Status string `json:"status"`
 	URL    string `json:"url,omitempty"`
 }
 
 // @Title Get URL by alias
-// @Description Return URL for redirect to original by short alias
+// @Description Return URL for redirect to original by short alias.
+// @Description Link rules are evaluated in order, the first matching rule overrides the URL.
+// @Description Otherwise, if the link has A/B variants, one is picked by weight and kept per visitor.
+// @Description Query parameters, UTM tags and /{alias}/rest/of/path suffix are applied per link options.
+// @Description /{alias}+ or the link preview flag returns an HTML preview page with Open Graph tags.
+// @Description A preview does not count as a click and does not pick an A/B variant.
+// @Description The preview Continue button leads to /{alias}?go=1, which counts the click and redirects.
 // @Tags redirect
 // @Accept  json
-// @Produce json
+// @Produce json,html
 // @Param   alias  path  string  true  "Short URL alias"
+// @Param   go     query string  false "1 - follow the link from its preview page"
 // @Success 200 {string} string "Redirect to original URL"
-// @Failure 404 {object} Response "Alias not found"
-// @Failure 500 {object} Response "Internal server error"
+// @Success 301 {string} string "Redirect with the link redirect code (301, 302, 307 or 308)"
+// @Failure 400 {object} apierr.Problem "bad_request: invalid path suffix"
+// @Failure 404 {object} apierr.Problem "link_not_found"
+// @Failure 403 {object} apierr.Problem "link_not_active (status is configurable)"
+// @Failure 410 {object} apierr.Problem "link_exhausted or link_expired"
+// @Failure 500 {object} apierr.Problem "internal"
 // @Router /{alias} [get]
-func NewRedirectHandler(logger *slog.Logger, urlGetter URLGetter) http.HandlerFunc {
+func NewRedirectHandler(logger *slog.Logger, resolver LinkResolver, opts Options) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
 		const op = "redirect_handler.RedirectHandlerConstruction"
@@ -39,36 +88,96 @@ func NewRedirectHandler(logger *slog.Logger, urlGetter URLGetter) http.HandlerFu
 		logger = logger.With(slog.String("op", op),
 			slog.String("request_id", middleware.GetReqID(r.Context())))
 
-		reqAlias := chi.URLParam(r, "alias")
+		// Суффикс "+" (/{alias}+) запрашивает страницу предпросмотра.
+		reqAlias, previewRequested := strings.CutSuffix(chi.URLParam(r, "alias"), "+")
 
 		//logger.Info("request alias is valid")
 
-		url, err := urlGetter.GetURL(reqAlias)
+		query := r.URL.Query()
+		follow := !previewRequested && query.Get(followParam) == "1"
+
+		// Предпросмотр не засчитывает переход, переход с него - засчитывает.
+		resolve := resolver.Resolve
+		switch {
+		case previewRequested:
+			resolve = resolver.Peek
+		case follow:
+			resolve = resolver.Follow
+			query.Del(followParam)
+		}
+
+		link, err := resolve(r.Context(), reqAlias)
 
 		if err != nil {
-			if errors.Is(err, storage.ErrURLNotFound) {
-				logger.Debug("URL not found", slog.String("alias", reqAlias))
-				render.Status(r, http.StatusNotFound)
-				render.JSON(w, r, Response{
-					Status: "Error",
-					Error:  "URL not found",
-				})
+			if errors.Is(err, storage.ErrURLNotActive) {
+				logger.Debug("URL is not active yet", slog.String("alias", reqAlias))
+				if opts.NotActive.FallbackURL != "" {
+					render.Status(r, http.StatusOK)
+					render.JSON(w, r, Response{
+						Status: "OK",
+						URL:    opts.NotActive.FallbackURL,
+					})
+					return
+				}
+				apierr.Write(w, r, apierr.New(opts.NotActive.Status, apierr.CodeLinkNotActive, opts.NotActive.Message))
 				return
 			}
 
-			logger.Error("Error while getting url", slog.Any("err", err))
-			render.Status(r, http.StatusInternalServerError)
-			render.JSON(w, r, Response{
-				Status: "Error",
-				Error:  "internal error",
-			})
+			apierr.Render(w, r, logger, err)
+			return
+		}
+
+		preview := previewRequested || link.Preview && !follow
+
+		url := link.URL
+		if target, ok := rules.Match(link.Rules, rules.VisitorFromRequest(r, opts.CountryHeader)); ok {
+			url = target
+		} else if !preview {
+			// Вариант A/B-теста выбирается и учитывается только при переходе.
+			if variant, ok := split.Pick(reqAlias, link.Variants, visitorKey(w, r)); ok {
+				url = variant.URL
+
+				metric.VariantServedTotal.Inc()
+				if err := resolver.RecordVariantServed(r.Context(), variant.ID); err != nil {
+					logger.Error("Cant record served variant", slog.Any("err", err))
+				}
+			}
+		}
+
+		url, err = target.Build(url, link.Forward, query, pathSuffix(r))
+		if err != nil {
+			if errors.Is(err, target.ErrPathForwardingDisabled) {
+				logger.Debug("path forwarding is disabled", slog.String("alias", reqAlias))
+				apierr.Write(w, r, apierr.FromError(storage.ErrURLNotFound))
+				return
+			}
+
+			if errors.Is(err, target.ErrInvalidSuffix) {
+				logger.Debug("invalid path suffix", slog.String("alias", reqAlias))
+				apierr.Write(w, r, apierr.BadRequest("invalid path"))
+				return
+			}
+
+			apierr.Render(w, r, logger, err)
 			return
 		}
 
 		//logger.Info("url was found", slog.String("url", url))
 
+		if preview {
+			if err := renderPreview(w, url, followURL(reqAlias, pathSuffix(r), query), link.Meta); err != nil {
+				logger.Error("Cant render preview page", slog.Any("err", err))
+			}
+			return
+		}
+
 		// http.Redirect(w, r, url, http.StatusFound)
 
+		if link.RedirectCode != 0 {
+			http.Redirect(w, r, url, link.RedirectCode)
+			return
+		}
+
 		render.Status(r, http.StatusOK)
 		render.JSON(w, r, Response{
 			Status: "OK",
@@ -76,3 +185,38 @@ func NewRedirectHandler(logger *slog.Logger, urlGetter URLGetter) http.HandlerFu
 		})
 	}
 }
+
+// visitorKey возвращает ключ посетителя из cookie, а при её отсутствии
+// выводит его из хеша IP-адреса и сохраняет в cookie.
+func visitorKey(w http.ResponseWriter, r *http.Request) string {
+	if c, err := r.Cookie(visitorCookie); err == nil && c.Value != "" {
+		return c.Value
+	}
+
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	sum := sha256.Sum256([]byte(ip))
+	key := hex.EncodeToString(sum[:8])
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     visitorCookie,
+		Value:    key,
+		Path:     "/",
+		MaxAge:   int(visitorCookieMaxAge.Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return key
+}
+
+// pathSuffix возвращает экранированную часть пути после алиаса:
+// /{alias}/rest/of/path -> rest/of/path. Путь берётся из запроса, а не из
+// параметров chi, потому что middleware.URLFormat отрезает расширения.
+func pathSuffix(r *http.Request) string {
+	path := strings.TrimPrefix(r.URL.EscapedPath(), "/")
+	_, suffix, _ := strings.Cut(path, "/")
+	return suffix
+}
@@ -2,18 +2,26 @@ package redirect_handler_test
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 
+	"github.com/RozmiDan/url_shortener/internal/http-server/apierr"
 	redirect_handler "github.com/RozmiDan/url_shortener/internal/http-server/handlers/redirect"
 	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/RozmiDan/url_shortener/internal/storage/memory"
+	"github.com/RozmiDan/url_shortener/internal/usecase/links"
 	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/render"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"gopkg.in/go-playground/assert.v1"
 )
 
@@ -21,13 +29,24 @@ type MockURLGetter struct {
 	mock.Mock
 }
 
-func (m *MockURLGetter) GetURL(alias string) (string, error) {
+func (m *MockURLGetter) Resolve(ctx context.Context, alias string) (storage.Link, error) {
 	args := m.Called(alias)
-	return args.Get(0).(string), args.Error(1)
+	return args.Get(0).(storage.Link), args.Error(1)
 }
 
-type URLGetter interface {
-	GetURL(alias string) (string, error)
+func (m *MockURLGetter) Follow(ctx context.Context, alias string) (storage.Link, error) {
+	args := m.Called(alias)
+	return args.Get(0).(storage.Link), args.Error(1)
+}
+
+func (m *MockURLGetter) Peek(ctx context.Context, alias string) (storage.Link, error) {
+	args := m.Called(alias)
+	return args.Get(0).(storage.Link), args.Error(1)
+}
+
+func (m *MockURLGetter) RecordVariantServed(ctx context.Context, variantID int64) error {
+	args := m.Called(variantID)
+	return args.Error(0)
 }
 
 func TestGetHandler(t *testing.T) {
@@ -39,6 +58,8 @@ func TestGetHandler(t *testing.T) {
 		mockErr          error
 		expectedStatus   int
 		expectedResponse redirect_handler.Response
+		expectedCode     apierr.Code
+		expectedDetail   string
 		expectCall       bool
 	}{
 		{
@@ -59,11 +80,39 @@ func TestGetHandler(t *testing.T) {
 			mockURL:        "",
 			mockErr:        storage.ErrURLNotFound,
 			expectedStatus: http.StatusNotFound,
-			expectedResponse: redirect_handler.Response{
-				Status: "Error",
-				Error:  "URL not found",
-			},
-			expectCall: true,
+			expectedCode:   apierr.CodeLinkNotFound,
+			expectedDetail: "alias not found",
+			expectCall:     true,
+		},
+		{
+			name:           "clicks limit exhausted",
+			alias:          "one-time",
+			mockURL:        "",
+			mockErr:        storage.ErrURLExhausted,
+			expectedStatus: http.StatusGone,
+			expectedCode:   apierr.CodeLinkExhausted,
+			expectedDetail: "clicks limit exhausted",
+			expectCall:     true,
+		},
+		{
+			name:           "link expired",
+			alias:          "campaign",
+			mockURL:        "",
+			mockErr:        storage.ErrURLExpired,
+			expectedStatus: http.StatusGone,
+			expectedCode:   apierr.CodeLinkExpired,
+			expectedDetail: "link has expired",
+			expectCall:     true,
+		},
+		{
+			name:           "link not active yet",
+			alias:          "upcoming",
+			mockURL:        "",
+			mockErr:        storage.ErrURLNotActive,
+			expectedStatus: http.StatusForbidden,
+			expectedCode:   apierr.CodeLinkNotActive,
+			expectedDetail: "URL is not active yet",
+			expectCall:     true,
 		},
 		{
 			name:           "internal error",
@@ -71,23 +120,27 @@ func TestGetHandler(t *testing.T) {
 			mockURL:        "",
 			mockErr:        errors.New("some internal error"),
 			expectedStatus: http.StatusInternalServerError,
-			expectedResponse: redirect_handler.Response{
-				Status: "Error",
-				Error:  "internal error",
-			},
-			expectCall: true,
+			expectedCode:   apierr.CodeInternal,
+			expectedDetail: "internal error",
+			expectCall:     true,
 		},
 	}
 
 	logger := slog.New(slog.NewTextHandler(bytes.NewBuffer(nil), nil))
+	opts := redirect_handler.Options{
+		NotActive: redirect_handler.NotActiveResponse{
+			Status:  http.StatusForbidden,
+			Message: "URL is not active yet",
+		},
+	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			mockGetter := new(MockURLGetter)
-			handler := redirect_handler.NewRedirectHandler(logger, mockGetter)
+			handler := redirect_handler.NewRedirectHandler(logger, mockGetter, opts)
 
 			if tc.expectCall {
-				mockGetter.On("GetURL", tc.alias).Return(tc.mockURL, tc.mockErr)
+				mockGetter.On("Resolve", tc.alias).Return(storage.Link{URL: tc.mockURL}, tc.mockErr)
 			}
 
 			r := chi.NewRouter()
@@ -98,17 +151,406 @@ func TestGetHandler(t *testing.T) {
 
 			r.ServeHTTP(rec, req)
 
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+
+			if tc.expectedCode == "" {
+				var response redirect_handler.Response
+				render.DecodeJSON(rec.Body, &response)
+				assert.Equal(t, tc.expectedResponse, response)
+			} else {
+				var problem apierr.Problem
+				render.DecodeJSON(rec.Body, &problem)
+				assert.Equal(t, tc.expectedCode, problem.Code)
+				assert.Equal(t, tc.expectedDetail, problem.Detail)
+			}
+
+			if tc.expectCall {
+				mockGetter.AssertCalled(t, "Resolve", tc.alias)
+			} else {
+				mockGetter.AssertNotCalled(t, "Resolve")
+			}
+		})
+	}
+}
+
+func TestGetHandlerNotActiveFallback(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(bytes.NewBuffer(nil), nil))
+
+	mockGetter := new(MockURLGetter)
+	mockGetter.On("Resolve", "upcoming").Return(storage.Link{}, storage.ErrURLNotActive)
+
+	handler := redirect_handler.NewRedirectHandler(logger, mockGetter, redirect_handler.Options{
+		NotActive: redirect_handler.NotActiveResponse{
+			Status:      http.StatusForbidden,
+			Message:     "URL is not active yet",
+			FallbackURL: "https://example.com/soon",
+		},
+	})
+
+	r := chi.NewRouter()
+	r.Get("/{alias}", handler)
+
+	req := httptest.NewRequest(http.MethodGet, "/upcoming", nil)
+	rec := httptest.NewRecorder()
+
+	r.ServeHTTP(rec, req)
+
+	var response redirect_handler.Response
+	render.DecodeJSON(rec.Body, &response)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, redirect_handler.Response{Status: "OK", URL: "https://example.com/soon"}, response)
+}
+
+func TestGetHandlerRules(t *testing.T) {
+	const (
+		iphoneUA  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148"
+		androidUA = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 Chrome/120.0 Mobile Safari/537.36"
+		desktopUA = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 Chrome/120.0 Safari/537.36"
+	)
+
+	link := storage.Link{
+		URL: "https://example.com",
+		Rules: []storage.Rule{
+			{ID: 1, Conditions: storage.RuleConditions{OS: []string{"ios"}}, TargetURL: "https://apps.apple.com/app"},
+			{ID: 2, Conditions: storage.RuleConditions{OS: []string{"android"}}, TargetURL: "https://play.google.com/app"},
+			{ID: 3, Conditions: storage.RuleConditions{Languages: []string{"ru"}}, TargetURL: "https://example.com/ru"},
+			{ID: 4, Conditions: storage.RuleConditions{Countries: []string{"DE"}}, TargetURL: "https://example.com/de"},
+			{ID: 5, Conditions: storage.RuleConditions{Query: map[string]string{"ref": "mail"}}, TargetURL: "https://example.com/mail"},
+		},
+	}
+
+	testCases := []struct {
+		name        string
+		path        string
+		headers     map[string]string
+		expectedURL string
+	}{
+		{
+			name:        "ios goes to app store",
+			path:        "/promo",
+			headers:     map[string]string{"User-Agent": iphoneUA, "Accept-Language": "ru"},
+			expectedURL: "https://apps.apple.com/app",
+		},
+		{
+			name:        "android goes to play store",
+			path:        "/promo",
+			headers:     map[string]string{"User-Agent": androidUA},
+			expectedURL: "https://play.google.com/app",
+		},
+		{
+			name:        "russian desktop goes to russian landing",
+			path:        "/promo",
+			headers:     map[string]string{"User-Agent": desktopUA, "Accept-Language": "en;q=0.5, ru-RU"},
+			expectedURL: "https://example.com/ru",
+		},
+		{
+			name:        "country header",
+			path:        "/promo",
+			headers:     map[string]string{"User-Agent": desktopUA, "CF-IPCountry": "de"},
+			expectedURL: "https://example.com/de",
+		},
+		{
+			name:        "query parameter",
+			path:        "/promo?ref=mail",
+			headers:     map[string]string{"User-Agent": desktopUA},
+			expectedURL: "https://example.com/mail",
+		},
+		{
+			name:        "default url",
+			path:        "/promo",
+			headers:     map[string]string{"User-Agent": desktopUA, "Accept-Language": "en-US"},
+			expectedURL: "https://example.com",
+		},
+	}
+
+	logger := slog.New(slog.NewTextHandler(bytes.NewBuffer(nil), nil))
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockGetter := new(MockURLGetter)
+			mockGetter.On("Resolve", "promo").Return(link, nil)
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", redirect_handler.NewRedirectHandler(logger, mockGetter,
+				redirect_handler.Options{CountryHeader: "CF-IPCountry"}))
+
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			for key, value := range tc.headers {
+				req.Header.Set(key, value)
+			}
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			var response redirect_handler.Response
+			render.DecodeJSON(rec.Body, &response)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tc.expectedURL, response.URL)
+		})
+	}
+}
+
+func TestGetHandlerVariants(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(bytes.NewBuffer(nil), nil))
+
+	link := storage.Link{
+		URL: "https://example.com",
+		Variants: []storage.Variant{
+			{ID: 1, URL: "https://a.example.com", Weight: 50},
+			{ID: 2, URL: "https://b.example.com", Weight: 50},
+		},
+	}
+
+	mockGetter := new(MockURLGetter)
+	mockGetter.On("Resolve", "promo").Return(link, nil)
+	mockGetter.On("RecordVariantServed", mock.AnythingOfType("int64")).Return(nil)
+
+	r := chi.NewRouter()
+	r.Get("/{alias}", redirect_handler.NewRedirectHandler(logger, mockGetter, redirect_handler.Options{}))
+
+	req := httptest.NewRequest(http.MethodGet, "/promo", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	var first redirect_handler.Response
+	render.DecodeJSON(rec.Body, &first)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEqual(t, link.URL, first.URL)
+
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("expected visitor cookie, got %d cookies", len(cookies))
+	}
+
+	// Посетитель с той же cookie получает тот же вариант.
+	for i := 0; i < 5; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/promo", nil)
+		req.RemoteAddr = "10.0.0." + strconv.Itoa(i) + ":1234"
+		req.AddCookie(cookies[0])
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		var response redirect_handler.Response
+		render.DecodeJSON(rec.Body, &response)
+		assert.Equal(t, first.URL, response.URL)
+		assert.Equal(t, 0, len(rec.Result().Cookies()))
+	}
+
+	mockGetter.AssertNumberOfCalls(t, "RecordVariantServed", 6)
+}
+
+func TestGetHandlerForwarding(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(bytes.NewBuffer(nil), nil))
+
+	testCases := []struct {
+		name           string
+		forward        storage.ForwardOptions
+		path           string
+		expectedStatus int
+		expectedURL    string
+	}{
+		{
+			name:           "query dropped by default",
+			path:           "/docs?utm_source=x",
+			expectedStatus: http.StatusOK,
+			expectedURL:    "https://example.com/docs",
+		},
+		{
+			name:           "query and path forwarded",
+			forward:        storage.ForwardOptions{QueryMode: storage.QueryMerge, ForwardPath: true},
+			path:           "/docs/guide/setup.html?utm_source=x",
+			expectedStatus: http.StatusOK,
+			expectedURL:    "https://example.com/docs/guide/setup.html?utm_source=x",
+		},
+		{
+			name:           "path forwarding disabled",
+			path:           "/docs/guide",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockGetter := new(MockURLGetter)
+			mockGetter.On("Resolve", "docs").Return(storage.Link{
+				URL:     "https://example.com/docs",
+				Forward: tc.forward,
+			}, nil)
+
+			handler := redirect_handler.NewRedirectHandler(logger, mockGetter, redirect_handler.Options{})
+			r := chi.NewRouter()
+			r.Use(middleware.URLFormat)
+			r.Get("/{alias}", handler)
+			r.Get("/{alias}/*", handler)
+
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
 			var response redirect_handler.Response
 			render.DecodeJSON(rec.Body, &response)
 
 			assert.Equal(t, tc.expectedStatus, rec.Code)
-			assert.Equal(t, tc.expectedResponse, response)
+			assert.Equal(t, tc.expectedURL, response.URL)
+		})
+	}
+}
 
-			if tc.expectCall {
-				mockGetter.AssertCalled(t, "GetURL", tc.alias)
-			} else {
-				mockGetter.AssertNotCalled(t, "GetURL")
+func TestGetHandlerPreview(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(bytes.NewBuffer(nil), nil))
+
+	testCases := []struct {
+		name        string
+		path        string
+		method      string
+		link        storage.Link
+		contains    []string
+		notContains []string
+	}{
+		{
+			name:   "plus suffix with metadata",
+			path:   "/promo+",
+			method: "Peek",
+			link: storage.Link{
+				URL: "https://example.com/sale",
+				Meta: storage.LinkMeta{
+					Title:       "Big <sale>",
+					Description: "Everything -50%",
+					ImageURL:    "https://example.com/cover.png",
+				},
+			},
+			contains: []string{
+				`<meta property="og:title" content="Big &lt;sale&gt;">`,
+				`<meta property="og:description" content="Everything -50%">`,
+				`<meta property="og:image" content="https://example.com/cover.png">`,
+				`<meta name="twitter:card" content="summary_large_image">`,
+				`<p class="destination">https://example.com/sale</p>`,
+				`href="/promo?go=1"`,
+			},
+		},
+		{
+			name:   "preview flag without metadata",
+			path:   "/promo",
+			method: "Resolve",
+			link:   storage.Link{URL: "https://example.com/sale", Preview: true},
+			contains: []string{
+				`<title>example.com</title>`,
+				`<meta name="twitter:card" content="summary">`,
+			},
+			notContains: []string{"og:image", "og:description"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockGetter := new(MockURLGetter)
+			mockGetter.On(tc.method, "promo").Return(tc.link, nil)
+
+			r := chi.NewRouter()
+			r.Get("/{alias}", redirect_handler.NewRedirectHandler(logger, mockGetter, redirect_handler.Options{}))
+
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			body := rec.Body.String()
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+			for _, s := range tc.contains {
+				assert.Equal(t, true, strings.Contains(body, s))
+			}
+			for _, s := range tc.notContains {
+				assert.Equal(t, false, strings.Contains(body, s))
 			}
 		})
 	}
 }
+
+func TestGetHandlerPreviewKeepsClicks(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(bytes.NewBuffer(nil), nil))
+
+	st := memory.New()
+	_, err := st.SaveURL(context.Background(), "https://example.com/once", "once", storage.URLOptions{
+		MaxClicks:    1,
+		RedirectCode: http.StatusFound,
+	})
+	require.NoError(t, err)
+
+	r := chi.NewRouter()
+	r.Get("/{alias}", redirect_handler.NewRedirectHandler(logger, links.New(st, links.Config{}), redirect_handler.Options{}))
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	for range 3 {
+		rec := get("/once+")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+	}
+
+	rec := get("/once")
+	assert.Equal(t, http.StatusFound, rec.Code)
+	assert.Equal(t, "https://example.com/once", rec.Header().Get("Location"))
+
+	assert.Equal(t, http.StatusGone, get("/once").Code)
+	assert.Equal(t, http.StatusGone, get("/once+").Code)
+}
+
+func TestGetHandlerPreviewFollowCounts(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(bytes.NewBuffer(nil), nil))
+
+	st := memory.New()
+	_, err := st.SaveURL(context.Background(), "https://example.com/once", "once", storage.URLOptions{
+		MaxClicks:    1,
+		Preview:      true,
+		RedirectCode: http.StatusFound,
+	})
+	require.NoError(t, err)
+
+	r := chi.NewRouter()
+	r.Get("/{alias}", redirect_handler.NewRedirectHandler(logger, links.New(st, links.Config{}), redirect_handler.Options{}))
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	// Страница предпросмотра открывается дважды, переход засчитывается
+	// только по кнопке "Continue".
+	for _, path := range []string{"/once", "/once+"} {
+		rec := get(path)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, true, strings.Contains(rec.Body.String(), `href="/once?go=1"`))
+	}
+
+	rec := get("/once?go=1")
+	assert.Equal(t, http.StatusFound, rec.Code)
+	assert.Equal(t, "https://example.com/once", rec.Header().Get("Location"))
+
+	assert.Equal(t, http.StatusGone, get("/once?go=1").Code)
+	assert.Equal(t, http.StatusGone, get("/once").Code)
+}
+
+func TestGetHandlerRedirectCode(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(bytes.NewBuffer(nil), nil))
+
+	mockGetter := new(MockURLGetter)
+	mockGetter.On("Resolve", "moved").Return(storage.Link{URL: "https://example.com/new", RedirectCode: http.StatusMovedPermanently}, nil)
+
+	r := chi.NewRouter()
+	r.Get("/{alias}", redirect_handler.NewRedirectHandler(logger, mockGetter, redirect_handler.Options{}))
+
+	req := httptest.NewRequest(http.MethodGet, "/moved", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusMovedPermanently, rec.Code)
+	assert.Equal(t, "https://example.com/new", rec.Header().Get("Location"))
+}
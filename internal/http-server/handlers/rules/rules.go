@@ -0,0 +1,368 @@
+package rules_handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/RozmiDan/url_shortener/internal/http-server/apierr"
+	"github.com/RozmiDan/url_shortener/internal/http-server/etag"
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+)
+
+type RuleLister interface {
+	List(ctx context.Context, alias string) ([]storage.Rule, int64, error)
+}
+
+type RuleAdder interface {
+	Add(ctx context.Context, alias string, rule storage.Rule, expectedVersion int64) (storage.Rule, int64, error)
+}
+
+type RuleReplacer interface {
+	Replace(ctx context.Context, alias string, rules []storage.Rule, expectedVersion int64) ([]storage.Rule, int64, error)
+}
+
+type RuleUpdater interface {
+	Update(ctx context.Context, alias string, rule storage.Rule, expectedVersion int64) (int64, error)
+}
+
+type RuleDeleter interface {
+	Delete(ctx context.Context, alias string, ruleID int64, expectedVersion int64) (int64, error)
+}
+
+// Rule - правило в теле запроса.
+type Rule struct {
+	Conditions storage.RuleConditions `json:"conditions"`
+	TargetURL  string                 `json:"target_url" validate:"required,url"`
+}
+
+type Request struct {
+	Rule
+
+	// ExpectedVersion - версия ссылки, которую клиент собирается изменить,
+	// то же, что If-Match. 0 - без проверки.
+	ExpectedVersion int64 `json:"expected_version,omitempty" validate:"omitempty,min=1"`
+}
+
+type ReplaceRequest struct {
+	Rules []Rule `json:"rules" validate:"dive"`
+
+	// ExpectedVersion - то же, что в Request.
+	ExpectedVersion int64 `json:"expected_version,omitempty" validate:"omitempty,min=1"`
+}
+
+type Response struct {
+	Status  string        `json:"status"`
+	Rule    *storage.Rule `json:"rule,omitempty"`
+	Version int64         `json:"version,omitempty"`
+}
+
+type ListResponse struct {
+	Status  string         `json:"status"`
+	Rules   []storage.Rule `json:"rules"`
+	Version int64          `json:"version,omitempty"`
+}
+
+// @Title List link rules
+// @Description Return ordered smart redirect rules of the link.
+// @Description The ETag header is the link version, used with If-Match when changing the rules.
+// @Tags rules
+// @Produce json
+// @Param   alias  path  string  true  "Short URL alias"
+// @Success 200 {object} ListResponse
+// @Failure 404 {object} apierr.Problem "link_not_found"
+// @Failure 500 {object} apierr.Problem "internal"
+// @Router /api/v1/url/{alias}/rules [get]
+func NewListHandler(logger *slog.Logger, ruleLister RuleLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.rules.NewListHandler"
+
+		opLogger := logger.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := chi.URLParam(r, "alias")
+
+		list, version, err := ruleLister.List(r.Context(), alias)
+		if err != nil {
+			apierr.Render(w, r, opLogger, err)
+			return
+		}
+
+		if list == nil {
+			list = []storage.Rule{}
+		}
+
+		w.Header().Set("ETag", etag.Format(version))
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, ListResponse{
+			Status:  "OK",
+			Rules:   list,
+			Version: version,
+		})
+	}
+}
+
+// @Title Add link rule
+// @Description Append a smart redirect rule to the end of the link rules.
+// @Description If-Match (or "expected_version") makes the change fail with 412 if the link was changed meanwhile.
+// @Tags rules
+// @Accept  json
+// @Produce json
+// @Param   alias     path    string   true   "Short URL alias"
+// @Param   If-Match  header  string   false  "ETag of the link version being changed"
+// @Param   input     body    Request  true   "Rule"
+// @Success 201 {object} Response
+// @Failure 400 {object} apierr.Problem "bad_request or validation_failed"
+// @Failure 404 {object} apierr.Problem "link_not_found"
+// @Failure 412 {object} apierr.Problem "version_conflict"
+// @Failure 500 {object} apierr.Problem "internal"
+// @Router /api/v1/url/{alias}/rules [post]
+func NewAddHandler(logger *slog.Logger, ruleAdder RuleAdder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.rules.NewAddHandler"
+
+		opLogger := logger.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := chi.URLParam(r, "alias")
+
+		var req Request
+		if !decodeRequest(w, r, opLogger, &req) {
+			return
+		}
+
+		expectedVersion, err := etag.Expected(r.Header.Get("If-Match"), req.ExpectedVersion)
+		if err != nil {
+			apierr.Render(w, r, opLogger, err)
+			return
+		}
+
+		rule, version, err := ruleAdder.Add(r.Context(), alias, req.toRule(), expectedVersion)
+		if err != nil {
+			apierr.Render(w, r, opLogger, err)
+			return
+		}
+
+		w.Header().Set("ETag", etag.Format(version))
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, Response{
+			Status:  "OK",
+			Rule:    &rule,
+			Version: version,
+		})
+	}
+}
+
+// @Title Replace link rules
+// @Description Replace all smart redirect rules of the link with a new ordered list.
+// @Description If-Match (or "expected_version") makes the change fail with 412 if the link was changed meanwhile.
+// @Tags rules
+// @Accept  json
+// @Produce json
+// @Param   alias     path    string          true   "Short URL alias"
+// @Param   If-Match  header  string          false  "ETag of the link version being changed"
+// @Param   input     body    ReplaceRequest  true   "Ordered rules"
+// @Success 200 {object} ListResponse
+// @Failure 400 {object} apierr.Problem "bad_request or validation_failed"
+// @Failure 404 {object} apierr.Problem "link_not_found"
+// @Failure 412 {object} apierr.Problem "version_conflict"
+// @Failure 500 {object} apierr.Problem "internal"
+// @Router /api/v1/url/{alias}/rules [put]
+func NewReplaceHandler(logger *slog.Logger, ruleReplacer RuleReplacer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.rules.NewReplaceHandler"
+
+		opLogger := logger.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := chi.URLParam(r, "alias")
+
+		var req ReplaceRequest
+		if !decodeRequest(w, r, opLogger, &req) {
+			return
+		}
+
+		expectedVersion, err := etag.Expected(r.Header.Get("If-Match"), req.ExpectedVersion)
+		if err != nil {
+			apierr.Render(w, r, opLogger, err)
+			return
+		}
+
+		newRules := make([]storage.Rule, len(req.Rules))
+		for i, item := range req.Rules {
+			newRules[i] = item.toRule()
+		}
+
+		saved, version, err := ruleReplacer.Replace(r.Context(), alias, newRules, expectedVersion)
+		if err != nil {
+			apierr.Render(w, r, opLogger, err)
+			return
+		}
+
+		w.Header().Set("ETag", etag.Format(version))
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, ListResponse{
+			Status:  "OK",
+			Rules:   saved,
+			Version: version,
+		})
+	}
+}
+
+// @Title Update link rule
+// @Description Update conditions and target of a smart redirect rule.
+// @Description If-Match (or "expected_version") makes the change fail with 412 if the link was changed meanwhile.
+// @Tags rules
+// @Accept  json
+// @Produce json
+// @Param   alias     path    string   true   "Short URL alias"
+// @Param   id        path    int      true   "Rule id"
+// @Param   If-Match  header  string   false  "ETag of the link version being changed"
+// @Param   input     body    Request  true   "Rule"
+// @Success 200 {object} Response
+// @Failure 400 {object} apierr.Problem "bad_request or validation_failed"
+// @Failure 404 {object} apierr.Problem "link_not_found or rule_not_found"
+// @Failure 412 {object} apierr.Problem "version_conflict"
+// @Failure 500 {object} apierr.Problem "internal"
+// @Router /api/v1/url/{alias}/rules/{id} [put]
+func NewUpdateHandler(logger *slog.Logger, ruleUpdater RuleUpdater) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.rules.NewUpdateHandler"
+
+		opLogger := logger.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := chi.URLParam(r, "alias")
+
+		ruleID, ok := parseRuleID(w, r, opLogger)
+		if !ok {
+			return
+		}
+
+		var req Request
+		if !decodeRequest(w, r, opLogger, &req) {
+			return
+		}
+
+		expectedVersion, err := etag.Expected(r.Header.Get("If-Match"), req.ExpectedVersion)
+		if err != nil {
+			apierr.Render(w, r, opLogger, err)
+			return
+		}
+
+		rule := req.toRule()
+		rule.ID = ruleID
+
+		version, err := ruleUpdater.Update(r.Context(), alias, rule, expectedVersion)
+		if err != nil {
+			apierr.Render(w, r, opLogger, err)
+			return
+		}
+
+		w.Header().Set("ETag", etag.Format(version))
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{
+			Status:  "OK",
+			Rule:    &rule,
+			Version: version,
+		})
+	}
+}
+
+// @Title Delete link rule
+// @Description Delete a smart redirect rule.
+// @Description If-Match makes the change fail with 412 if the link was changed meanwhile.
+// @Tags rules
+// @Produce json
+// @Param   alias     path    string  true   "Short URL alias"
+// @Param   id        path    int     true   "Rule id"
+// @Param   If-Match  header  string  false  "ETag of the link version being changed"
+// @Success 200 {object} Response
+// @Failure 400 {object} apierr.Problem "bad_request: invalid rule id"
+// @Failure 404 {object} apierr.Problem "link_not_found or rule_not_found"
+// @Failure 412 {object} apierr.Problem "version_conflict"
+// @Failure 500 {object} apierr.Problem "internal"
+// @Router /api/v1/url/{alias}/rules/{id} [delete]
+func NewDeleteHandler(logger *slog.Logger, ruleDeleter RuleDeleter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.rules.NewDeleteHandler"
+
+		opLogger := logger.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := chi.URLParam(r, "alias")
+
+		ruleID, ok := parseRuleID(w, r, opLogger)
+		if !ok {
+			return
+		}
+
+		expectedVersion, err := etag.Expected(r.Header.Get("If-Match"), 0)
+		if err != nil {
+			apierr.Render(w, r, opLogger, err)
+			return
+		}
+
+		version, err := ruleDeleter.Delete(r.Context(), alias, ruleID, expectedVersion)
+		if err != nil {
+			apierr.Render(w, r, opLogger, err)
+			return
+		}
+
+		w.Header().Set("ETag", etag.Format(version))
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{
+			Status:  "OK",
+			Version: version,
+		})
+	}
+}
+
+func (rule Rule) toRule() storage.Rule {
+	return storage.Rule{
+		Conditions: rule.Conditions,
+		TargetURL:  rule.TargetURL,
+	}
+}
+
+// decodeRequest читает и проверяет тело запроса, при ошибке сам
+// отправляет ответ 400. Условия правил проверяет rules.Service.
+func decodeRequest(w http.ResponseWriter, r *http.Request, logger *slog.Logger, req any) bool {
+	if err := render.DecodeJSON(r.Body, req); err != nil {
+		logger.Debug("failed to decode request body", slog.Any("err", err))
+		apierr.Write(w, r, apierr.BadRequest("failed to decode request"))
+		return false
+	}
+
+	if err := apierr.Validate(req); err != nil {
+		logger.Debug("validation error", slog.Any("err", err))
+		apierr.Write(w, r, apierr.Invalid(err))
+		return false
+	}
+
+	return true
+}
+
+func parseRuleID(w http.ResponseWriter, r *http.Request, logger *slog.Logger) (int64, bool) {
+	ruleID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		logger.Debug("invalid rule id", slog.Any("err", err))
+		apierr.Write(w, r, apierr.BadRequest("invalid rule id"))
+		return 0, false
+	}
+	return ruleID, true
+}
@@ -0,0 +1,250 @@
+package rules_handler_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	rules_handler "github.com/RozmiDan/url_shortener/internal/http-server/handlers/rules"
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/RozmiDan/url_shortener/internal/usecase/rules"
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRuleStorage struct {
+	mock.Mock
+}
+
+func (m *MockRuleStorage) ListRules(ctx context.Context, alias string) ([]storage.Rule, int64, error) {
+	args := m.Called(alias)
+	return args.Get(0).([]storage.Rule), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockRuleStorage) AddRule(ctx context.Context, alias string, rule storage.Rule, expectedVersion int64) (storage.Rule, int64, error) {
+	args := m.Called(alias, rule, expectedVersion)
+	return args.Get(0).(storage.Rule), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockRuleStorage) ReplaceRules(ctx context.Context, alias string, rules []storage.Rule, expectedVersion int64) ([]storage.Rule, int64, error) {
+	args := m.Called(alias, rules, expectedVersion)
+	return args.Get(0).([]storage.Rule), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockRuleStorage) UpdateRule(ctx context.Context, alias string, rule storage.Rule, expectedVersion int64) (int64, error) {
+	args := m.Called(alias, rule, expectedVersion)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRuleStorage) DeleteRule(ctx context.Context, alias string, ruleID int64, expectedVersion int64) (int64, error) {
+	args := m.Called(alias, ruleID, expectedVersion)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func newRouter(st *MockRuleStorage) http.Handler {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	service := rules.New(st)
+
+	r := chi.NewRouter()
+	r.Get("/url/{alias}/rules", rules_handler.NewListHandler(logger, service))
+	r.Post("/url/{alias}/rules", rules_handler.NewAddHandler(logger, service))
+	r.Put("/url/{alias}/rules", rules_handler.NewReplaceHandler(logger, service))
+	r.Put("/url/{alias}/rules/{id}", rules_handler.NewUpdateHandler(logger, service))
+	r.Delete("/url/{alias}/rules/{id}", rules_handler.NewDeleteHandler(logger, service))
+
+	return r
+}
+
+func TestRulesHandlers(t *testing.T) {
+	iosRule := storage.Rule{
+		Conditions: storage.RuleConditions{OS: []string{"ios"}},
+		TargetURL:  "https://apps.apple.com/app",
+	}
+	savedRule := iosRule
+	savedRule.ID = 7
+
+	testCases := []struct {
+		name             string
+		method           string
+		path             string
+		ifMatch          string
+		body             string
+		setup            func(st *MockRuleStorage)
+		expectedStatus   int
+		expectedContains string
+		expectedETag     string
+	}{
+		{
+			name:   "list",
+			method: http.MethodGet,
+			path:   "/url/promo/rules",
+			setup: func(st *MockRuleStorage) {
+				st.On("ListRules", "promo").Return([]storage.Rule(nil), int64(3), nil)
+			},
+			expectedStatus:   http.StatusOK,
+			expectedContains: `"rules":[],"version":3`,
+			expectedETag:     `"3"`,
+		},
+		{
+			name:   "list unknown alias",
+			method: http.MethodGet,
+			path:   "/url/missing/rules",
+			setup: func(st *MockRuleStorage) {
+				st.On("ListRules", "missing").Return([]storage.Rule(nil), int64(0), storage.ErrAliasNotFound)
+			},
+			expectedStatus:   http.StatusNotFound,
+			expectedContains: `"detail":"alias not found"`,
+		},
+		{
+			name:   "add",
+			method: http.MethodPost,
+			path:   "/url/promo/rules",
+			body:   `{"conditions": {"os": ["ios"]}, "target_url": "https://apps.apple.com/app"}`,
+			setup: func(st *MockRuleStorage) {
+				st.On("AddRule", "promo", iosRule, int64(0)).Return(savedRule, int64(4), nil)
+			},
+			expectedStatus:   http.StatusCreated,
+			expectedContains: `"id":7`,
+			expectedETag:     `"4"`,
+		},
+		{
+			name:    "add with If-Match",
+			method:  http.MethodPost,
+			path:    "/url/promo/rules",
+			ifMatch: `"3"`,
+			body:    `{"conditions": {"os": ["ios"]}, "target_url": "https://apps.apple.com/app"}`,
+			setup: func(st *MockRuleStorage) {
+				st.On("AddRule", "promo", iosRule, int64(3)).Return(savedRule, int64(4), nil)
+			},
+			expectedStatus:   http.StatusCreated,
+			expectedContains: `"version":4`,
+			expectedETag:     `"4"`,
+		},
+		{
+			name:    "add stale version",
+			method:  http.MethodPost,
+			path:    "/url/promo/rules",
+			ifMatch: `"2"`,
+			body:    `{"conditions": {"os": ["ios"]}, "target_url": "https://apps.apple.com/app"}`,
+			setup: func(st *MockRuleStorage) {
+				st.On("AddRule", "promo", iosRule, int64(2)).Return(storage.Rule{}, int64(0), storage.ErrVersionConflict)
+			},
+			expectedStatus:   http.StatusPreconditionFailed,
+			expectedContains: `"code":"version_conflict"`,
+		},
+		{
+			name:             "add If-Match contradicts expected_version",
+			method:           http.MethodPost,
+			path:             "/url/promo/rules",
+			ifMatch:          `"3"`,
+			body:             `{"conditions": {}, "target_url": "https://example.com", "expected_version": 2}`,
+			expectedStatus:   http.StatusPreconditionFailed,
+			expectedContains: `"code":"version_conflict"`,
+		},
+		{
+			name:             "add invalid target",
+			method:           http.MethodPost,
+			path:             "/url/promo/rules",
+			body:             `{"conditions": {}, "target_url": "not-url"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedContains: `"detail":"invalid request parameters"`,
+		},
+		{
+			name:             "add unknown device",
+			method:           http.MethodPost,
+			path:             "/url/promo/rules",
+			body:             `{"conditions": {"devices": ["fridge"]}, "target_url": "https://example.com"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedContains: `unknown device`,
+		},
+		{
+			name:   "replace",
+			method: http.MethodPut,
+			path:   "/url/promo/rules",
+			body:   `{"rules": [{"conditions": {"os": ["ios"]}, "target_url": "https://apps.apple.com/app"}], "expected_version": 5}`,
+			setup: func(st *MockRuleStorage) {
+				st.On("ReplaceRules", "promo", []storage.Rule{iosRule}, int64(5)).Return([]storage.Rule{savedRule}, int64(6), nil)
+			},
+			expectedStatus:   http.StatusOK,
+			expectedContains: `"rules":[{"id":7`,
+			expectedETag:     `"6"`,
+		},
+		{
+			name:   "update missing rule",
+			method: http.MethodPut,
+			path:   "/url/promo/rules/7",
+			body:   `{"conditions": {"os": ["ios"]}, "target_url": "https://apps.apple.com/app"}`,
+			setup: func(st *MockRuleStorage) {
+				st.On("UpdateRule", "promo", savedRule, int64(0)).Return(int64(0), storage.ErrRuleNotFound)
+			},
+			expectedStatus:   http.StatusNotFound,
+			expectedContains: `"detail":"rule not found"`,
+		},
+		{
+			name:             "replace with invalid rule",
+			method:           http.MethodPut,
+			path:             "/url/promo/rules",
+			body:             `{"rules": [{"conditions": {}, "target_url": "https://example.com"}, {"conditions": {"time_from": "09:00"}, "target_url": "https://example.com"}]}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedContains: `"field":"rules[1].conditions"`,
+		},
+		{
+			name:             "delete invalid id",
+			method:           http.MethodDelete,
+			path:             "/url/promo/rules/abc",
+			expectedStatus:   http.StatusBadRequest,
+			expectedContains: `"detail":"invalid rule id"`,
+		},
+		{
+			name:   "delete",
+			method: http.MethodDelete,
+			path:   "/url/promo/rules/7",
+			setup: func(st *MockRuleStorage) {
+				st.On("DeleteRule", "promo", int64(7), int64(0)).Return(int64(5), nil)
+			},
+			expectedStatus:   http.StatusOK,
+			expectedContains: `"version":5`,
+			expectedETag:     `"5"`,
+		},
+		{
+			name:    "delete with If-Match",
+			method:  http.MethodDelete,
+			path:    "/url/promo/rules/7",
+			ifMatch: `"4"`,
+			setup: func(st *MockRuleStorage) {
+				st.On("DeleteRule", "promo", int64(7), int64(4)).Return(int64(0), storage.ErrVersionConflict)
+			},
+			expectedStatus:   http.StatusPreconditionFailed,
+			expectedContains: `"code":"version_conflict"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			st := new(MockRuleStorage)
+			if tc.setup != nil {
+				tc.setup(st)
+			}
+
+			req := httptest.NewRequest(tc.method, tc.path, bytes.NewReader([]byte(tc.body)))
+			if tc.ifMatch != "" {
+				req.Header.Set("If-Match", tc.ifMatch)
+			}
+			rec := httptest.NewRecorder()
+
+			newRouter(st).ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			assert.Contains(t, rec.Body.String(), tc.expectedContains)
+			if tc.expectedETag != "" {
+				assert.Equal(t, tc.expectedETag, rec.Header().Get("ETag"))
+			}
+
+			st.AssertExpectations(t)
+		})
+	}
+}
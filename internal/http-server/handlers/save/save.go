@@ -2,51 +2,92 @@ package save_handler
 
 import (
 	"context"
-	"errors"
 	"log/slog"
 	"net/http"
 	"time"
 
+	"github.com/RozmiDan/url_shortener/internal/http-server/apierr"
 	"github.com/RozmiDan/url_shortener/internal/storage"
-	"github.com/RozmiDan/url_shortener/internal/usecase/random"
+	"github.com/RozmiDan/url_shortener/internal/usecase/links"
 	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/render"
-	"github.com/go-playground/validator"
 )
 
-const (
-	aliasLength    = 6
-	requestTimeout = 2 * time.Second
-)
-
-type URLSaver interface {
-	SaveURL(ctx context.Context, urlToSave string, alias string) (int64, error)
+type LinkCreator interface {
+	Create(ctx context.Context, draft links.Draft, conflict links.Conflict) (string, error)
 }
 
 type Request struct {
-	URL   string `json:"url" validate:"required,url"`
-	Alias string `json:"alias,omitempty"`
+	URL       string `json:"url" validate:"required,url"`
+	Alias     string `json:"alias,omitempty" validate:"omitempty,excludesall=+"`
+	MaxClicks int64  `json:"max_clicks,omitempty" validate:"min=0"`
+
+	ActiveFrom  *time.Time `json:"active_from,omitempty"`
+	ActiveUntil *time.Time `json:"active_until,omitempty"`
+
+	Variants []Variant `json:"variants,omitempty" validate:"max=10,dive"`
+
+	QueryMode   string `json:"query_mode,omitempty" validate:"omitempty,oneof=drop merge override"`
+	UTM         *UTM   `json:"utm,omitempty"`
+	ForwardPath bool   `json:"forward_path,omitempty"`
+
+	Title       string `json:"title,omitempty" validate:"max=200"`
+	Description string `json:"description,omitempty" validate:"max=1000"`
+	Image       string `json:"image,omitempty" validate:"omitempty,url"`
+	Preview     bool   `json:"preview,omitempty"`
+
+	Tags     []string       `json:"tags,omitempty" validate:"max=20,dive,max=50"`
+	Folder   string         `json:"folder,omitempty" validate:"max=100"`
+	Metadata map[string]any `json:"metadata,omitempty"`
+
+	RedirectCode int `json:"redirect_code,omitempty" validate:"omitempty,oneof=301 302 307 308"`
+}
+
+type UTM struct {
+	Source   string `json:"source,omitempty"`
+	Medium   string `json:"medium,omitempty"`
+	Campaign string `json:"campaign,omitempty"`
+	Term     string `json:"term,omitempty"`
+	Content  string `json:"content,omitempty"`
+}
+
+type Variant struct {
+	URL    string `json:"url" validate:"required,url"`
+	Weight int    `json:"weight" validate:"min=1"`
 }
 
 type Response struct {
 	Status string `json:"status"`
-	Error  string `json:"error,omitempty"`
 	Alias  string `json:"alias,omitempty"`
 }
 
 // SaveURLHandler godoc
 // @Summary      Creates a short URL
 // @Description  Creates a short URL. If alias is not specified, a random string of 6 characters is generated.
+// @Description  An alias that is already taken is rejected with 409.
+// @Description  If max_clicks is set, the link stops working after that many redirects.
+// @Description  active_from and active_until limit the time window in which the link works.
+// @Description  variants split traffic between several URLs by weight (A/B test).
+// @Description  query_mode (drop, merge, override) controls incoming query parameters on redirect,
+// @Description  utm parameters are appended to every redirect, forward_path enables /{alias}/rest/of/path.
+// @Description  title, description and image are shown on the preview page and in Open Graph tags,
+// @Description  preview makes /{alias} always return the preview page. Alias must not contain "+".
+// @Description  tags, folder and free-form metadata organize links and are used as list filters.
+// @Description  redirect_code (301, 302, 307, 308) makes /{alias} answer with a real HTTP redirect.
+// @Description  With Idempotency-Key a retried request gets the stored response instead of creating
+// @Description  another link; reusing the key with a different body is rejected with 422.
 // @Tags         url
 // @Accept       json
 // @Produce      json
-// @Param        Request  body     Request  true  "URL Saving Parameters"
+// @Param        Idempotency-Key  header   string   false  "Client-generated key that makes retries safe"
+// @Param        Request          body     Request  true   "URL Saving Parameters"
 // @Success      200      {object} Response
-// @Failure      400      {object} Response "invalid request parameters"
-// @Failure      409      {object} Response "URL already exists"
-// @Failure      500      {object} Response "Internal server error"
-// @Router       /url [post]
-func NewSaveHandler(logger *slog.Logger, urlSaver URLSaver) http.HandlerFunc {
+// @Failure      400      {object} apierr.Problem "bad_request or validation_failed with the invalid fields"
+// @Failure      409      {object} apierr.Problem "alias_exists or request_in_progress"
+// @Failure      422      {object} apierr.Problem "idempotency_key_reused"
+// @Failure      500      {object} apierr.Problem "internal"
+// @Router       /api/v1/url [post]
+func NewSaveHandler(logger *slog.Logger, creator LinkCreator) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
 		opLogger := logger.With(
@@ -57,50 +98,20 @@ func NewSaveHandler(logger *slog.Logger, urlSaver URLSaver) http.HandlerFunc {
 		var req Request
 		if err := render.DecodeJSON(r.Body, &req); err != nil {
 			opLogger.Debug("failed to decode request body", slog.Any("err", err))
-			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, Response{
-				Status: "Error",
-				Error:  "failed to decode request",
-			})
+			apierr.Write(w, r, apierr.BadRequest("failed to decode request"))
 			return
 		}
 
-		if err := validator.New().Struct(req); err != nil {
+		if err := req.Validate(); err != nil {
 			opLogger.Debug("validation error", slog.Any("err", err))
-			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, Response{
-				Status: "Error",
-				Error:  "invalid request parameters",
-			})
+			apierr.Write(w, r, apierr.Invalid(err))
 			return
 		}
 
-		alias := req.Alias
-		if alias == "" {
-			alias = random.NewAliasForURL(aliasLength)
-		}
-
-		ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
-		defer cancel()
-
-		_, err := urlSaver.SaveURL(ctx, req.URL, alias)
+		// Время операции ограничивает хранилище (postgres.write_timeout).
+		alias, err := creator.Create(r.Context(), req.Draft(), links.ConflictFail)
 		if err != nil {
-			if errors.Is(err, storage.ErrAliasExists) {
-				opLogger.Debug("alias already exists", slog.String("alias", alias))
-				render.Status(r, http.StatusConflict)
-				render.JSON(w, r, Response{
-					Status: "Error",
-					Error:  "Alias already exists",
-				})
-				return
-			}
-
-			opLogger.Error("failed to save URL", slog.Any("err", err))
-			render.Status(r, http.StatusInternalServerError)
-			render.JSON(w, r, Response{
-				Status: "Error",
-				Error:  "internal error",
-			})
+			apierr.Render(w, r, opLogger, err)
 			return
 		}
 
@@ -111,3 +122,120 @@ func NewSaveHandler(logger *slog.Logger, urlSaver URLSaver) http.HandlerFunc {
 		})
 	}
 }
+
+// Validate проверяет формат запроса. Правила самой ссылки проверяет
+// links.Service при сохранении.
+func (req Request) Validate() error {
+	return apierr.Validate(req)
+}
+
+// Draft переводит запрос в ссылку для links.Service.
+func (req Request) Draft() links.Draft {
+	return links.Draft{
+		Alias:   req.Alias,
+		URL:     req.URL,
+		Options: req.Options(),
+	}
+}
+
+// Options переводит запрос в параметры ссылки хранилища.
+func (req Request) Options() storage.URLOptions {
+	return storage.URLOptions{
+		MaxClicks:   req.MaxClicks,
+		ActiveFrom:  req.ActiveFrom,
+		ActiveUntil: req.ActiveUntil,
+		Variants:    toStorageVariants(req.Variants),
+		Forward: storage.ForwardOptions{
+			QueryMode:   req.QueryMode,
+			UTM:         req.UTM.params(),
+			ForwardPath: req.ForwardPath,
+		},
+		Meta: storage.LinkMeta{
+			Title:       req.Title,
+			Description: req.Description,
+			ImageURL:    req.Image,
+		},
+		Preview: req.Preview,
+		Organization: storage.Organization{
+			Tags:     storage.NormalizeTags(req.Tags),
+			Folder:   req.Folder,
+			Metadata: req.Metadata,
+		},
+		RedirectCode: req.RedirectCode,
+	}
+}
+
+// FromState возвращает ссылку в виде запроса на её создание.
+func FromState(state storage.LinkState) Request {
+	opts := state.Options
+
+	doc := Request{
+		URL:          state.URL,
+		Alias:        state.Alias,
+		MaxClicks:    opts.MaxClicks,
+		ActiveFrom:   opts.ActiveFrom,
+		ActiveUntil:  opts.ActiveUntil,
+		QueryMode:    opts.Forward.QueryMode,
+		ForwardPath:  opts.Forward.ForwardPath,
+		Title:        opts.Meta.Title,
+		Description:  opts.Meta.Description,
+		Image:        opts.Meta.ImageURL,
+		Preview:      opts.Preview,
+		Tags:         opts.Organization.Tags,
+		Folder:       opts.Organization.Folder,
+		Metadata:     opts.Organization.Metadata,
+		RedirectCode: opts.RedirectCode,
+	}
+
+	if utm := opts.Forward.UTM; len(utm) > 0 {
+		doc.UTM = &UTM{
+			Source:   utm["utm_source"],
+			Medium:   utm["utm_medium"],
+			Campaign: utm["utm_campaign"],
+			Term:     utm["utm_term"],
+			Content:  utm["utm_content"],
+		}
+	}
+
+	if len(doc.Metadata) == 0 {
+		doc.Metadata = nil
+	}
+
+	return doc
+}
+
+func toStorageVariants(variants []Variant) []storage.Variant {
+	if len(variants) == 0 {
+		return nil
+	}
+
+	result := make([]storage.Variant, len(variants))
+	for i, v := range variants {
+		result[i] = storage.Variant{URL: v.URL, Weight: v.Weight}
+	}
+	return result
+}
+
+func (u *UTM) params() map[string]string {
+	if u == nil {
+		return nil
+	}
+
+	params := make(map[string]string)
+	for key, value := range map[string]string{
+		"utm_source":   u.Source,
+		"utm_medium":   u.Medium,
+		"utm_campaign": u.Campaign,
+		"utm_term":     u.Term,
+		"utm_content":  u.Content,
+	} {
+		if value != "" {
+			params[key] = value
+		}
+	}
+
+	if len(params) == 0 {
+		return nil
+	}
+	return params
+}
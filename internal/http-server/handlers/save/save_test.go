@@ -11,18 +11,19 @@ import (
 
 	save_handler "github.com/RozmiDan/url_shortener/internal/http-server/handlers/save"
 	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/RozmiDan/url_shortener/internal/usecase/links"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
-// Mock для интерфейса URLSaver
-type MockURLSaver struct {
+// Mock для интерфейса LinkCreator
+type MockLinkCreator struct {
 	mock.Mock
 }
 
-func (m *MockURLSaver) SaveURL(ctx context.Context, urlToSave string, alias string) (int64, error) {
-	args := m.Called(urlToSave, alias)
-	return args.Get(0).(int64), args.Error(1)
+func (m *MockLinkCreator) Create(ctx context.Context, draft links.Draft, conflict links.Conflict) (string, error) {
+	args := m.Called(draft, conflict)
+	return args.String(0), args.Error(1)
 }
 
 func TestSaveHandler(t *testing.T) {
@@ -31,6 +32,7 @@ func TestSaveHandler(t *testing.T) {
 		name           string
 		alias          string
 		url            string
+		maxClicks      int64
 		mockErr        error
 		expectedStatus int
 		expectedBody   string
@@ -40,7 +42,7 @@ func TestSaveHandler(t *testing.T) {
 			alias:          "custom",
 			url:            "https://example.com",
 			mockErr:        nil,
-			expectedStatus: http.StatusOK,
+			expectedStatus: http.StatusCreated,
 			expectedBody:   `{"status":"OK","alias":"custom"}`,
 		},
 		{
@@ -48,15 +50,40 @@ func TestSaveHandler(t *testing.T) {
 			url:            "https://google.com",
 			alias:          "",
 			mockErr:        nil,
-			expectedStatus: http.StatusOK,
+			expectedStatus: http.StatusCreated,
 			expectedBody:   `"status":"OK"`,
 		},
+		{
+			name:           "successful save with clicks limit",
+			alias:          "invite",
+			url:            "https://example.com/invite",
+			maxClicks:      1,
+			mockErr:        nil,
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `{"status":"OK","alias":"invite"}`,
+		},
+		{
+			name:           "negative clicks limit",
+			url:            "https://example.com",
+			alias:          "negative",
+			maxClicks:      -1,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `"errors":[{"field":"max_clicks","reason":"must be at least 0"}]`,
+		},
 		{
 			name:           "validation error",
 			url:            "not-url",
 			alias:          "fasd",
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   `{"status":"Error","error":"invalid request parameters"}`,
+			expectedBody:   `"code":"validation_failed","errors":[{"field":"url","reason":"must be a valid URL"}]`,
+		},
+		{
+			name:           "invalid link",
+			url:            "https://example.com",
+			alias:          "window",
+			mockErr:        &links.ValidationError{Field: "active_until", Reason: "active_until must be after active_from"},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `"detail":"active_until must be after active_from","code":"validation_failed","errors":[{"field":"active_until","reason":"active_until must be after active_from"}]`,
 		},
 		{
 			name:           "URL already exists",
@@ -64,26 +91,31 @@ func TestSaveHandler(t *testing.T) {
 			alias:          "exists",
 			mockErr:        storage.ErrAliasExists,
 			expectedStatus: http.StatusConflict,
-			expectedBody:   `{"status":"Error","error":"Alias already exists"}`,
+			expectedBody:   `"status":409,"detail":"alias already exists","code":"alias_exists"`,
 		},
 	}
 
 	logger := slog.New(slog.NewTextHandler(bytes.NewBuffer(nil), nil))
 
-	mockSaver := new(MockURLSaver)
-	handler := save_handler.NewSaveHandler(logger, mockSaver)
+	mockCreator := new(MockLinkCreator)
+	handler := save_handler.NewSaveHandler(logger, mockCreator)
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			mockSaver.ExpectedCalls = nil // Сбрасываем ожидания между кейсами
+			mockCreator.ExpectedCalls = nil // Сбрасываем ожидания между кейсами
 
-			if tc.mockErr == nil && tc.url != "not-url" {
-				mockSaver.On("SaveURL", tc.url, mock.AnythingOfType("string")).Return(int64(1), nil)
+			draft := links.Draft{Alias: tc.alias, URL: tc.url, Options: storage.URLOptions{MaxClicks: tc.maxClicks}}
+			if tc.expectedStatus == http.StatusCreated {
+				alias := tc.alias
+				if alias == "" {
+					alias = "AbC123"
+				}
+				mockCreator.On("Create", draft, links.ConflictFail).Return(alias, nil)
 			} else if tc.mockErr != nil {
-				mockSaver.On("SaveURL", tc.url, tc.alias).Return(int64(0), tc.mockErr)
+				mockCreator.On("Create", draft, links.ConflictFail).Return("", tc.mockErr)
 			}
 
-			input := fmt.Sprintf(`{"url": "%s", "alias": "%s"}`, tc.url, tc.alias)
+			input := fmt.Sprintf(`{"url": "%s", "alias": "%s", "max_clicks": %d}`, tc.url, tc.alias, tc.maxClicks)
 
 			req := httptest.NewRequest(http.MethodPost, "/save", bytes.NewReader([]byte(input)))
 			rec := httptest.NewRecorder()
@@ -92,8 +124,11 @@ func TestSaveHandler(t *testing.T) {
 
 			assert.Equal(t, tc.expectedStatus, rec.Code)
 			assert.Contains(t, rec.Body.String(), tc.expectedBody)
+			if tc.expectedStatus != http.StatusCreated {
+				assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+			}
 
-			mockSaver.AssertExpectations(t)
+			mockCreator.AssertExpectations(t)
 		})
 	}
 }
@@ -0,0 +1,170 @@
+package tags_handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/RozmiDan/url_shortener/internal/http-server/apierr"
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+)
+
+type TagLister interface {
+	List(ctx context.Context) ([]storage.Tag, error)
+}
+
+type TagRenamer interface {
+	Rename(ctx context.Context, oldName, newName string) error
+}
+
+type TagMerger interface {
+	Merge(ctx context.Context, sources []string, target string) error
+}
+
+type RenameRequest struct {
+	Name string `json:"name" validate:"required,max=50"`
+}
+
+type MergeRequest struct {
+	Sources []string `json:"sources" validate:"required,min=1,dive,required"`
+	Target  string   `json:"target" validate:"required,max=50"`
+}
+
+type Response struct {
+	Status string        `json:"status"`
+	Tags   []storage.Tag `json:"tags,omitempty"`
+}
+
+// @Title List tags
+// @Description Return all tags with the number of links carrying each of them
+// @Tags tags
+// @Produce json
+// @Success 200 {object} Response
+// @Failure 500 {object} apierr.Problem "internal"
+// @Router /api/v1/tags [get]
+func NewListHandler(logger *slog.Logger, tagLister TagLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.tags.NewListHandler"
+
+		opLogger := logger.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		tags, err := tagLister.List(r.Context())
+		if err != nil {
+			apierr.Render(w, r, opLogger, err)
+			return
+		}
+
+		if tags == nil {
+			tags = []storage.Tag{}
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{
+			Status: "OK",
+			Tags:   tags,
+		})
+	}
+}
+
+// @Title Rename tag
+// @Description Rename a tag on all links at once
+// @Tags tags
+// @Accept  json
+// @Produce json
+// @Param   tag    path  string         true  "Current tag name"
+// @Param   input  body  RenameRequest  true  "New tag name"
+// @Success 200 {object} Response
+// @Failure 400 {object} apierr.Problem "bad_request or validation_failed"
+// @Failure 404 {object} apierr.Problem "tag_not_found"
+// @Failure 409 {object} apierr.Problem "tag_exists: use merge"
+// @Failure 500 {object} apierr.Problem "internal"
+// @Router /api/v1/tags/{tag}/rename [post]
+func NewRenameHandler(logger *slog.Logger, tagRenamer TagRenamer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.tags.NewRenameHandler"
+
+		opLogger := logger.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req RenameRequest
+		if !decodeRequest(w, r, opLogger, &req) {
+			return
+		}
+
+		oldName := chi.URLParam(r, "tag")
+		if err := tagRenamer.Rename(r.Context(), oldName, req.Name); err != nil {
+			apierr.Render(w, r, opLogger, err)
+			return
+		}
+
+		opLogger.Info("tag renamed", slog.String("from", oldName), slog.String("to", req.Name))
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{
+			Status: "OK",
+		})
+	}
+}
+
+// @Title Merge tags
+// @Description Replace source tags with the target tag on all links at once and delete the source tags
+// @Tags tags
+// @Accept  json
+// @Produce json
+// @Param   input  body  MergeRequest  true  "Source and target tags"
+// @Success 200 {object} Response
+// @Failure 400 {object} apierr.Problem "bad_request or validation_failed"
+// @Failure 404 {object} apierr.Problem "tag_not_found: none of the source tags exist"
+// @Failure 500 {object} apierr.Problem "internal"
+// @Router /api/v1/tags/merge [post]
+func NewMergeHandler(logger *slog.Logger, tagMerger TagMerger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.tags.NewMergeHandler"
+
+		opLogger := logger.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req MergeRequest
+		if !decodeRequest(w, r, opLogger, &req) {
+			return
+		}
+
+		if err := tagMerger.Merge(r.Context(), req.Sources, req.Target); err != nil {
+			apierr.Render(w, r, opLogger, err)
+			return
+		}
+
+		opLogger.Info("tags merged", slog.Any("sources", req.Sources), slog.String("target", req.Target))
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{
+			Status: "OK",
+		})
+	}
+}
+
+func decodeRequest(w http.ResponseWriter, r *http.Request, logger *slog.Logger, req any) bool {
+	if err := render.DecodeJSON(r.Body, req); err != nil {
+		logger.Debug("failed to decode request body", slog.Any("err", err))
+		apierr.Write(w, r, apierr.BadRequest("failed to decode request"))
+		return false
+	}
+
+	if err := apierr.Validate(req); err != nil {
+		logger.Debug("validation error", slog.Any("err", err))
+		apierr.Write(w, r, apierr.Invalid(err))
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,91 @@
+package tags_handler_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	tags_handler "github.com/RozmiDan/url_shortener/internal/http-server/handlers/tags"
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/RozmiDan/url_shortener/internal/usecase/tags"
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockTagStorage struct {
+	tags.Repository
+	mock.Mock
+}
+
+func (m *MockTagStorage) RenameTag(ctx context.Context, oldName, newName string) error {
+	return m.Called(oldName, newName).Error(0)
+}
+
+func (m *MockTagStorage) MergeTags(ctx context.Context, sources []string, target string) error {
+	return m.Called(sources, target).Error(0)
+}
+
+func TestRenameHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+
+	testCases := []struct {
+		name           string
+		tag            string
+		body           string
+		newName        string
+		mockErr        error
+		expectedStatus int
+	}{
+		{name: "success", tag: "Promo", body: `{"name":" Marketing "}`, newName: "marketing", expectedStatus: http.StatusOK},
+		{name: "same name", tag: "promo", body: `{"name":"PROMO"}`, expectedStatus: http.StatusBadRequest},
+		{name: "not found", tag: "missing", body: `{"name":"other"}`, newName: "other", mockErr: storage.ErrTagNotFound, expectedStatus: http.StatusNotFound},
+		{name: "exists", tag: "promo", body: `{"name":"sale"}`, newName: "sale", mockErr: storage.ErrTagExists, expectedStatus: http.StatusConflict},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			st := new(MockTagStorage)
+			if tc.newName != "" {
+				st.On("RenameTag", "promo", tc.newName).Return(tc.mockErr).Maybe()
+				st.On("RenameTag", "missing", tc.newName).Return(tc.mockErr).Maybe()
+			}
+
+			r := chi.NewRouter()
+			r.Post("/tags/{tag}/rename", tags_handler.NewRenameHandler(logger, tags.New(st)))
+
+			req := httptest.NewRequest(http.MethodPost, "/tags/"+tc.tag+"/rename", bytes.NewBufferString(tc.body))
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+		})
+	}
+}
+
+func TestMergeHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+
+	st := new(MockTagStorage)
+	st.On("MergeTags", []string{"promo", "sale"}, "marketing").Return(nil)
+
+	handler := tags_handler.NewMergeHandler(logger, tags.New(st))
+
+	req := httptest.NewRequest(http.MethodPost, "/tags/merge",
+		bytes.NewBufferString(`{"sources":["Sale","promo","sale"],"target":"Marketing"}`))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	st.AssertExpectations(t)
+
+	req = httptest.NewRequest(http.MethodPost, "/tags/merge", bytes.NewBufferString(`{"sources":[],"target":"x"}`))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,230 @@
+package update_handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/RozmiDan/url_shortener/internal/http-server/apierr"
+	"github.com/RozmiDan/url_shortener/internal/http-server/etag"
+	save_handler "github.com/RozmiDan/url_shortener/internal/http-server/handlers/save"
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/RozmiDan/url_shortener/internal/usecase/links"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+)
+
+type LinkGetter interface {
+	Get(ctx context.Context, alias string) (storage.LinkState, error)
+}
+
+type LinkPatcher interface {
+	LinkGetter
+	Update(ctx context.Context, alias string, draft links.Draft, expectedVersion int64) (storage.LinkState, error)
+}
+
+// LinkResponse - ссылка в том же виде, в котором она создаётся через POST /url.
+type LinkResponse struct {
+	Status  string                `json:"status"`
+	Link    *save_handler.Request `json:"link,omitempty"`
+	Version int64                 `json:"version,omitempty"`
+}
+
+// @Title Get link
+// @Description Return all mutable fields of the link. The ETag header is used with If-Match in PATCH.
+// @Tags url
+// @Produce json
+// @Param   alias  path  string  true  "Short URL alias"
+// @Success 200 {object} LinkResponse
+// @Failure 404 {object} apierr.Problem "link_not_found"
+// @Failure 500 {object} apierr.Problem "internal"
+// @Router /api/v1/url/{alias} [get]
+func NewGetHandler(logger *slog.Logger, linkGetter LinkGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.update.NewGetHandler"
+
+		opLogger := logger.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		state, err := linkGetter.Get(r.Context(), chi.URLParam(r, "alias"))
+		if err != nil {
+			apierr.Render(w, r, opLogger, err)
+			return
+		}
+
+		doc := save_handler.FromState(state)
+
+		w.Header().Set("ETag", etag.Format(state.Version))
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, LinkResponse{
+			Status:  "OK",
+			Link:    &doc,
+			Version: state.Version,
+		})
+	}
+}
+
+// @Title Patch link
+// @Description Apply a JSON Merge Patch (RFC 7396) to the link: url, alias, max_clicks, active_from, active_until,
+// @Description query_mode, utm, forward_path, title, description, image, preview, tags, folder, metadata, redirect_code.
+// @Description null removes the value. The result is validated by the same rules as POST /url and saved in one transaction.
+// @Description If-Match with the ETag from GET /url/{alias} (or "expected_version" in the patch) makes the update fail
+// @Description with 412 if the link was changed meanwhile.
+// @Tags url
+// @Accept  json
+// @Produce json
+// @Param   alias     path    string               true   "Short URL alias"
+// @Param   If-Match  header  string               false  "ETag of the link version being patched"
+// @Param   input     body    save_handler.Request  true   "Merge patch"
+// @Success 200 {object} LinkResponse
+// @Failure 400 {object} apierr.Problem "bad_request or validation_failed"
+// @Failure 404 {object} apierr.Problem "link_not_found"
+// @Failure 409 {object} apierr.Problem "alias_exists"
+// @Failure 412 {object} apierr.Problem "version_conflict"
+// @Failure 500 {object} apierr.Problem "internal"
+// @Router /api/v1/url/{alias} [patch]
+func NewPatchHandler(logger *slog.Logger, linkPatcher LinkPatcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.update.NewPatchHandler"
+
+		opLogger := logger.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := chi.URLParam(r, "alias")
+
+		var patch map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&patch); err != nil || patch == nil {
+			opLogger.Debug("failed to decode patch", slog.Any("err", err))
+			apierr.Write(w, r, apierr.BadRequest("patch must be a JSON object"))
+			return
+		}
+
+		// expected_version не относится к полям ссылки и в патч не попадает.
+		var expectedVersion int64
+		if raw, ok := patch["expected_version"]; ok {
+			number, ok := raw.(float64)
+			if !ok || number != float64(int64(number)) {
+				apierr.Write(w, r, apierr.BadRequest("expected_version must be an integer"))
+				return
+			}
+			expectedVersion = int64(number)
+			delete(patch, "expected_version")
+		}
+
+		expectedVersion, err := etag.Expected(r.Header.Get("If-Match"), expectedVersion)
+		if err != nil {
+			apierr.Render(w, r, opLogger, err)
+			return
+		}
+
+		state, err := linkPatcher.Get(r.Context(), alias)
+		if err != nil {
+			apierr.Render(w, r, opLogger, err)
+			return
+		}
+
+		if expectedVersion != 0 && expectedVersion != state.Version {
+			apierr.Render(w, r, opLogger, storage.ErrVersionConflict)
+			return
+		}
+
+		doc, err := applyPatch(save_handler.FromState(state), patch)
+		if err != nil {
+			opLogger.Debug("invalid patch", slog.Any("err", err))
+			apierr.Write(w, r, apierr.BadRequest(err.Error()))
+			return
+		}
+
+		if err := doc.Validate(); err != nil {
+			opLogger.Debug("validation error", slog.Any("err", err))
+			apierr.Write(w, r, apierr.Invalid(err))
+			return
+		}
+
+		// Обновление проверяет ту версию, которую мы прочитали, поэтому
+		// параллельное изменение между чтением и записью не потеряется.
+		updated, err := linkPatcher.Update(r.Context(), alias, doc.Draft(), state.Version)
+		if err != nil {
+			apierr.Render(w, r, opLogger, err)
+			return
+		}
+
+		opLogger.Info("link patched", slog.String("alias", alias), slog.String("new_alias", updated.Alias))
+
+		w.Header().Set("ETag", etag.Format(updated.Version))
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, LinkResponse{
+			Status:  "OK",
+			Link:    &doc,
+			Version: updated.Version,
+		})
+	}
+}
+
+// applyPatch применяет merge patch к документу ссылки.
+func applyPatch(doc save_handler.Request, patch map[string]any) (save_handler.Request, error) {
+	if _, ok := patch["variants"]; ok {
+		return save_handler.Request{}, errors.New("variants cannot be changed with PATCH")
+	}
+
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return save_handler.Request{}, err
+	}
+
+	var current map[string]any
+	if err := json.Unmarshal(raw, &current); err != nil {
+		return save_handler.Request{}, err
+	}
+
+	raw, err = json.Marshal(mergePatch(current, patch))
+	if err != nil {
+		return save_handler.Request{}, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+
+	var result save_handler.Request
+	if err := dec.Decode(&result); err != nil {
+		return save_handler.Request{}, errors.New("invalid patch: " + err.Error())
+	}
+
+	if result.Alias == "" {
+		return save_handler.Request{}, errors.New("alias cannot be removed")
+	}
+
+	return result, nil
+}
+
+// mergePatch реализует RFC 7396: объекты сливаются рекурсивно, null
+// удаляет ключ, любое другое значение заменяет прежнее целиком.
+func mergePatch(target, patch any) any {
+	patchObj, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	targetObj, ok := target.(map[string]any)
+	if !ok {
+		targetObj = make(map[string]any)
+	}
+
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = mergePatch(targetObj[key], value)
+	}
+
+	return targetObj
+}
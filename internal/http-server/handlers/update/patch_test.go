@@ -0,0 +1,152 @@
+package update_handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	update_handler "github.com/RozmiDan/url_shortener/internal/http-server/handlers/update"
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/RozmiDan/url_shortener/internal/storage/memory"
+	"github.com/RozmiDan/url_shortener/internal/usecase/links"
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPatchRouter(st *memory.Storage) *chi.Mux {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+
+	service := links.New(st, links.Config{})
+
+	router := chi.NewRouter()
+	router.Get("/url/{alias}", update_handler.NewGetHandler(logger, service))
+	router.Patch("/url/{alias}", update_handler.NewPatchHandler(logger, service))
+	return router
+}
+
+func doPatch(router http.Handler, alias, body, ifMatch string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPatch, "/url/"+alias, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestPatchHandler(t *testing.T) {
+	st := memory.New()
+	_, err := st.SaveURL(context.Background(), "https://example.com", "abc", storage.URLOptions{
+		MaxClicks: 10,
+		Meta:      storage.LinkMeta{Title: "Example"},
+		Organization: storage.Organization{
+			Tags:     []string{"a", "b"},
+			Metadata: map[string]any{"owner": "team", "env": "prod"},
+		},
+	})
+	require.NoError(t, err)
+
+	router := newPatchRouter(st)
+
+	rr := doPatch(router, "abc", `{
+		"url": "https://example.org",
+		"title": null,
+		"tags": ["c"],
+		"metadata": {"env": null, "team": "growth"},
+		"redirect_code": 308
+	}`, "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.NotEmpty(t, rr.Header().Get("ETag"))
+
+	state, err := st.GetLinkState(context.Background(), "abc")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.org", state.URL)
+	assert.EqualValues(t, 10, state.Options.MaxClicks)
+	assert.Empty(t, state.Options.Meta.Title)
+	assert.Equal(t, []string{"c"}, state.Options.Organization.Tags)
+	assert.Equal(t, map[string]any{"owner": "team", "team": "growth"}, state.Options.Organization.Metadata)
+	assert.Equal(t, http.StatusPermanentRedirect, state.Options.RedirectCode)
+}
+
+func TestPatchHandlerErrors(t *testing.T) {
+	st := memory.New()
+	for _, alias := range []string{"abc", "taken"} {
+		_, err := st.SaveURL(context.Background(), "https://example.com", alias, storage.URLOptions{})
+		require.NoError(t, err)
+	}
+
+	router := newPatchRouter(st)
+
+	testCases := []struct {
+		name           string
+		alias          string
+		body           string
+		expectedStatus int
+	}{
+		{name: "not an object", alias: "abc", body: `[1]`, expectedStatus: http.StatusBadRequest},
+		{name: "unknown field", alias: "abc", body: `{"foo": 1}`, expectedStatus: http.StatusBadRequest},
+		{name: "url removed", alias: "abc", body: `{"url": null}`, expectedStatus: http.StatusBadRequest},
+		{name: "alias removed", alias: "abc", body: `{"alias": null}`, expectedStatus: http.StatusBadRequest},
+		{name: "variants", alias: "abc", body: `{"variants": []}`, expectedStatus: http.StatusBadRequest},
+		{name: "bad redirect code", alias: "abc", body: `{"redirect_code": 303}`, expectedStatus: http.StatusBadRequest},
+		{name: "alias exists", alias: "abc", body: `{"alias": "taken"}`, expectedStatus: http.StatusConflict},
+		{name: "not found", alias: "missing", body: `{}`, expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := doPatch(router, tc.alias, tc.body, "")
+			assert.Equal(t, tc.expectedStatus, rr.Code, rr.Body.String())
+		})
+	}
+}
+
+func TestPatchHandlerIfMatch(t *testing.T) {
+	st := memory.New()
+	_, err := st.SaveURL(context.Background(), "https://example.com", "abc", storage.URLOptions{})
+	require.NoError(t, err)
+
+	router := newPatchRouter(st)
+
+	req := httptest.NewRequest(http.MethodGet, "/url/abc", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var resp update_handler.LinkResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, "abc", resp.Link.Alias)
+
+	etag := rr.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	// Первый PATCH с актуальным ETag проходит и меняет версию.
+	rr = doPatch(router, "abc", `{"folder": "first"}`, etag)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.NotEqual(t, etag, rr.Header().Get("ETag"))
+
+	// Второй PATCH со старым ETag не должен затереть первое изменение.
+	rr = doPatch(router, "abc", `{"folder": "second"}`, etag)
+	assert.Equal(t, http.StatusPreconditionFailed, rr.Code)
+
+	state, err := st.GetLinkState(context.Background(), "abc")
+	require.NoError(t, err)
+	assert.Equal(t, "first", state.Options.Organization.Folder)
+
+	rr = doPatch(router, "abc", `{"folder": "third"}`, "*")
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	// expected_version в теле работает так же, как If-Match.
+	rr = doPatch(router, "abc", `{"folder": "fourth", "expected_version": 1}`, "")
+	assert.Equal(t, http.StatusPreconditionFailed, rr.Code)
+
+	rr = doPatch(router, "abc", `{"folder": "fourth", "expected_version": 3}`, "")
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, `"4"`, rr.Header().Get("ETag"))
+}
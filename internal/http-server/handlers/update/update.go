@@ -1,43 +1,69 @@
 package update_handler
 
 import (
-	"errors"
+	"context"
 	"log/slog"
 	"net/http"
+	"time"
 
+	"github.com/RozmiDan/url_shortener/internal/http-server/apierr"
+	"github.com/RozmiDan/url_shortener/internal/http-server/etag"
 	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/RozmiDan/url_shortener/internal/usecase/links"
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/render"
 )
 
-type URLUpdater interface {
-	UpdateURL(currAlias string, newAlias string) error
+type LinkChanger interface {
+	Apply(ctx context.Context, alias string, change links.Change, expectedVersion int64) (int64, error)
 }
 
 type Request struct {
-	NewAlias string `json:"newAlias"`
+	NewAlias string  `json:"newAlias,omitempty"`
+	Window   *Window `json:"window,omitempty"`
+
+	// Tags, Folder и Metadata заменяют значения целиком, отсутствующее
+	// поле не меняется.
+	Tags     *[]string       `json:"tags,omitempty" validate:"omitempty,max=20,dive,max=50"`
+	Folder   *string         `json:"folder,omitempty" validate:"omitempty,max=100"`
+	Metadata *map[string]any `json:"metadata,omitempty"`
+
+	// ExpectedVersion - версия ссылки, которую клиент собирается изменить,
+	// то же, что If-Match. 0 - без проверки.
+	ExpectedVersion int64 `json:"expected_version,omitempty" validate:"omitempty,min=1"`
+}
+
+// Window заменяет окно активности ссылки целиком, отсутствующая граница
+// снимает ограничение.
+type Window struct {
+	ActiveFrom  *time.Time `json:"active_from,omitempty"`
+	ActiveUntil *time.Time `json:"active_until,omitempty"`
 }
 
 type Response struct {
-	Status string `json:"status"`
-	Error  string `json:"error,omitempty"`
+	Status  string `json:"status"`
+	Version int64  `json:"version,omitempty"`
 }
 
 // @Title Update URL alias
-// @Description Update existing short URL alias
+// @Description Update existing short URL alias, its activation window, tags, folder and metadata.
+// @Description Deprecated: use PATCH /url/{alias}, which can change every field of the link.
 // @Tags url
 // @Accept  json
 // @Produce json
-// @Param   alias  path  string  true  "Current short URL alias"
-// @Param   input  body  Request  true  "New alias data"
+// @Param   alias     path    string   true   "Current short URL alias"
+// @Param   If-Match  header  string   false  "ETag of the link version being updated"
+// @Param   input     body    Request  true   "New alias data"
 // @Success 200 {object} Response
-// @Failure 400 {object} Response "Invalid input data"
-// @Failure 404 {object} Response "Alias not found"
-// @Failure 409 {object} Response "New alias already exists"
-// @Failure 500 {object} Response "Internal server error"
-// @Router /url/{alias} [put]
-func NewUpdateHandler(logger *slog.Logger, urlUpdater URLUpdater) http.HandlerFunc {
+// @Failure 400 {object} apierr.Problem "bad_request or validation_failed"
+// @Failure 404 {object} apierr.Problem "link_not_found"
+// @Failure 409 {object} apierr.Problem "alias_exists"
+// @Failure 412 {object} apierr.Problem "version_conflict"
+// @Failure 500 {object} apierr.Problem "internal"
+// @Deprecated
+// @Router /api/v1/url/{alias} [put]
+func NewUpdateHandler(logger *slog.Logger, changer LinkChanger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.update.newupdatehandler"
 
@@ -49,83 +75,59 @@ func NewUpdateHandler(logger *slog.Logger, urlUpdater URLUpdater) http.HandlerFu
 		curAlias := chi.URLParam(r, "alias")
 		if curAlias == "" {
 			logger.Debug("empty current alias")
-			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, Response{
-				Status: "Error",
-				Error:  "empty current alias",
-			})
+			apierr.Write(w, r, apierr.BadRequest("empty current alias"))
 			return
 		}
 
 		var req Request
 		err := render.DecodeJSON(r.Body, &req)
 		if err != nil {
-			logger.Error("failed to decode request body")
-			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, Response{
-				Status: "Error",
-				Error:  "failed to decode request",
-			})
+			logger.Debug("failed to decode request body", slog.Any("err", err))
+			apierr.Write(w, r, apierr.BadRequest("failed to decode request"))
 			return
 		}
 
-		//logger.Info("request body decoded\n", slog.Any("request", req))
-
-		newAlias := req.NewAlias
-
-		if newAlias == curAlias {
-			logger.Debug("the same alias")
-			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, Response{
-				Status: "Error",
-				Error:  "new alias must be different",
-			})
+		if err := apierr.Validate(req); err != nil {
+			logger.Debug("validation error", slog.Any("err", err))
+			apierr.Write(w, r, apierr.Invalid(err))
 			return
 		}
 
-		if req.NewAlias == "" {
-			logger.Debug("new alias is empty")
-			render.Status(r, http.StatusBadRequest)
-			render.JSON(w, r, Response{
-				Status: "Error",
-				Error:  "new alias is required",
-			})
+		expectedVersion, err := etag.Expected(r.Header.Get("If-Match"), req.ExpectedVersion)
+		if err != nil {
+			apierr.Render(w, r, logger, err)
 			return
 		}
 
-		if err := urlUpdater.UpdateURL(curAlias, newAlias); err != nil {
-			if errors.Is(err, storage.ErrAliasExists) {
-				logger.Debug("Cant update alias\n", slog.Any("err", err))
-				render.Status(r, http.StatusConflict)
-				render.JSON(w, r, Response{
-					Status: "Error",
-					Error:  "alias already exists",
-				})
-				return
-			} else if errors.Is(err, storage.ErrAliasNotFound) {
-				logger.Debug("Cant update alias\n", slog.Any("err", err))
-				render.Status(r, http.StatusNotFound)
-				render.JSON(w, r, Response{
-					Status: "Error",
-					Error:  "alias not found",
-				})
-				return
-			}
-
-			logger.Error("Cant update alias\n", slog.Any("err", err))
-			render.Status(r, http.StatusInternalServerError)
-			render.JSON(w, r, Response{
-				Status: "Error",
-				Error:  "internal error",
-			})
+		version, err := changer.Apply(r.Context(), curAlias, req.change(), expectedVersion)
+		if err != nil {
+			apierr.Render(w, r, logger, err)
 			return
 		}
 
-		//logger.Info("Elias has been successfully updated")
-
+		w.Header().Set("ETag", etag.Format(version))
 		render.Status(r, http.StatusOK)
 		render.JSON(w, r, Response{
-			Status: "OK",
+			Status:  "OK",
+			Version: version,
 		})
 	}
 }
+
+func (req Request) change() links.Change {
+	change := links.Change{
+		NewAlias: req.NewAlias,
+		Organization: storage.OrganizationPatch{
+			Tags:     req.Tags,
+			Folder:   req.Folder,
+			Metadata: req.Metadata,
+		},
+	}
+	if req.Window != nil {
+		change.Window = &links.Window{
+			ActiveFrom:  req.Window.ActiveFrom,
+			ActiveUntil: req.Window.ActiveUntil,
+		}
+	}
+	return change
+}
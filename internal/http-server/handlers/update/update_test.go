@@ -2,26 +2,42 @@ package update_handler_test
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	update_handler "github.com/RozmiDan/url_shortener/internal/http-server/handlers/update"
 	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/RozmiDan/url_shortener/internal/usecase/links"
 	"github.com/go-chi/chi"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
+// MockURLUpdater - хранилище за links.Service, остальные методы
+// Repository в тестах не вызываются.
 type MockURLUpdater struct {
+	links.Repository
 	mock.Mock
 }
 
-func (m *MockURLUpdater) UpdateURL(currAlias string, newAlias string) error {
-	args := m.Called(currAlias, newAlias)
-	return args.Error(0)
+func (m *MockURLUpdater) GetLinkState(ctx context.Context, alias string) (storage.LinkState, error) {
+	args := m.Called(alias)
+	return args.Get(0).(storage.LinkState), args.Error(1)
+}
+
+func (m *MockURLUpdater) UpdateLink(ctx context.Context, alias string, state storage.LinkState, expectedVersion int64) (storage.LinkState, error) {
+	args := m.Called(alias, state, expectedVersion)
+	return args.Get(0).(storage.LinkState), args.Error(1)
+}
+
+// linkState - состояние ссылки, которое возвращает мок.
+func linkState(alias string, version int64) storage.LinkState {
+	return storage.LinkState{Alias: alias, URL: "https://example.com", Version: version}
 }
 
 func TestUpdateHandlerIntegration(t *testing.T) {
@@ -31,7 +47,8 @@ func TestUpdateHandlerIntegration(t *testing.T) {
 		name             string
 		currAlias        string
 		newAlias         string
-		mockErr          error
+		currErr          error
+		newAliasTaken    bool
 		expectedStatus   int
 		expectedContains string
 		expectUpdateCall bool
@@ -40,7 +57,6 @@ func TestUpdateHandlerIntegration(t *testing.T) {
 			name:             "successful update",
 			currAlias:        "oldAlias",
 			newAlias:         "newAlias",
-			mockErr:          nil,
 			expectedStatus:   http.StatusOK,
 			expectedContains: `"status":"OK"`,
 			expectUpdateCall: true,
@@ -49,48 +65,48 @@ func TestUpdateHandlerIntegration(t *testing.T) {
 			name:             "alias already exists",
 			currAlias:        "alias1",
 			newAlias:         "alias2",
-			mockErr:          storage.ErrAliasExists,
+			newAliasTaken:    true,
 			expectedStatus:   http.StatusConflict,
-			expectedContains: `"error":"alias already exists"`,
-			expectUpdateCall: true,
+			expectedContains: `"detail":"alias already exists"`,
 		},
 		{
 			name:             "alias not found",
 			currAlias:        "notfound",
 			newAlias:         "updateMe",
-			mockErr:          storage.ErrAliasNotFound,
+			currErr:          storage.ErrAliasNotFound,
 			expectedStatus:   http.StatusNotFound,
-			expectedContains: `"error":"alias not found"`,
-			expectUpdateCall: true,
+			expectedContains: `"detail":"alias not found"`,
 		},
 		{
 			name:             "empty newAlias",
 			currAlias:        "aliasX",
 			newAlias:         "",
-			mockErr:          nil,
 			expectedStatus:   http.StatusBadRequest,
-			expectedContains: `"error":"new alias is required"`,
-			expectUpdateCall: false,
+			expectedContains: `"detail":"new alias, window, tags, folder or metadata is required"`,
 		},
 		{
 			name:             "same alias",
 			currAlias:        "same",
 			newAlias:         "same",
-			mockErr:          nil,
 			expectedStatus:   http.StatusBadRequest,
-			expectedContains: `"error":"new alias must be different"`,
-			expectUpdateCall: false,
+			expectedContains: `"detail":"new alias must be different"`,
 		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			mockUpdater := new(MockURLUpdater)
-			handler := update_handler.NewUpdateHandler(logger, mockUpdater)
+			handler := update_handler.NewUpdateHandler(logger, links.New(mockUpdater, links.Config{}))
 
-			// Настраиваем ожидания мока только если вызов ожидается
+			mockUpdater.On("GetLinkState", tc.currAlias).Return(linkState(tc.currAlias, 1), tc.currErr).Maybe()
+			if tc.newAliasTaken {
+				mockUpdater.On("GetLinkState", tc.newAlias).Return(linkState(tc.newAlias, 1), nil)
+			} else {
+				mockUpdater.On("GetLinkState", tc.newAlias).Return(storage.LinkState{}, storage.ErrAliasNotFound).Maybe()
+			}
 			if tc.expectUpdateCall {
-				mockUpdater.On("UpdateURL", tc.currAlias, tc.newAlias).Return(tc.mockErr)
+				mockUpdater.On("UpdateLink", tc.currAlias, linkState(tc.newAlias, 1), int64(1)).
+					Return(linkState(tc.newAlias, 2), nil)
 			}
 
 			input := fmt.Sprintf(`{"newAlias": "%s"}`, tc.newAlias)
@@ -109,10 +125,141 @@ func TestUpdateHandlerIntegration(t *testing.T) {
 
 			// Проверяем вызовы мока только если они ожидаются
 			if tc.expectUpdateCall {
-				mockUpdater.AssertCalled(t, "UpdateURL", tc.currAlias, tc.newAlias)
+				mockUpdater.AssertExpectations(t)
 			} else {
-				mockUpdater.AssertNotCalled(t, "UpdateURL")
+				mockUpdater.AssertNotCalled(t, "UpdateLink")
 			}
 		})
 	}
 }
+
+func TestUpdateHandlerWindow(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+
+	from := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2030, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name             string
+		input            string
+		currErr          error
+		expectedStatus   int
+		expectedContains string
+		expectWindowCall bool
+		expectedFrom     *time.Time
+		expectedUntil    *time.Time
+	}{
+		{
+			name:             "set window",
+			input:            `{"window": {"active_from": "2030-01-01T00:00:00Z", "active_until": "2030-02-01T00:00:00Z"}}`,
+			expectedStatus:   http.StatusOK,
+			expectedContains: `"status":"OK"`,
+			expectWindowCall: true,
+			expectedFrom:     &from,
+			expectedUntil:    &until,
+		},
+		{
+			name:             "clear window",
+			input:            `{"window": {}}`,
+			expectedStatus:   http.StatusOK,
+			expectedContains: `"status":"OK"`,
+			expectWindowCall: true,
+		},
+		{
+			name:             "alias not found",
+			input:            `{"window": {"active_from": "2030-01-01T00:00:00Z"}}`,
+			currErr:          storage.ErrAliasNotFound,
+			expectedStatus:   http.StatusNotFound,
+			expectedContains: `"detail":"alias not found"`,
+		},
+		{
+			name:             "until before from",
+			input:            `{"window": {"active_from": "2030-02-01T00:00:00Z", "active_until": "2030-01-01T00:00:00Z"}}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedContains: `"detail":"active_until must be after active_from"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockUpdater := new(MockURLUpdater)
+			handler := update_handler.NewUpdateHandler(logger, links.New(mockUpdater, links.Config{}))
+
+			// У текущей ссылки уже есть окно, запрос заменяет его целиком.
+			current := linkState("campaign", 1)
+			current.Options.ActiveFrom = &until
+			mockUpdater.On("GetLinkState", "campaign").Return(current, tc.currErr).Maybe()
+
+			if tc.expectWindowCall {
+				want := linkState("campaign", 1)
+				want.Options.ActiveFrom = tc.expectedFrom
+				want.Options.ActiveUntil = tc.expectedUntil
+				mockUpdater.On("UpdateLink", "campaign", want, int64(1)).Return(linkState("campaign", 2), nil)
+			}
+
+			r := chi.NewRouter()
+			r.Put("/url/{alias}", handler)
+
+			req := httptest.NewRequest(http.MethodPut, "/url/campaign", bytes.NewReader([]byte(tc.input)))
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			assert.Contains(t, rec.Body.String(), tc.expectedContains)
+
+			if tc.expectWindowCall {
+				mockUpdater.AssertExpectations(t)
+			} else {
+				mockUpdater.AssertNotCalled(t, "UpdateLink")
+			}
+		})
+	}
+}
+
+func TestUpdateHandlerVersion(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+
+	input := `{"newAlias": "renamed", "folder": "spring", "window": {}}`
+
+	t.Run("changes are applied at once", func(t *testing.T) {
+		want := linkState("renamed", 4)
+		want.Options.Organization.Folder = "spring"
+
+		mockUpdater := new(MockURLUpdater)
+		mockUpdater.On("GetLinkState", "campaign").Return(linkState("campaign", 4), nil)
+		mockUpdater.On("GetLinkState", "renamed").Return(storage.LinkState{}, storage.ErrAliasNotFound)
+		mockUpdater.On("UpdateLink", "campaign", want, int64(4)).Return(linkState("renamed", 5), nil)
+
+		r := chi.NewRouter()
+		r.Put("/url/{alias}", update_handler.NewUpdateHandler(logger, links.New(mockUpdater, links.Config{})))
+
+		req := httptest.NewRequest(http.MethodPut, "/url/campaign", bytes.NewReader([]byte(input)))
+		req.Header.Set("If-Match", `"4"`)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.Equal(t, `"5"`, rec.Header().Get("ETag"))
+		assert.Contains(t, rec.Body.String(), `"version":5`)
+		mockUpdater.AssertExpectations(t)
+	})
+
+	t.Run("version conflict", func(t *testing.T) {
+		mockUpdater := new(MockURLUpdater)
+		mockUpdater.On("GetLinkState", "campaign").Return(linkState("campaign", 5), nil)
+
+		r := chi.NewRouter()
+		r.Put("/url/{alias}", update_handler.NewUpdateHandler(logger, links.New(mockUpdater, links.Config{})))
+
+		body := `{"newAlias": "renamed", "folder": "spring", "window": {}, "expected_version": 4}`
+		req := httptest.NewRequest(http.MethodPut, "/url/campaign", bytes.NewReader([]byte(body)))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"detail":"link was modified concurrently"`)
+		assert.Contains(t, rec.Body.String(), `"code":"version_conflict"`)
+		mockUpdater.AssertNotCalled(t, "UpdateLink")
+	})
+}
@@ -0,0 +1,56 @@
+package variants_handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/RozmiDan/url_shortener/internal/http-server/apierr"
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+)
+
+type VariantLister interface {
+	Variants(ctx context.Context, alias string) ([]storage.Variant, error)
+}
+
+type Response struct {
+	Status   string            `json:"status"`
+	Variants []storage.Variant `json:"variants,omitempty"`
+}
+
+// @Title List A/B variants
+// @Description Return A/B variants of the link with the number of redirects served by each
+// @Tags url
+// @Produce json
+// @Param   alias  path  string  true  "Short URL alias"
+// @Success 200 {object} Response
+// @Failure 404 {object} apierr.Problem "link_not_found"
+// @Failure 500 {object} apierr.Problem "internal"
+// @Router /api/v1/url/{alias}/variants [get]
+func NewListHandler(logger *slog.Logger, variantLister VariantLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.variants.NewListHandler"
+
+		opLogger := logger.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := chi.URLParam(r, "alias")
+
+		variants, err := variantLister.Variants(r.Context(), alias)
+		if err != nil {
+			apierr.Render(w, r, opLogger, err)
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{
+			Status:   "OK",
+			Variants: variants,
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package variants_handler_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	variants_handler "github.com/RozmiDan/url_shortener/internal/http-server/handlers/variants"
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/RozmiDan/url_shortener/internal/usecase/links"
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockVariantLister struct {
+	links.Repository
+	mock.Mock
+}
+
+func (m *MockVariantLister) ListVariants(ctx context.Context, alias string) ([]storage.Variant, error) {
+	args := m.Called(alias)
+	return args.Get(0).([]storage.Variant), args.Error(1)
+}
+
+func TestListHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+
+	testCases := []struct {
+		name             string
+		alias            string
+		variants         []storage.Variant
+		mockErr          error
+		expectedStatus   int
+		expectedContains string
+	}{
+		{
+			name:  "success",
+			alias: "promo",
+			variants: []storage.Variant{
+				{ID: 1, URL: "https://a.example.com", Weight: 70, Clicks: 7},
+				{ID: 2, URL: "https://b.example.com", Weight: 30, Clicks: 3},
+			},
+			expectedStatus:   http.StatusOK,
+			expectedContains: `{"id":1,"url":"https://a.example.com","weight":70,"clicks":7}`,
+		},
+		{
+			name:             "alias not found",
+			alias:            "missing",
+			mockErr:          storage.ErrAliasNotFound,
+			expectedStatus:   http.StatusNotFound,
+			expectedContains: `"detail":"alias not found"`,
+		},
+		{
+			name:             "internal error",
+			alias:            "broken",
+			mockErr:          errors.New("some internal error"),
+			expectedStatus:   http.StatusInternalServerError,
+			expectedContains: `"detail":"internal error"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			lister := new(MockVariantLister)
+			lister.On("ListVariants", tc.alias).Return(tc.variants, tc.mockErr)
+
+			r := chi.NewRouter()
+			r.Get("/url/{alias}/variants", variants_handler.NewListHandler(logger, links.New(lister, links.Config{})))
+
+			req := httptest.NewRequest(http.MethodGet, "/url/"+tc.alias+"/variants", nil)
+			rec := httptest.NewRecorder()
+
+			r.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			assert.Contains(t, rec.Body.String(), tc.expectedContains)
+		})
+	}
+}
@@ -0,0 +1,376 @@
+package webhooks_handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/RozmiDan/url_shortener/internal/http-server/apierr"
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+)
+
+type WebhookCreator interface {
+	Create(ctx context.Context, webhook storage.Webhook) (storage.Webhook, error)
+}
+
+type WebhookLister interface {
+	List(ctx context.Context) ([]storage.Webhook, error)
+}
+
+type WebhookGetter interface {
+	Get(ctx context.Context, id int64) (storage.Webhook, error)
+}
+
+type WebhookUpdater interface {
+	Update(ctx context.Context, webhook storage.Webhook) (storage.Webhook, error)
+}
+
+type WebhookDeleter interface {
+	Delete(ctx context.Context, id int64) error
+}
+
+type DeadLetterLister interface {
+	DeadLetters(ctx context.Context) ([]storage.Delivery, error)
+}
+
+type DeliveryRetrier interface {
+	Retry(ctx context.Context, id int64) error
+}
+
+type Request struct {
+	URL    string   `json:"url" validate:"required,url"`
+	Events []string `json:"events" validate:"required,min=1,dive,oneof=link.created link.renamed link.deleted link.clicked"`
+	// Secret - ключ подписи HMAC, если не задан при создании, генерируется.
+	Secret string `json:"secret,omitempty"`
+	Active *bool  `json:"active,omitempty"`
+}
+
+type Response struct {
+	Status  string           `json:"status"`
+	Webhook *storage.Webhook `json:"webhook,omitempty"`
+	// Secret возвращается только при создании подписки.
+	Secret string `json:"secret,omitempty"`
+}
+
+type ListResponse struct {
+	Status   string            `json:"status"`
+	Webhooks []storage.Webhook `json:"webhooks"`
+}
+
+type DeadLetterResponse struct {
+	Status     string             `json:"status"`
+	Deliveries []storage.Delivery `json:"deliveries"`
+}
+
+// @Title Create webhook
+// @Description Subscribe a URL to link lifecycle and click events. At least one event is required
+// @Description The URL must use http or https and point to a public address
+// @Tags webhooks
+// @Accept  json
+// @Produce json
+// @Param   input  body  Request  true  "Webhook subscription"
+// @Success 201 {object} Response
+// @Failure 400 {object} apierr.Problem "bad_request or validation_failed"
+// @Failure 500 {object} apierr.Problem "internal"
+// @Router /api/v1/webhooks [post]
+func NewCreateHandler(logger *slog.Logger, creator WebhookCreator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.webhooks.NewCreateHandler"
+
+		opLogger := logger.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+		if !decodeRequest(w, r, opLogger, &req) {
+			return
+		}
+
+		created, err := creator.Create(r.Context(), req.toWebhook())
+		if err != nil {
+			apierr.Render(w, r, opLogger, err)
+			return
+		}
+
+		opLogger.Info("webhook created", slog.Int64("id", created.ID))
+
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, Response{
+			Status:  "OK",
+			Webhook: &created,
+			Secret:  created.Secret,
+		})
+	}
+}
+
+// @Title List webhooks
+// @Description Return all webhook subscriptions
+// @Tags webhooks
+// @Produce json
+// @Success 200 {object} ListResponse
+// @Failure 500 {object} apierr.Problem "internal"
+// @Router /api/v1/webhooks [get]
+func NewListHandler(logger *slog.Logger, lister WebhookLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.webhooks.NewListHandler"
+
+		opLogger := logger.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		webhooks, err := lister.List(r.Context())
+		if err != nil {
+			apierr.Render(w, r, opLogger, err)
+			return
+		}
+
+		if webhooks == nil {
+			webhooks = []storage.Webhook{}
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, ListResponse{
+			Status:   "OK",
+			Webhooks: webhooks,
+		})
+	}
+}
+
+// @Title Get webhook
+// @Description Return a webhook subscription
+// @Tags webhooks
+// @Produce json
+// @Param   id  path  int  true  "Webhook id"
+// @Success 200 {object} Response
+// @Failure 400 {object} apierr.Problem "bad_request: invalid webhook id"
+// @Failure 404 {object} apierr.Problem "webhook_not_found"
+// @Failure 500 {object} apierr.Problem "internal"
+// @Router /api/v1/webhooks/{id} [get]
+func NewGetHandler(logger *slog.Logger, getter WebhookGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.webhooks.NewGetHandler"
+
+		opLogger := logger.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		id, ok := parseID(w, r, opLogger)
+		if !ok {
+			return
+		}
+
+		webhook, err := getter.Get(r.Context(), id)
+		if err != nil {
+			apierr.Render(w, r, opLogger, err)
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{
+			Status:  "OK",
+			Webhook: &webhook,
+		})
+	}
+}
+
+// @Title Update webhook
+// @Description Replace URL, event filter and state of a webhook subscription. Empty secret keeps the current one
+// @Description The URL must use http or https and point to a public address
+// @Tags webhooks
+// @Accept  json
+// @Produce json
+// @Param   id     path  int      true  "Webhook id"
+// @Param   input  body  Request  true  "Webhook subscription"
+// @Success 200 {object} Response
+// @Failure 400 {object} apierr.Problem "bad_request or validation_failed"
+// @Failure 404 {object} apierr.Problem "webhook_not_found"
+// @Failure 500 {object} apierr.Problem "internal"
+// @Router /api/v1/webhooks/{id} [put]
+func NewUpdateHandler(logger *slog.Logger, updater WebhookUpdater) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.webhooks.NewUpdateHandler"
+
+		opLogger := logger.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		id, ok := parseID(w, r, opLogger)
+		if !ok {
+			return
+		}
+
+		var req Request
+		if !decodeRequest(w, r, opLogger, &req) {
+			return
+		}
+
+		webhook := req.toWebhook()
+		webhook.ID = id
+
+		updated, err := updater.Update(r.Context(), webhook)
+		if err != nil {
+			apierr.Render(w, r, opLogger, err)
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{
+			Status:  "OK",
+			Webhook: &updated,
+		})
+	}
+}
+
+// @Title Delete webhook
+// @Description Delete a webhook subscription together with its pending deliveries
+// @Tags webhooks
+// @Produce json
+// @Param   id  path  int  true  "Webhook id"
+// @Success 200 {object} Response
+// @Failure 400 {object} apierr.Problem "bad_request: invalid webhook id"
+// @Failure 404 {object} apierr.Problem "webhook_not_found"
+// @Failure 500 {object} apierr.Problem "internal"
+// @Router /api/v1/webhooks/{id} [delete]
+func NewDeleteHandler(logger *slog.Logger, deleter WebhookDeleter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.webhooks.NewDeleteHandler"
+
+		opLogger := logger.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		id, ok := parseID(w, r, opLogger)
+		if !ok {
+			return
+		}
+
+		if err := deleter.Delete(r.Context(), id); err != nil {
+			apierr.Render(w, r, opLogger, err)
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{
+			Status: "OK",
+		})
+	}
+}
+
+// @Title List dead letters
+// @Description Return webhook deliveries that failed after all retry attempts
+// @Tags webhooks
+// @Produce json
+// @Success 200 {object} DeadLetterResponse
+// @Failure 500 {object} apierr.Problem "internal"
+// @Router /api/v1/webhooks/dead-letters [get]
+func NewDeadLetterHandler(logger *slog.Logger, lister DeadLetterLister) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.webhooks.NewDeadLetterHandler"
+
+		opLogger := logger.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		deliveries, err := lister.DeadLetters(r.Context())
+		if err != nil {
+			apierr.Render(w, r, opLogger, err)
+			return
+		}
+
+		if deliveries == nil {
+			deliveries = []storage.Delivery{}
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, DeadLetterResponse{
+			Status:     "OK",
+			Deliveries: deliveries,
+		})
+	}
+}
+
+// @Title Retry dead letter
+// @Description Put a failed webhook delivery back into the delivery queue
+// @Tags webhooks
+// @Produce json
+// @Param   id  path  int  true  "Delivery id"
+// @Success 200 {object} Response
+// @Failure 400 {object} apierr.Problem "bad_request: invalid delivery id"
+// @Failure 404 {object} apierr.Problem "delivery_not_found"
+// @Failure 500 {object} apierr.Problem "internal"
+// @Router /api/v1/webhooks/dead-letters/{id}/retry [post]
+func NewRetryHandler(logger *slog.Logger, retrier DeliveryRetrier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.webhooks.NewRetryHandler"
+
+		opLogger := logger.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		id, ok := parseID(w, r, opLogger)
+		if !ok {
+			return
+		}
+
+		if err := retrier.Retry(r.Context(), id); err != nil {
+			apierr.Render(w, r, opLogger, err)
+			return
+		}
+
+		render.Status(r, http.StatusOK)
+		render.JSON(w, r, Response{
+			Status: "OK",
+		})
+	}
+}
+
+func (req Request) toWebhook() storage.Webhook {
+	active := true
+	if req.Active != nil {
+		active = *req.Active
+	}
+
+	return storage.Webhook{
+		URL:    req.URL,
+		Secret: req.Secret,
+		Events: req.Events,
+		Active: active,
+	}
+}
+
+func decodeRequest(w http.ResponseWriter, r *http.Request, logger *slog.Logger, req *Request) bool {
+	if err := render.DecodeJSON(r.Body, req); err != nil {
+		logger.Debug("failed to decode request body", slog.Any("err", err))
+		apierr.Write(w, r, apierr.BadRequest("failed to decode request"))
+		return false
+	}
+
+	if err := apierr.Validate(req); err != nil {
+		logger.Debug("validation error", slog.Any("err", err))
+		apierr.Write(w, r, apierr.Invalid(err))
+		return false
+	}
+
+	return true
+}
+
+func parseID(w http.ResponseWriter, r *http.Request, logger *slog.Logger) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		logger.Debug("invalid id", slog.Any("err", err))
+		apierr.Write(w, r, apierr.BadRequest("invalid id"))
+		return 0, false
+	}
+	return id, true
+}
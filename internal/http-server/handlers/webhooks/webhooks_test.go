@@ -0,0 +1,176 @@
+package webhooks_handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	webhooks_handler "github.com/RozmiDan/url_shortener/internal/http-server/handlers/webhooks"
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/RozmiDan/url_shortener/internal/usecase/webhook"
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockWebhookStorage struct {
+	webhook.Repository
+	mock.Mock
+}
+
+func (m *MockWebhookStorage) CreateWebhook(ctx context.Context, webhook storage.Webhook) (storage.Webhook, error) {
+	args := m.Called(webhook)
+	// Хранилище возвращает подписку с присвоенным id.
+	webhook.ID = args.Get(0).(int64)
+	return webhook, args.Error(1)
+}
+
+func (m *MockWebhookStorage) GetWebhook(ctx context.Context, id int64) (storage.Webhook, error) {
+	args := m.Called(id)
+	return args.Get(0).(storage.Webhook), args.Error(1)
+}
+
+func (m *MockWebhookStorage) RetryDelivery(ctx context.Context, id int64) error {
+	return m.Called(id).Error(0)
+}
+
+func TestCreateHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+
+	testCases := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedEvents []string
+		secret         string
+	}{
+		{
+			name:           "generated secret",
+			body:           `{"url":"https://crm.example.com/hook","events":["link.created","link.deleted"]}`,
+			expectedStatus: http.StatusCreated,
+			expectedEvents: []string{"link.created", "link.deleted"},
+		},
+		{
+			name:           "explicit secret",
+			body:           `{"url":"https://crm.example.com/hook","events":["link.created"],"secret":"abc"}`,
+			expectedStatus: http.StatusCreated,
+			secret:         "abc",
+			expectedEvents: []string{"link.created"},
+		},
+		{
+			name:           "empty events",
+			body:           `{"url":"https://crm.example.com/hook","events":[]}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "missing events",
+			body:           `{"url":"https://crm.example.com/hook"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown event",
+			body:           `{"url":"https://crm.example.com/hook","events":["link.exploded"]}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid url",
+			body:           `{"url":"not a url","events":["link.created"]}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "private address",
+			body:           `{"url":"http://10.0.0.5/hook","events":["link.created"]}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "cloud metadata",
+			body:           `{"url":"http://169.254.169.254/latest/meta-data/","events":["link.created"]}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "localhost",
+			body:           `{"url":"http://localhost:8080/hook","events":["link.created"]}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			st := new(MockWebhookStorage)
+			st.On("CreateWebhook", mock.Anything).Return(int64(1), nil).Maybe()
+
+			handler := webhooks_handler.NewCreateHandler(logger, webhook.NewSubscriptions(st, false))
+
+			req := httptest.NewRequest(http.MethodPost, "/webhooks", bytes.NewBufferString(tc.body))
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			require.Equal(t, tc.expectedStatus, rec.Code)
+			if tc.expectedStatus != http.StatusCreated {
+				st.AssertNotCalled(t, "CreateWebhook", mock.Anything)
+				return
+			}
+
+			var resp webhooks_handler.Response
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			require.NotNil(t, resp.Webhook)
+			assert.True(t, resp.Webhook.Active)
+			assert.Equal(t, tc.expectedEvents, resp.Webhook.Events)
+			if tc.secret != "" {
+				assert.Equal(t, tc.secret, resp.Secret)
+			} else {
+				assert.Len(t, resp.Secret, 64)
+			}
+		})
+	}
+}
+
+func TestGetHandlerHidesSecret(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+
+	st := new(MockWebhookStorage)
+	st.On("GetWebhook", int64(7)).Return(storage.Webhook{ID: 7, URL: "https://crm.example.com", Secret: "hidden", Active: true}, nil)
+	st.On("GetWebhook", int64(8)).Return(storage.Webhook{}, storage.ErrWebhookNotFound)
+
+	r := chi.NewRouter()
+	r.Get("/webhooks/{id}", webhooks_handler.NewGetHandler(logger, webhook.NewSubscriptions(st, false)))
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/webhooks/7", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "hidden")
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/webhooks/8", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/webhooks/abc", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestRetryHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+
+	st := new(MockWebhookStorage)
+	st.On("RetryDelivery", int64(3)).Return(nil)
+	st.On("RetryDelivery", int64(4)).Return(storage.ErrDeliveryNotFound)
+
+	r := chi.NewRouter()
+	r.Post("/webhooks/dead-letters/{id}/retry", webhooks_handler.NewRetryHandler(logger, webhook.NewSubscriptions(st, false)))
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/webhooks/dead-letters/3/retry", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/webhooks/dead-letters/4/retry", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"detail":"delivery not found"`)
+}
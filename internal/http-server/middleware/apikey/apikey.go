@@ -0,0 +1,70 @@
+package middleware_apikey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/RozmiDan/url_shortener/internal/http-server/apierr"
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/go-chi/chi/middleware"
+)
+
+const HeaderKey = "X-API-Key"
+
+type Storage interface {
+	FindAPIKey(ctx context.Context, hash string) (storage.APIKey, error)
+}
+
+// Required возвращает middleware, которое пропускает только запросы с
+// действующим ключом в X-API-Key или Authorization: Bearer. Ключи
+// выпускает и отзывает команда keys.
+func Required(logger *slog.Logger, st Storage) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		log := logger.With(slog.String("component", "middleware/apikey"))
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			opLogger := log.With(slog.String("request_id", middleware.GetReqID(r.Context())))
+
+			key := requestKey(r)
+			if key == "" {
+				unauthorized(w, r, "API key is required")
+				return
+			}
+
+			apiKey, err := st.FindAPIKey(r.Context(), storage.HashAPIKey(key))
+			if err != nil {
+				if errors.Is(err, storage.ErrAPIKeyNotFound) {
+					opLogger.Debug("unknown or revoked api key")
+					unauthorized(w, r, "API key is invalid or revoked")
+					return
+				}
+				apierr.Render(w, r, opLogger, fmt.Errorf("find api key: %w", err))
+				return
+			}
+
+			opLogger.Debug("api key accepted", slog.Int64("api_key_id", apiKey.ID))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func requestKey(r *http.Request) string {
+	if key := r.Header.Get(HeaderKey); key != "" {
+		return key
+	}
+
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request, detail string) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	apierr.Write(w, r, apierr.New(http.StatusUnauthorized, apierr.CodeUnauthorized, detail))
+}
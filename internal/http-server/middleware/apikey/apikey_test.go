@@ -0,0 +1,55 @@
+package middleware_apikey_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	middleware_apikey "github.com/RozmiDan/url_shortener/internal/http-server/middleware/apikey"
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/RozmiDan/url_shortener/internal/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequired(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	st := memory.New()
+
+	key, apiKey, err := storage.NewAPIKey("crm")
+	require.NoError(t, err)
+	apiKey, err = st.CreateAPIKey(ctx, apiKey)
+	require.NoError(t, err)
+
+	handler := middleware_apikey.Required(logger, st)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	call := func(header, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/url", nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := call("", "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, "Bearer", rec.Header().Get("WWW-Authenticate"))
+	assert.Contains(t, rec.Body.String(), `"code":"unauthorized"`)
+
+	assert.Equal(t, http.StatusNoContent, call(middleware_apikey.HeaderKey, key).Code)
+	assert.Equal(t, http.StatusNoContent, call("Authorization", "Bearer "+key).Code)
+	assert.Equal(t, http.StatusUnauthorized, call(middleware_apikey.HeaderKey, key+"x").Code)
+	assert.Equal(t, http.StatusUnauthorized, call("Authorization", "Basic "+key).Code)
+
+	_, err = st.RevokeAPIKey(ctx, apiKey.ID)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, call(middleware_apikey.HeaderKey, key).Code)
+}
@@ -0,0 +1,150 @@
+package middleware_consistency
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RozmiDan/url_shortener/internal/storage"
+)
+
+// Метка последнего изменения возвращается клиенту в заголовке и cookie
+// и приходит обратно с его следующими запросами.
+const (
+	HeaderLastWrite = "X-Last-Write"
+	cookieLastWrite = "url_shortener_lw"
+)
+
+// ReadYourWrites возвращает middleware, которое после успешного изменения
+// данных в течение window направляет чтения того же клиента на основную
+// базу, чтобы он не получил устаревший ответ с реплики.
+//
+// Время изменения хранится не на сервере, а у клиента: ответ на изменение
+// содержит метку, подписанную secret, в заголовке X-Last-Write и в cookie.
+// Клиент возвращает её в cookie или заголовке, и её может проверить любой
+// экземпляр сервиса с тем же secret. Пустой secret заменяется случайным,
+// и метку принимает только выдавший её экземпляр. window <= 0 отключает
+// middleware.
+func ReadYourWrites(window time.Duration, secret []byte) func(next http.Handler) http.Handler {
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		_, _ = rand.Read(secret)
+	}
+	marks := &writeMarks{secret: secret, window: window, now: time.Now}
+
+	return func(next http.Handler) http.Handler {
+		if window <= 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if marks.recent(r) {
+				r = r.WithContext(storage.WithPrimary(r.Context()))
+			}
+
+			if !isWrite(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			mw := &markWriter{ResponseWriter: w, window: window, mark: marks.issue}
+			next.ServeHTTP(mw, r)
+			if !mw.wroteHeader {
+				mw.WriteHeader(http.StatusOK)
+			}
+		})
+	}
+}
+
+// writeMarks выдаёт и проверяет метки вида "<unix ms>.<hmac>".
+type writeMarks struct {
+	secret []byte
+	window time.Duration
+	now    func() time.Time
+}
+
+func (m *writeMarks) issue() string {
+	at := strconv.FormatInt(m.now().UnixMilli(), 10)
+	return at + "." + m.sign(at)
+}
+
+func (m *writeMarks) sign(at string) string {
+	mac := hmac.New(sha256.New, m.secret)
+	mac.Write([]byte(at))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// recent сообщает, пришла ли с запросом действительная метка изменения
+// не старше window.
+func (m *writeMarks) recent(r *http.Request) bool {
+	mark := r.Header.Get(HeaderLastWrite)
+	if mark == "" {
+		c, err := r.Cookie(cookieLastWrite)
+		if err != nil {
+			return false
+		}
+		mark = c.Value
+	}
+
+	at, sig, ok := strings.Cut(mark, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(m.sign(at))) {
+		return false
+	}
+
+	ms, err := strconv.ParseInt(at, 10, 64)
+	if err != nil {
+		return false
+	}
+	age := m.now().Sub(time.UnixMilli(ms))
+	// Отрицательный возраст - расхождение часов экземпляров.
+	return age < m.window && age > -m.window
+}
+
+func isWrite(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
+}
+
+// markWriter добавляет к успешному ответу на изменение метку времени
+// изменения. Её нужно поставить до заголовков ответа, поэтому решение
+// принимается по коду ответа в WriteHeader.
+type markWriter struct {
+	http.ResponseWriter
+	window      time.Duration
+	mark        func() string
+	wroteHeader bool
+}
+
+func (mw *markWriter) WriteHeader(code int) {
+	if !mw.wroteHeader {
+		mw.wroteHeader = true
+		if code < http.StatusBadRequest {
+			mark := mw.mark()
+			mw.Header().Set(HeaderLastWrite, mark)
+			http.SetCookie(mw, &http.Cookie{
+				Name:     cookieLastWrite,
+				Value:    mark,
+				Path:     "/",
+				MaxAge:   int(mw.window.Seconds()) + 1,
+				HttpOnly: true,
+				SameSite: http.SameSiteLaxMode,
+			})
+		}
+	}
+	mw.ResponseWriter.WriteHeader(code)
+}
+
+func (mw *markWriter) Write(b []byte) (int, error) {
+	if !mw.wroteHeader {
+		mw.WriteHeader(http.StatusOK)
+	}
+	return mw.ResponseWriter.Write(b)
+}
@@ -0,0 +1,97 @@
+package middleware_consistency_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	middleware_consistency "github.com/RozmiDan/url_shortener/internal/http-server/middleware/consistency"
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadYourWrites(t *testing.T) {
+	var primary bool
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		primary = storage.UsePrimary(r.Context())
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	})
+
+	secret := []byte("shared")
+	window := 50 * time.Millisecond
+
+	// Два экземпляра сервиса с общим ключом.
+	first := middleware_consistency.ReadYourWrites(window, secret)(next)
+	second := middleware_consistency.ReadYourWrites(window, secret)(next)
+	other := middleware_consistency.ReadYourWrites(window, []byte("other"))(next)
+
+	do := func(h http.Handler, method, path string, prepare func(*http.Request)) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if prepare != nil {
+			prepare(req)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	do(first, http.MethodGet, "/url", nil)
+	assert.False(t, primary)
+
+	// Неудачное изменение не переключает клиента на основную базу.
+	rec := do(first, http.MethodPost, "/fail", nil)
+	assert.Empty(t, rec.Header().Get(middleware_consistency.HeaderLastWrite))
+	assert.Empty(t, rec.Result().Cookies())
+
+	rec = do(first, http.MethodPost, "/url", nil)
+	mark := rec.Header().Get(middleware_consistency.HeaderLastWrite)
+	require.NotEmpty(t, mark)
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+
+	withHeader := func(req *http.Request) { req.Header.Set(middleware_consistency.HeaderLastWrite, mark) }
+	withCookie := func(req *http.Request) { req.AddCookie(cookies[0]) }
+
+	do(second, http.MethodGet, "/url", withHeader)
+	assert.True(t, primary)
+	do(second, http.MethodGet, "/url", withCookie)
+	assert.True(t, primary)
+
+	// Метку с чужим ключом или изменённую не принимают.
+	do(other, http.MethodGet, "/url", withHeader)
+	assert.False(t, primary)
+	do(second, http.MethodGet, "/url", func(req *http.Request) {
+		req.Header.Set(middleware_consistency.HeaderLastWrite, "9999999999999"+mark[len("9999999999999"):])
+	})
+	assert.False(t, primary)
+
+	// Адрес клиента больше ни на что не влияет.
+	do(second, http.MethodGet, "/url", func(req *http.Request) {
+		req.Header.Set("X-Forwarded-For", "192.0.2.1")
+	})
+	assert.False(t, primary)
+
+	time.Sleep(60 * time.Millisecond)
+	do(second, http.MethodGet, "/url", withHeader)
+	assert.False(t, primary)
+}
+
+func TestReadYourWritesDisabled(t *testing.T) {
+	var primary bool
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		primary = storage.UsePrimary(r.Context())
+	})
+
+	h := middleware_consistency.ReadYourWrites(0, nil)(next)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/url", nil))
+	assert.Empty(t, rec.Header().Get(middleware_consistency.HeaderLastWrite))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/url", nil))
+	assert.False(t, primary)
+}
@@ -0,0 +1,192 @@
+package middleware_idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/RozmiDan/url_shortener/internal/http-server/apierr"
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/go-chi/chi/middleware"
+)
+
+const (
+	HeaderKey      = "Idempotency-Key"
+	HeaderReplayed = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+	maxBodySize  = 1 << 20
+)
+
+type Storage interface {
+	ReserveIdempotencyKey(ctx context.Context, key, requestHash string, ttl, lockTimeout time.Duration) (storage.IdempotencyRecord, bool, error)
+	CompleteIdempotencyKey(ctx context.Context, rec storage.IdempotencyRecord) error
+	ReleaseIdempotencyKey(ctx context.Context, key, requestHash string) error
+	PurgeIdempotencyKeys(ctx context.Context) (int64, error)
+}
+
+type Options struct {
+	// TTL - сколько хранится ответ на запрос с ключом.
+	TTL time.Duration
+	// LockTimeout - через сколько ключ, занятый незавершённым запросом,
+	// можно занять снова (например, если экземпляр сервиса упал).
+	LockTimeout time.Duration
+}
+
+// New возвращает middleware, которое выполняет запрос с заголовком
+// Idempotency-Key не больше одного раза: повтор с тем же ключом и телом
+// получает сохранённый ответ, с другим телом - 422, а повтор, пришедший
+// пока первый запрос ещё выполняется, - 409. Запросы без ключа проходят
+// как обычно.
+func New(logger *slog.Logger, st Storage, opts Options) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		log := logger.With(slog.String("component", "middleware/idempotency"))
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(HeaderKey)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			opLogger := log.With(
+				slog.String("request_id", middleware.GetReqID(r.Context())),
+				slog.String("idempotency_key", key),
+			)
+
+			if len(key) > maxKeyLength {
+				apierr.Write(w, r, apierr.BadRequest("Idempotency-Key is too long"))
+				return
+			}
+
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+			if err != nil {
+				opLogger.Debug("failed to read request body", slog.Any("err", err))
+				apierr.Write(w, r, apierr.BadRequest("failed to read request"))
+				return
+			}
+			if len(body) > maxBodySize {
+				apierr.Write(w, r, apierr.New(http.StatusRequestEntityTooLarge, apierr.CodeRequestTooLarge, "request body is too large"))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			hash := requestHash(r, body)
+
+			rec, reserved, err := st.ReserveIdempotencyKey(r.Context(), key, hash, opts.TTL, opts.LockTimeout)
+			if err != nil {
+				apierr.Render(w, r, opLogger, fmt.Errorf("reserve idempotency key: %w", err))
+				return
+			}
+
+			if !reserved {
+				switch {
+				case rec.RequestHash != hash:
+					opLogger.Debug("idempotency key reused with another request")
+					apierr.Write(w, r, apierr.New(http.StatusUnprocessableEntity, apierr.CodeIdempotencyKeyReused,
+						"Idempotency-Key was already used with a different request"))
+				case rec.InFlight():
+					opLogger.Debug("request with idempotency key is in progress")
+					w.Header().Set("Retry-After", "1")
+					apierr.Write(w, r, apierr.New(http.StatusConflict, apierr.CodeRequestInProgress,
+						"request with this Idempotency-Key is still in progress"))
+				default:
+					opLogger.Debug("replaying stored response", slog.Int("status", rec.Status))
+					if rec.ContentType != "" {
+						w.Header().Set("Content-Type", rec.ContentType)
+					}
+					w.Header().Set(HeaderReplayed, "true")
+					w.WriteHeader(rec.Status)
+					_, _ = w.Write(rec.Body)
+				}
+				return
+			}
+
+			rw := &recorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rw, r)
+
+			// Ответ сохраняется, даже если клиент уже отключился: именно
+			// в этом случае он и придёт с повтором.
+			ctx := context.WithoutCancel(r.Context())
+
+			// Ошибку сервера не запоминаем, чтобы повтор выполнил запрос заново.
+			if rw.status >= http.StatusInternalServerError {
+				if err := st.ReleaseIdempotencyKey(ctx, key, hash); err != nil {
+					opLogger.Error("failed to release idempotency key", slog.Any("err", err))
+				}
+				return
+			}
+
+			rec.Status = rw.status
+			rec.ContentType = rw.Header().Get("Content-Type")
+			rec.Body = rw.body.Bytes()
+
+			if err := st.CompleteIdempotencyKey(ctx, rec); err != nil {
+				opLogger.Error("failed to save idempotent response", slog.Any("err", err))
+			}
+		})
+	}
+}
+
+// RunPurge каждые interval удаляет истёкшие ключи, пока не отменён ctx.
+func RunPurge(ctx context.Context, logger *slog.Logger, st Storage, interval time.Duration) {
+	log := logger.With(slog.String("component", "middleware/idempotency"))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		purged, err := st.PurgeIdempotencyKeys(ctx)
+		if err != nil {
+			log.Error("failed to purge idempotency keys", slog.Any("err", err))
+			continue
+		}
+		if purged > 0 {
+			log.Debug("idempotency keys purged", slog.Int64("count", purged))
+		}
+	}
+}
+
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method))
+	h.Write([]byte(" "))
+	h.Write([]byte(r.URL.Path))
+	h.Write([]byte("\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recorder пропускает ответ клиенту и одновременно запоминает его.
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rw *recorder) WriteHeader(code int) {
+	if !rw.wroteHeader {
+		rw.status = code
+		rw.wroteHeader = true
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recorder) Write(b []byte) (int, error) {
+	rw.wroteHeader = true
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
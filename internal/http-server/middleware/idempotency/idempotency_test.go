@@ -0,0 +1,136 @@
+package middleware_idempotency_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	middleware_idempotency "github.com/RozmiDan/url_shortener/internal/http-server/middleware/idempotency"
+	"github.com/RozmiDan/url_shortener/internal/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var opts = middleware_idempotency.Options{TTL: time.Hour, LockTimeout: time.Minute}
+
+func newLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+}
+
+func post(h http.Handler, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/url", bytes.NewBufferString(body))
+	if key != "" {
+		req.Header.Set(middleware_idempotency.HeaderKey, key)
+	}
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	var calls atomic.Int64
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, `{"alias":"random%d"}`, n)
+	})
+
+	h := middleware_idempotency.New(newLogger(), memory.New(), opts)(next)
+
+	first := post(h, "key-1", `{"url":"https://example.com"}`)
+	require.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, `{"alias":"random1"}`, first.Body.String())
+	assert.Empty(t, first.Header().Get(middleware_idempotency.HeaderReplayed))
+
+	retry := post(h, "key-1", `{"url":"https://example.com"}`)
+	assert.Equal(t, http.StatusOK, retry.Code)
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, "application/json", retry.Header().Get("Content-Type"))
+	assert.Equal(t, "true", retry.Header().Get(middleware_idempotency.HeaderReplayed))
+
+	other := post(h, "key-1", `{"url":"https://example.org"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, other.Code)
+
+	// Без ключа запрос выполняется каждый раз.
+	post(h, "", `{"url":"https://example.com"}`)
+	post(h, "", `{"url":"https://example.com"}`)
+	assert.EqualValues(t, 3, calls.Load())
+}
+
+func TestIdempotencyInFlight(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	var calls atomic.Int64
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusOK)
+	})
+
+	h := middleware_idempotency.New(newLogger(), memory.New(), opts)(next)
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- post(h, "key-1", `{}`)
+	}()
+
+	<-started
+	dup := post(h, "key-1", `{}`)
+	assert.Equal(t, http.StatusConflict, dup.Code)
+	assert.Equal(t, "1", dup.Header().Get("Retry-After"))
+
+	close(release)
+	assert.Equal(t, http.StatusOK, (<-done).Code)
+
+	assert.Equal(t, http.StatusOK, post(h, "key-1", `{}`).Code)
+	assert.EqualValues(t, 1, calls.Load())
+}
+
+func TestIdempotencyServerErrorNotStored(t *testing.T) {
+	var calls atomic.Int64
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	h := middleware_idempotency.New(newLogger(), memory.New(), opts)(next)
+
+	assert.Equal(t, http.StatusInternalServerError, post(h, "key-1", `{}`).Code)
+	assert.Equal(t, http.StatusCreated, post(h, "key-1", `{}`).Code)
+	assert.Equal(t, http.StatusCreated, post(h, "key-1", `{}`).Code)
+	assert.EqualValues(t, 2, calls.Load())
+}
+
+func TestIdempotencyExpired(t *testing.T) {
+	st := memory.New()
+
+	var calls atomic.Int64
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	})
+
+	short := middleware_idempotency.Options{TTL: time.Millisecond, LockTimeout: time.Minute}
+	h := middleware_idempotency.New(newLogger(), st, short)(next)
+
+	post(h, "key-1", `{}`)
+	time.Sleep(5 * time.Millisecond)
+
+	purged, err := st.PurgeIdempotencyKeys(context.Background())
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, purged)
+
+	post(h, "key-1", `{"other":true}`)
+	assert.EqualValues(t, 2, calls.Load())
+}
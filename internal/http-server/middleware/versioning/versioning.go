@@ -0,0 +1,101 @@
+package middleware_versioning
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RozmiDan/url_shortener/internal/http-server/apierr"
+)
+
+// vendorPrefix + версия + "+json" - тип ответа конкретной версии API,
+// например application/vnd.url-shortener.v1+json.
+const vendorPrefix = "application/vnd.url-shortener."
+
+// MediaType возвращает тип ответа версии API.
+func MediaType(version string) string {
+	return vendorPrefix + version + "+json"
+}
+
+// Accept возвращает middleware, которое отвечает 406, если клиент
+// принимает только другую версию API или не принимает JSON.
+func Accept(version string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept")
+
+			accept := r.Header.Get("Accept")
+			if accept == "" || acceptable(accept, version) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			apierr.Write(w, r, apierr.New(http.StatusNotAcceptable, apierr.CodeNotAcceptable,
+				"this endpoint serves "+MediaType(version)+" and application/json"))
+		})
+	}
+}
+
+func acceptable(accept, version string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		if q, ok := params["q"]; ok {
+			if weight, err := strconv.ParseFloat(q, 64); err == nil && weight == 0 {
+				continue
+			}
+		}
+
+		switch mediaType {
+		case "*/*", "application/*", "application/json", apierr.ContentType, MediaType(version):
+			return true
+		}
+	}
+	return false
+}
+
+// Deprecation - даты, которые старые пути API отдают клиентам.
+type Deprecation struct {
+	// DeprecatedAt - с какого момента путь устарел, нулевое значение -
+	// Deprecation: true.
+	DeprecatedAt time.Time
+	// Sunset - когда путь перестанет работать, нулевое значение - без
+	// заголовка Sunset.
+	Sunset time.Time
+	// Successor - префикс нового пути, например /api/v1.
+	Successor string
+}
+
+// Deprecated возвращает middleware, которое помечает ответ заголовками
+// Deprecation (RFC 9745), Sunset (RFC 8594) и Link на тот же путь
+// под Successor.
+func Deprecated(d Deprecation) func(next http.Handler) http.Handler {
+	deprecation := "true"
+	if !d.DeprecatedAt.IsZero() {
+		deprecation = "@" + strconv.FormatInt(d.DeprecatedAt.Unix(), 10)
+	}
+
+	var sunset string
+	if !d.Sunset.IsZero() {
+		sunset = d.Sunset.UTC().Format(http.TimeFormat)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			h.Set("Deprecation", deprecation)
+			if sunset != "" {
+				h.Set("Sunset", sunset)
+			}
+			if d.Successor != "" {
+				h.Add("Link", `<`+d.Successor+r.URL.EscapedPath()+`>; rel="successor-version"`)
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
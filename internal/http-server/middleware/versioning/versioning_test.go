@@ -0,0 +1,76 @@
+package middleware_versioning_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RozmiDan/url_shortener/internal/http-server/apierr"
+	middleware_versioning "github.com/RozmiDan/url_shortener/internal/http-server/middleware/versioning"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccept(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	h := middleware_versioning.Accept("v1")(next)
+
+	testCases := []struct {
+		accept string
+		status int
+	}{
+		{"", http.StatusOK},
+		{"*/*", http.StatusOK},
+		{"application/json", http.StatusOK},
+		{"application/vnd.url-shortener.v1+json", http.StatusOK},
+		{"text/html, application/json;q=0.5", http.StatusOK},
+		{"application/vnd.url-shortener.v2+json", http.StatusNotAcceptable},
+		{"text/html", http.StatusNotAcceptable},
+		{"application/json;q=0", http.StatusNotAcceptable},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.accept, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/url", nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.status, rec.Code)
+			assert.Equal(t, "Accept", rec.Header().Get("Vary"))
+			if tc.status == http.StatusNotAcceptable {
+				assert.Equal(t, apierr.ContentType, rec.Header().Get("Content-Type"))
+				assert.Contains(t, rec.Body.String(), `"code":"not_acceptable"`)
+			}
+		})
+	}
+}
+
+func TestDeprecated(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	h := middleware_versioning.Deprecated(middleware_versioning.Deprecation{
+		DeprecatedAt: time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+		Sunset:       time.Date(2027, 4, 19, 0, 0, 0, 0, time.UTC),
+		Successor:    "/api/v1",
+	})(next)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/url/docs", nil))
+
+	assert.Equal(t, "@1792368000", rec.Header().Get("Deprecation"))
+	assert.Equal(t, "Mon, 19 Apr 2027 00:00:00 GMT", rec.Header().Get("Sunset"))
+	assert.Equal(t, `</api/v1/url/docs>; rel="successor-version"`, rec.Header().Get("Link"))
+
+	// Без дат - только признак устаревания.
+	h = middleware_versioning.Deprecated(middleware_versioning.Deprecation{})(next)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/url/docs", nil))
+
+	assert.Equal(t, "true", rec.Header().Get("Deprecation"))
+	assert.Empty(t, rec.Header().Get("Sunset"))
+	assert.Empty(t, rec.Header().Get("Link"))
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/RozmiDan/url_shortener/internal/config"
+	"github.com/RozmiDan/url_shortener/internal/health"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/render"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	httpSwagger "github.com/swaggo/http-swagger"
+)
+
+// InitAdminServer создаёт служебный сервер: метрики, проверки состояния,
+// pprof и swagger. Он слушает отдельный адрес, закрытый от интернета.
+func InitAdminServer(cnfg *config.Config, logger *slog.Logger, h *health.Health) *http.Server {
+	router := chi.NewRouter()
+
+	router.Use(middleware.RequestID)
+	router.Use(middleware.Recoverer)
+
+	router.Handle("/metrics", promhttp.Handler())
+	router.Get("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		render.JSON(w, r, health.Report{Status: health.StatusOK})
+	})
+	router.Get("/readyz", readyHandler(logger, h))
+	router.Mount("/debug", middleware.Profiler())
+	router.Get("/swagger/*", httpSwagger.WrapHandler)
+
+	return &http.Server{
+		Addr:         cnfg.Admin.Port,
+		Handler:      router,
+		ReadTimeout:  cnfg.HttpInfo.Timeout,
+		WriteTimeout: cnfg.Admin.Timeout,
+		IdleTimeout:  cnfg.HttpInfo.IdleTimeout,
+	}
+}
+
+// readyHandler отвечает 503 с результатом каждой проверки, пока сервис
+// не готов или останавливается.
+func readyHandler(logger *slog.Logger, h *health.Health) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := h.Ready(r.Context())
+
+		if !report.OK() {
+			if report.Status != health.StatusDraining {
+				logger.Warn("readiness check failed", slog.Any("checks", report.Checks))
+			}
+			render.Status(r, http.StatusServiceUnavailable)
+		}
+
+		render.JSON(w, r, report)
+	}
+}
@@ -0,0 +1,59 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RozmiDan/url_shortener/internal/config"
+	"github.com/RozmiDan/url_shortener/internal/health"
+	"github.com/RozmiDan/url_shortener/internal/http-server/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminServer(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+
+	get := func(h http.Handler, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	var storageErr error
+	readiness := health.New(time.Second)
+	readiness.Register("storage", func(ctx context.Context) error { return storageErr })
+
+	h := server.InitAdminServer(&config.Config{}, logger, readiness).Handler
+
+	for _, path := range []string{"/healthz", "/readyz", "/metrics", "/debug/pprof/"} {
+		assert.Equal(t, http.StatusOK, get(h, path).Code, path)
+	}
+
+	storageErr = errors.New("connection refused")
+
+	assert.Equal(t, http.StatusOK, get(h, "/healthz").Code)
+
+	rec := get(h, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	var report health.Report
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	assert.Equal(t, health.StatusUnavailable, report.Status)
+	assert.Equal(t, health.StatusFailed, report.Checks["storage"].Status)
+	assert.Equal(t, "connection refused", report.Checks["storage"].Error)
+
+	readiness.Drain()
+
+	rec = get(h, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.JSONEq(t, `{"status":"draining"}`, rec.Body.String())
+	assert.Equal(t, http.StatusOK, get(h, "/healthz").Code)
+}
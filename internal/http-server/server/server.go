@@ -4,27 +4,43 @@ import (
 	"context"
 	"log/slog"
 	"net/http"
+	"time"
 
 	_ "github.com/RozmiDan/url_shortener/docs"
 	"github.com/RozmiDan/url_shortener/internal/config"
+	"github.com/RozmiDan/url_shortener/internal/http-server/apierr"
+	broken_handler "github.com/RozmiDan/url_shortener/internal/http-server/handlers/broken"
 	delete_handler "github.com/RozmiDan/url_shortener/internal/http-server/handlers/delete"
+	list_handler "github.com/RozmiDan/url_shortener/internal/http-server/handlers/list"
 	redirect_handler "github.com/RozmiDan/url_shortener/internal/http-server/handlers/redirect"
+	rules_handler "github.com/RozmiDan/url_shortener/internal/http-server/handlers/rules"
 	save_handler "github.com/RozmiDan/url_shortener/internal/http-server/handlers/save"
+	tags_handler "github.com/RozmiDan/url_shortener/internal/http-server/handlers/tags"
 	update_handler "github.com/RozmiDan/url_shortener/internal/http-server/handlers/update"
+	variants_handler "github.com/RozmiDan/url_shortener/internal/http-server/handlers/variants"
+	webhooks_handler "github.com/RozmiDan/url_shortener/internal/http-server/handlers/webhooks"
+	middleware_apikey "github.com/RozmiDan/url_shortener/internal/http-server/middleware/apikey"
+	middleware_consistency "github.com/RozmiDan/url_shortener/internal/http-server/middleware/consistency"
+	middleware_idempotency "github.com/RozmiDan/url_shortener/internal/http-server/middleware/idempotency"
 	middleware_logger "github.com/RozmiDan/url_shortener/internal/http-server/middleware/logger"
 	middleware_metrics "github.com/RozmiDan/url_shortener/internal/http-server/middleware/metrics"
+	middleware_versioning "github.com/RozmiDan/url_shortener/internal/http-server/middleware/versioning"
+	"github.com/RozmiDan/url_shortener/internal/usecase/links"
+	"github.com/RozmiDan/url_shortener/internal/usecase/rules"
+	"github.com/RozmiDan/url_shortener/internal/usecase/tags"
+	"github.com/RozmiDan/url_shortener/internal/usecase/webhook"
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/cors"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
-	httpSwagger "github.com/swaggo/http-swagger"
 )
 
 type DataBase interface {
-	SaveURL(ctx context.Context, urlToSave string, alias string) (int64, error)
-	GetURL(alias string) (string, error)
-	DeleteURL(alias string) error
-	UpdateURL(currAlias string, newAlias string) error
+	links.Repository
+	rules.Repository
+	tags.Repository
+	webhook.Repository
+	middleware_idempotency.Storage
+	middleware_apikey.Storage
 }
 
 func InitServer(cnfg *config.Config, logger *slog.Logger, db DataBase) *http.Server {
@@ -35,20 +51,78 @@ func InitServer(cnfg *config.Config, logger *slog.Logger, db DataBase) *http.Ser
 	router.Use(middleware_metrics.MetricsMiddleware)
 	router.Use(middleware.Recoverer)
 	router.Use(middleware.URLFormat)
+	router.Use(requestDeadline(cnfg.HttpInfo.Timeout))
+
+	// Без реплик все чтения и так идут на основную базу.
+	var readYourWrites time.Duration
+	if len(cnfg.PostgreURL.Replicas) > 0 {
+		readYourWrites = cnfg.PostgreURL.ReadYourWritesWindow
+	}
+	router.Use(middleware_consistency.ReadYourWrites(readYourWrites, []byte(cnfg.PostgreURL.ReadYourWritesSecret)))
+
 	router.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"*"},
+		ExposedHeaders:   []string{"ETag", middleware_idempotency.HeaderReplayed, "Deprecation", "Sunset", "Link", middleware_consistency.HeaderLastWrite},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
 
-	router.Post("/url", save_handler.NewSaveHandler(logger, db))
-	router.Get("/{alias}", redirect_handler.NewRedirectHandler(logger, db))
-	router.Get("/swagger/*", httpSwagger.WrapHandler)
-	router.Put("/url/{alias}", update_handler.NewUpdateHandler(logger, db))
-	router.Delete("/url/{alias}", delete_handler.NewDeleteHandler(logger, db))
-	router.Handle("/metrics", promhttp.Handler())
+	redirectOpts := redirect_handler.Options{
+		NotActive: redirect_handler.NotActiveResponse{
+			Status:      cnfg.Redirect.NotActiveStatus,
+			Message:     cnfg.Redirect.NotActiveMessage,
+			FallbackURL: cnfg.Redirect.NotActiveFallbackURL,
+		},
+		CountryHeader: cnfg.Redirect.CountryHeader,
+	}
+
+	router.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		apierr.Write(w, r, apierr.New(http.StatusNotFound, apierr.CodeNotFound, "no such endpoint"))
+	})
+	router.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		apierr.Write(w, r, apierr.New(http.StatusMethodNotAllowed, apierr.CodeMethodNotAllowed, r.Method+" is not allowed here"))
+	})
+
+	svc := services{
+		links:    links.New(db, links.Config{ReservedAliases: ReservedAliases}),
+		rules:    rules.New(db),
+		tags:     tags.New(db),
+		webhooks: webhook.NewSubscriptions(db, cnfg.Webhooks.AllowPrivate),
+	}
+
+	idempotent := middleware_idempotency.New(logger, db, middleware_idempotency.Options{
+		TTL:         cnfg.Idempotency.TTL,
+		LockTimeout: cnfg.Idempotency.LockTimeout,
+	})
+
+	v1 := routesV1(logger, svc, idempotent)
+	if cnfg.API.RequireKey {
+		v1 = withMiddleware(v1, middleware_apikey.Required(logger, db))
+	}
+
+	router.Route("/api/v1", func(r chi.Router) {
+		r.Use(middleware_versioning.Accept("v1"))
+		v1(r)
+	})
+
+	// Старые пути без префикса работают до даты Sunset и отдают клиентам
+	// заголовки Deprecation и Link на замену под /api/v1.
+	if cnfg.API.Legacy {
+		router.Group(func(r chi.Router) {
+			r.Use(middleware_versioning.Deprecated(middleware_versioning.Deprecation{
+				DeprecatedAt: cnfg.API.LegacyDeprecatedAt,
+				Sunset:       cnfg.API.LegacySunset,
+				Successor:    "/api/v1",
+			}))
+			v1(r)
+		})
+	}
+
+	redirectHandler := redirect_handler.NewRedirectHandler(logger, svc.links, redirectOpts)
+	router.Get("/{alias}", redirectHandler)
+	router.Get("/{alias}/*", redirectHandler)
 
 	server := &http.Server{
 		Addr:         cnfg.HttpInfo.Port,
@@ -60,3 +134,78 @@ func InitServer(cnfg *config.Config, logger *slog.Logger, db DataBase) *http.Ser
 
 	return server
 }
+
+// ReservedAliases - первые сегменты путей сервиса. Ссылка с таким alias
+// была бы недоступна: её перекрыл бы маршрут API.
+var ReservedAliases = []string{"api", "url", "tags", "webhooks"}
+
+// services - сценарии, через которые обработчики работают с хранилищем.
+type services struct {
+	links    *links.Service
+	rules    *rules.Service
+	tags     *tags.Service
+	webhooks *webhook.Subscriptions
+}
+
+// routesV1 возвращает функцию, регистрирующую API управления ссылками
+// версии 1. Её монтируют под /api/v1 и, для совместимости, в корень.
+func routesV1(
+	logger *slog.Logger,
+	svc services,
+	idempotent func(http.Handler) http.Handler,
+) func(r chi.Router) {
+	return func(r chi.Router) {
+		r.With(idempotent).Post("/url", save_handler.NewSaveHandler(logger, svc.links))
+		r.Get("/url", list_handler.NewListHandler(logger, svc.links))
+		r.Get("/url/broken", broken_handler.NewBrokenHandler(logger, svc.links))
+		r.Get("/url/{alias}", update_handler.NewGetHandler(logger, svc.links))
+		r.Patch("/url/{alias}", update_handler.NewPatchHandler(logger, svc.links))
+		r.Put("/url/{alias}", update_handler.NewUpdateHandler(logger, svc.links))
+		r.Delete("/url/{alias}", delete_handler.NewDeleteHandler(logger, svc.links))
+		r.Get("/url/{alias}/rules", rules_handler.NewListHandler(logger, svc.rules))
+		r.Post("/url/{alias}/rules", rules_handler.NewAddHandler(logger, svc.rules))
+		r.Put("/url/{alias}/rules", rules_handler.NewReplaceHandler(logger, svc.rules))
+		r.Put("/url/{alias}/rules/{id}", rules_handler.NewUpdateHandler(logger, svc.rules))
+		r.Delete("/url/{alias}/rules/{id}", rules_handler.NewDeleteHandler(logger, svc.rules))
+		r.Get("/url/{alias}/variants", variants_handler.NewListHandler(logger, svc.links))
+		r.Get("/tags", tags_handler.NewListHandler(logger, svc.tags))
+		r.Post("/tags/merge", tags_handler.NewMergeHandler(logger, svc.tags))
+		r.Post("/tags/{tag}/rename", tags_handler.NewRenameHandler(logger, svc.tags))
+		r.Post("/webhooks", webhooks_handler.NewCreateHandler(logger, svc.webhooks))
+		r.Get("/webhooks", webhooks_handler.NewListHandler(logger, svc.webhooks))
+		r.Get("/webhooks/dead-letters", webhooks_handler.NewDeadLetterHandler(logger, svc.webhooks))
+		r.Post("/webhooks/dead-letters/{id}/retry", webhooks_handler.NewRetryHandler(logger, svc.webhooks))
+		r.Get("/webhooks/{id}", webhooks_handler.NewGetHandler(logger, svc.webhooks))
+		r.Put("/webhooks/{id}", webhooks_handler.NewUpdateHandler(logger, svc.webhooks))
+		r.Delete("/webhooks/{id}", webhooks_handler.NewDeleteHandler(logger, svc.webhooks))
+	}
+}
+
+// withMiddleware добавляет middleware ко всем маршрутам routes.
+func withMiddleware(routes func(r chi.Router), mw func(http.Handler) http.Handler) func(r chi.Router) {
+	return func(r chi.Router) {
+		r.Group(func(r chi.Router) {
+			r.Use(mw)
+			routes(r)
+		})
+	}
+}
+
+// requestDeadline ограничивает контекст запроса таймаутом сервера: по
+// истечении WriteTimeout ответ клиенту уже не дойдёт, и работа с базой
+// для него должна прекратиться.
+func requestDeadline(timeout time.Duration) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if timeout <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
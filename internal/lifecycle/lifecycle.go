@@ -0,0 +1,251 @@
+// Package lifecycle запускает компоненты сервиса в порядке зависимостей и
+// останавливает их в обратном порядке с общим дедлайном.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Component - часть сервиса со своим запуском и остановкой. Start не
+// должен блокироваться: долгую работу он запускает в горутине.
+type Component struct {
+	Name string
+	// DependsOn - компоненты, которые запускаются раньше и
+	// останавливаются позже этого.
+	DependsOn []string
+	// Start и Stop можно не задавать.
+	Start func(ctx context.Context) error
+	Stop  func(ctx context.Context) error
+}
+
+// StopError - компонент не остановился или не успел остановиться до
+// дедлайна.
+type StopError struct {
+	Component string
+	Err       error
+}
+
+func (e *StopError) Error() string {
+	if errors.Is(e.Err, context.DeadlineExceeded) {
+		return fmt.Sprintf("%s did not stop in time", e.Component)
+	}
+	return fmt.Sprintf("%s: %v", e.Component, e.Err)
+}
+
+func (e *StopError) Unwrap() error {
+	return e.Err
+}
+
+type Manager struct {
+	logger *slog.Logger
+
+	components []Component
+	started    []Component
+
+	failed     chan error
+	reportOnce sync.Once
+}
+
+func New(logger *slog.Logger) *Manager {
+	return &Manager{
+		logger: logger.With(slog.String("component", "lifecycle")),
+		failed: make(chan error, 1),
+	}
+}
+
+func (m *Manager) Add(c Component) {
+	m.components = append(m.components, c)
+}
+
+// Failed возвращает первую ошибку, с которой компонент завершился уже
+// после запуска, например сервер не смог принимать соединения.
+func (m *Manager) Failed() <-chan error {
+	return m.failed
+}
+
+func (m *Manager) fail(name string, err error) {
+	m.reportOnce.Do(func() {
+		m.failed <- fmt.Errorf("%s: %w", name, err)
+	})
+}
+
+// Start запускает компоненты: зависимости раньше зависящих от них, в
+// остальном - в порядке добавления. При ошибке уже запущенные
+// компоненты остаются запущенными, их останавливает Stop.
+func (m *Manager) Start(ctx context.Context) error {
+	const op = "lifecycle.Start"
+
+	ordered, err := order(m.components)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, c := range ordered {
+		if c.Start != nil {
+			if err := c.Start(ctx); err != nil {
+				return fmt.Errorf("%s: %s: %w", op, c.Name, err)
+			}
+		}
+		m.started = append(m.started, c)
+		m.logger.Debug("component started", slog.String("name", c.Name))
+	}
+
+	return nil
+}
+
+// Stop останавливает запущенные компоненты в обратном порядке. Дедлайн
+// ctx общий для всех: компонент, не успевший до него, попадает в ошибку,
+// но остальные всё равно останавливаются.
+func (m *Manager) Stop(ctx context.Context) error {
+	var errs []error
+
+	for i := len(m.started) - 1; i >= 0; i-- {
+		c := m.started[i]
+		if c.Stop == nil {
+			continue
+		}
+
+		start := time.Now()
+		if err := c.Stop(ctx); err != nil {
+			stopErr := &StopError{Component: c.Name, Err: err}
+			m.logger.Error("component stop failed", slog.String("name", c.Name), slog.Any("err", stopErr))
+			errs = append(errs, stopErr)
+			continue
+		}
+		m.logger.Info("component stopped", slog.String("name", c.Name), slog.Duration("took", time.Since(start)))
+	}
+	m.started = nil
+
+	return errors.Join(errs...)
+}
+
+// Server - компонент HTTP-сервера на ln, nil - на srv.Addr. Start
+// занимает адрес, поэтому ошибка вроде занятого порта возвращается сразу.
+// Stop ждёт завершения запросов до дедлайна, а оставшиеся соединения
+// закрывает принудительно.
+func (m *Manager) Server(name string, srv *http.Server, ln net.Listener, dependsOn ...string) Component {
+	return Component{
+		Name:      name,
+		DependsOn: dependsOn,
+		Start: func(ctx context.Context) error {
+			if ln == nil {
+				var err error
+				if ln, err = net.Listen("tcp", srv.Addr); err != nil {
+					return err
+				}
+			}
+
+			m.logger.Info("starting server", slog.String("name", name), slog.String("addr", ln.Addr().String()))
+			go func() {
+				if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					m.fail(name, err)
+				}
+			}()
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			err := srv.Shutdown(ctx)
+			if err == nil {
+				return nil
+			}
+			if closeErr := srv.Close(); closeErr != nil {
+				err = errors.Join(err, closeErr)
+			}
+			return err
+		},
+	}
+}
+
+// Worker - компонент фонового процесса run. Stop отменяет его контекст и
+// ждёт, пока run вернётся, - так процесс успевает записать результат
+// текущего прохода до закрытия базы.
+func (m *Manager) Worker(name string, run func(ctx context.Context), dependsOn ...string) Component {
+	var (
+		cancel context.CancelFunc
+		done   chan struct{}
+	)
+
+	return Component{
+		Name:      name,
+		DependsOn: dependsOn,
+		Start: func(context.Context) error {
+			var ctx context.Context
+			ctx, cancel = context.WithCancel(context.Background())
+			done = make(chan struct{})
+
+			go func() {
+				defer close(done)
+				run(ctx)
+			}()
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			cancel()
+
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	}
+}
+
+// order сортирует компоненты так, чтобы зависимости шли раньше, сохраняя
+// порядок добавления там, где он не важен.
+func order(components []Component) ([]Component, error) {
+	known := make(map[string]bool, len(components))
+	for _, c := range components {
+		if known[c.Name] {
+			return nil, fmt.Errorf("component %q added twice", c.Name)
+		}
+		known[c.Name] = true
+	}
+	for _, c := range components {
+		for _, dep := range c.DependsOn {
+			if !known[dep] {
+				return nil, fmt.Errorf("component %q depends on unknown %q", c.Name, dep)
+			}
+		}
+	}
+
+	placed := make(map[string]bool, len(components))
+	ordered := make([]Component, 0, len(components))
+
+	for len(ordered) < len(components) {
+		progress := false
+
+		for _, c := range components {
+			if placed[c.Name] || !ready(c, placed) {
+				continue
+			}
+			placed[c.Name] = true
+			ordered = append(ordered, c)
+			progress = true
+			break
+		}
+
+		if !progress {
+			return nil, errors.New("components have a dependency cycle")
+		}
+	}
+
+	return ordered, nil
+}
+
+func ready(c Component, placed map[string]bool) bool {
+	for _, dep := range c.DependsOn {
+		if !placed[dep] {
+			return false
+		}
+	}
+	return true
+}
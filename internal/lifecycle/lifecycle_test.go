@@ -0,0 +1,197 @@
+package lifecycle_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/RozmiDan/url_shortener/internal/lifecycle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newManager() *lifecycle.Manager {
+	return lifecycle.New(slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)))
+}
+
+func TestOrder(t *testing.T) {
+	m := newManager()
+
+	var events []string
+	add := func(name string, deps ...string) {
+		m.Add(lifecycle.Component{
+			Name:      name,
+			DependsOn: deps,
+			Start: func(context.Context) error {
+				events = append(events, "start "+name)
+				return nil
+			},
+			Stop: func(context.Context) error {
+				events = append(events, "stop "+name)
+				return nil
+			},
+		})
+	}
+
+	add("server", "storage", "cache")
+	add("storage")
+	add("worker", "storage")
+	add("cache")
+
+	require.NoError(t, m.Start(context.Background()))
+	require.NoError(t, m.Stop(context.Background()))
+
+	assert.Equal(t, []string{
+		"start storage", "start worker", "start cache", "start server",
+		"stop server", "stop cache", "stop worker", "stop storage",
+	}, events)
+}
+
+func TestOrderInvalid(t *testing.T) {
+	m := newManager()
+	m.Add(lifecycle.Component{Name: "a", DependsOn: []string{"b"}})
+	m.Add(lifecycle.Component{Name: "b", DependsOn: []string{"a"}})
+	assert.ErrorContains(t, m.Start(context.Background()), "dependency cycle")
+
+	m = newManager()
+	m.Add(lifecycle.Component{Name: "a", DependsOn: []string{"missing"}})
+	assert.ErrorContains(t, m.Start(context.Background()), `depends on unknown "missing"`)
+}
+
+func TestStartFailure(t *testing.T) {
+	m := newManager()
+
+	var stopped []string
+	m.Add(lifecycle.Component{
+		Name: "storage",
+		Stop: func(context.Context) error {
+			stopped = append(stopped, "storage")
+			return nil
+		},
+	})
+	m.Add(lifecycle.Component{
+		Name:  "server",
+		Start: func(context.Context) error { return errors.New("address in use") },
+		Stop: func(context.Context) error {
+			stopped = append(stopped, "server")
+			return nil
+		},
+	})
+
+	assert.EqualError(t, m.Start(context.Background()), "lifecycle.Start: server: address in use")
+
+	// Останавливается только то, что успело запуститься.
+	require.NoError(t, m.Stop(context.Background()))
+	assert.Equal(t, []string{"storage"}, stopped)
+}
+
+func TestStopDeadline(t *testing.T) {
+	m := newManager()
+
+	var storageClosed bool
+	m.Add(lifecycle.Component{
+		Name: "storage",
+		Stop: func(context.Context) error {
+			storageClosed = true
+			return nil
+		},
+	})
+
+	release := make(chan struct{})
+	defer close(release)
+	m.Add(m.Worker("stuck", func(ctx context.Context) {
+		<-release
+	}, "storage"))
+
+	flushed := false
+	m.Add(m.Worker("writer", func(ctx context.Context) {
+		<-ctx.Done()
+		flushed = true
+	}, "storage"))
+
+	require.NoError(t, m.Start(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err := m.Stop(ctx)
+	assert.EqualError(t, err, "stuck did not stop in time")
+
+	var stopErr *lifecycle.StopError
+	require.ErrorAs(t, err, &stopErr)
+	assert.Equal(t, "stuck", stopErr.Component)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	assert.True(t, flushed)
+	assert.True(t, storageClosed)
+}
+
+func TestServer(t *testing.T) {
+	m := newManager()
+
+	srv := &http.Server{
+		Addr: "127.0.0.1:0",
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}),
+	}
+	m.Add(m.Server("public", srv, nil))
+	require.NoError(t, m.Start(context.Background()))
+	require.NoError(t, m.Stop(context.Background()))
+
+	// Занятый адрес - ошибка запуска, а не ошибка в фоне.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	m = newManager()
+	m.Add(m.Server("public", &http.Server{Addr: ln.Addr().String()}, nil))
+	assert.Error(t, m.Start(context.Background()))
+}
+
+func TestServerStopDeadline(t *testing.T) {
+	m := newManager()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})}
+	m.Add(m.Server("public", srv, ln))
+	require.NoError(t, m.Start(context.Background()))
+
+	reqErr := make(chan error, 1)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String())
+		if err == nil {
+			resp.Body.Close()
+		}
+		reqErr <- err
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err = m.Stop(ctx)
+	assert.EqualError(t, err, "public did not stop in time")
+
+	// Зависший запрос не держит соединение после остановки.
+	select {
+	case err := <-reqErr:
+		assert.Error(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("connection was not closed")
+	}
+}
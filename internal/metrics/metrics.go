@@ -1,6 +1,8 @@
 package metrics
 
 import (
+	"sync"
+
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -21,8 +23,38 @@ var (
 		},
 		[]string{"path", "method"},
 	)
+
+	// Без меток alias и варианта: их число не ограничено. Переходы по
+	// отдельным вариантам учитываются в базе.
+	VariantServedTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "redirect_variant_served_total",
+			Help: "Количество переходов по вариантам A/B-тестов.",
+		},
+	)
+
+	WebhookDeliveriesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "webhook_deliveries_total",
+			Help: "Количество попыток доставки вебхуков по результату.",
+		},
+		[]string{"result"},
+	)
+
+	BrokenLinks = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "links_broken",
+			Help: "Количество ссылок, адрес которых при последней проверке был недоступен.",
+		},
+	)
 )
 
+var registerOnce sync.Once
+
+// RegisterMetrics регистрирует метрики сервиса, повторный вызов ничего не
+// делает: в тестах в одном процессе запускается несколько серверов.
 func RegisterMetrics() {
-	prometheus.MustRegister(HTTPRequestsTotal, HTTPRequestsDuration)
+	registerOnce.Do(func() {
+		prometheus.MustRegister(HTTPRequestsTotal, HTTPRequestsDuration, VariantServedTotal, BrokenLinks, WebhookDeliveriesTotal)
+	})
 }
@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PoolStater - источник статистики пула соединений с базой.
+type PoolStater interface {
+	Stat() *pgxpool.Stat
+}
+
+// poolCollector снимает pgxpool.Stat в момент сбора метрик, поэтому
+// значения всегда актуальны и не требуют фонового обновления.
+type poolCollector struct {
+	pool PoolStater
+
+	acquiredConns        *prometheus.Desc
+	idleConns            *prometheus.Desc
+	constructingConns    *prometheus.Desc
+	totalConns           *prometheus.Desc
+	maxConns             *prometheus.Desc
+	acquireCount         *prometheus.Desc
+	acquireDuration      *prometheus.Desc
+	canceledAcquireCount *prometheus.Desc
+	emptyAcquireCount    *prometheus.Desc
+	newConnsCount        *prometheus.Desc
+	lifetimeDestroyCount *prometheus.Desc
+	idleDestroyCount     *prometheus.Desc
+}
+
+// NewPoolCollector возвращает коллектор статистики пула. name попадает
+// в метку pool и различает пулы, если их несколько.
+func NewPoolCollector(name string, pool PoolStater) prometheus.Collector {
+	labels := prometheus.Labels{"pool": name}
+	desc := func(metric, help string) *prometheus.Desc {
+		return prometheus.NewDesc("db_pool_"+metric, help, nil, labels)
+	}
+
+	return &poolCollector{
+		pool: pool,
+
+		acquiredConns:        desc("acquired_conns", "Количество соединений, занятых запросами."),
+		idleConns:            desc("idle_conns", "Количество свободных соединений."),
+		constructingConns:    desc("constructing_conns", "Количество соединений, которые сейчас открываются."),
+		totalConns:           desc("total_conns", "Общее количество соединений в пуле."),
+		maxConns:             desc("max_conns", "Максимальный размер пула."),
+		acquireCount:         desc("acquire_total", "Количество успешно полученных соединений."),
+		acquireDuration:      desc("acquire_duration_seconds_total", "Суммарное время ожидания соединения в секундах."),
+		canceledAcquireCount: desc("canceled_acquire_total", "Количество ожиданий соединения, прерванных отменой контекста."),
+		emptyAcquireCount:    desc("empty_acquire_total", "Количество получений соединения, которым пришлось ждать, потому что пул был пуст."),
+		newConnsCount:        desc("new_conns_total", "Количество открытых соединений."),
+		lifetimeDestroyCount: desc("max_lifetime_destroy_total", "Количество соединений, закрытых из-за MaxConnLifetime."),
+		idleDestroyCount:     desc("max_idle_destroy_total", "Количество соединений, закрытых из-за MaxConnIdleTime."),
+	}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+
+	gauge := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value)
+	}
+	counter := func(desc *prometheus.Desc, value float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, value)
+	}
+
+	gauge(c.acquiredConns, float64(stat.AcquiredConns()))
+	gauge(c.idleConns, float64(stat.IdleConns()))
+	gauge(c.constructingConns, float64(stat.ConstructingConns()))
+	gauge(c.totalConns, float64(stat.TotalConns()))
+	gauge(c.maxConns, float64(stat.MaxConns()))
+
+	counter(c.acquireCount, float64(stat.AcquireCount()))
+	counter(c.acquireDuration, stat.AcquireDuration().Seconds())
+	counter(c.canceledAcquireCount, float64(stat.CanceledAcquireCount()))
+	counter(c.emptyAcquireCount, float64(stat.EmptyAcquireCount()))
+	counter(c.newConnsCount, float64(stat.NewConnsCount()))
+	counter(c.lifetimeDestroyCount, float64(stat.MaxLifetimeDestroyCount()))
+	counter(c.idleDestroyCount, float64(stat.MaxIdleDestroyCount()))
+}
+
+// RegisterPool регистрирует метрики пула соединений. Если пул с тем же
+// name уже зарегистрирован (повторный app.New в одном процессе), его
+// коллектор заменяется новым, чтобы метрики не снимались с закрытого пула.
+func RegisterPool(name string, pool PoolStater) {
+	registerPool(prometheus.DefaultRegisterer, name, pool)
+}
+
+func registerPool(reg prometheus.Registerer, name string, pool PoolStater) {
+	collector := NewPoolCollector(name, pool)
+
+	err := reg.Register(collector)
+	var already prometheus.AlreadyRegisteredError
+	if errors.As(err, &already) {
+		reg.Unregister(already.ExistingCollector)
+		err = reg.Register(collector)
+	}
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,31 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterPoolTwice(t *testing.T) {
+	// Пул без MinConns не подключается к базе при создании.
+	pool, err := pgxpool.New(context.Background(), "postgres://localhost:1/db")
+	require.NoError(t, err)
+	defer pool.Close()
+
+	reg := prometheus.NewRegistry()
+
+	assert.NotPanics(t, func() {
+		registerPool(reg, "primary", pool)
+		registerPool(reg, "primary", pool)
+		registerPool(reg, "replica-0", pool)
+	})
+
+	count, err := testutil.GatherAndCount(reg, "db_pool_total_conns")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
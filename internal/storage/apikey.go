@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// apiKeyPrefix отличает ключи сервиса от других секретов, например в логах
+// и сканерах утечек.
+const apiKeyPrefix = "us_"
+
+// APIKey - ключ доступа к API управления ссылками. Сам ключ не хранится,
+// только его хеш и первые символы для опознания.
+type APIKey struct {
+	ID        int64      `json:"id"`
+	Name      string     `json:"name"`
+	Prefix    string     `json:"prefix"`
+	Hash      string     `json:"-"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// NewAPIKey генерирует ключ и возвращает его вместе с описанием для
+// сохранения. Ключ показывается один раз, восстановить его по хешу нельзя.
+func NewAPIKey(name string) (string, APIKey, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", APIKey{}, err
+	}
+
+	key := apiKeyPrefix + hex.EncodeToString(buf)
+
+	return key, APIKey{
+		Name:   name,
+		Prefix: key[:len(apiKeyPrefix)+8],
+		Hash:   HashAPIKey(key),
+	}, nil
+}
+
+// HashAPIKey - хеш, по которому ключ ищется в хранилище. Ключ случайный и
+// длинный, поэтому соль и медленный хеш не нужны.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,18 @@
+package storage
+
+import "context"
+
+type primaryKey struct{}
+
+// WithPrimary помечает контекст так, что чтения в нём идут на основную
+// базу, минуя реплики. Нужно, чтобы клиент сразу видел свои изменения,
+// пока реплики их ещё не получили.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// UsePrimary сообщает, помечен ли контекст через WithPrimary.
+func UsePrimary(ctx context.Context) bool {
+	primary, _ := ctx.Value(primaryKey{}).(bool)
+	return primary
+}
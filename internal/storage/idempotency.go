@@ -0,0 +1,21 @@
+package storage
+
+import "time"
+
+// IdempotencyRecord - запрос с заголовком Idempotency-Key и ответ на него.
+type IdempotencyRecord struct {
+	Key string
+	// RequestHash - хеш метода, пути и тела запроса. Повтор ключа с другим
+	// запросом отклоняется.
+	RequestHash string
+	// Status - код сохранённого ответа, 0 - запрос ещё выполняется.
+	Status      int
+	ContentType string
+	Body        []byte
+	ExpiresAt   time.Time
+}
+
+// InFlight сообщает, что запрос с этим ключом ещё выполняется.
+func (r IdempotencyRecord) InFlight() bool {
+	return r.Status == 0
+}
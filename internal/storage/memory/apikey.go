@@ -0,0 +1,50 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/RozmiDan/url_shortener/internal/storage"
+)
+
+func (s *Storage) CreateAPIKey(ctx context.Context, key storage.APIKey) (storage.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastAPIKeyID++
+	key.ID = s.lastAPIKeyID
+	key.CreatedAt = time.Now()
+	key.RevokedAt = nil
+	s.apiKeys[key.ID] = &key
+
+	return key, nil
+}
+
+func (s *Storage) RevokeAPIKey(ctx context.Context, id int64) (storage.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k, ok := s.apiKeys[id]
+	if !ok {
+		return storage.APIKey{}, storage.ErrAPIKeyNotFound
+	}
+	if k.RevokedAt == nil {
+		now := time.Now()
+		k.RevokedAt = &now
+	}
+
+	return *k, nil
+}
+
+func (s *Storage) FindAPIKey(ctx context.Context, hash string) (storage.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, k := range s.apiKeys {
+		if k.Hash == hash && k.RevokedAt == nil {
+			return *k, nil
+		}
+	}
+
+	return storage.APIKey{}, storage.ErrAPIKeyNotFound
+}
@@ -0,0 +1,84 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/RozmiDan/url_shortener/internal/storage"
+)
+
+type idempotencyKey struct {
+	rec         storage.IdempotencyRecord
+	lockedUntil time.Time
+}
+
+func (s *Storage) ReserveIdempotencyKey(ctx context.Context, key, requestHash string, ttl, lockTimeout time.Duration) (storage.IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	if k, ok := s.idempotency[key]; ok {
+		expired := !k.rec.ExpiresAt.After(now)
+		abandoned := k.rec.InFlight() && !k.lockedUntil.After(now)
+		if !expired && !abandoned {
+			rec := k.rec
+			rec.Body = slices.Clone(rec.Body)
+			return rec, false, nil
+		}
+	}
+
+	rec := storage.IdempotencyRecord{
+		Key:         key,
+		RequestHash: requestHash,
+		ExpiresAt:   now.Add(ttl),
+	}
+	s.idempotency[key] = &idempotencyKey{rec: rec, lockedUntil: now.Add(lockTimeout)}
+
+	return rec, true, nil
+}
+
+func (s *Storage) CompleteIdempotencyKey(ctx context.Context, rec storage.IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k, ok := s.idempotency[rec.Key]
+	if !ok || k.rec.RequestHash != rec.RequestHash || !k.rec.InFlight() {
+		return nil
+	}
+
+	k.rec.Status = rec.Status
+	k.rec.ContentType = rec.ContentType
+	k.rec.Body = slices.Clone(rec.Body)
+
+	return nil
+}
+
+func (s *Storage) ReleaseIdempotencyKey(ctx context.Context, key, requestHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if k, ok := s.idempotency[key]; ok && k.rec.RequestHash == requestHash && k.rec.InFlight() {
+		delete(s.idempotency, key)
+	}
+
+	return nil
+}
+
+func (s *Storage) PurgeIdempotencyKeys(ctx context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	var purged int64
+	for key, k := range s.idempotency {
+		if !k.rec.ExpiresAt.After(now) {
+			delete(s.idempotency, key)
+			purged++
+		}
+	}
+
+	return purged, nil
+}
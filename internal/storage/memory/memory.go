@@ -0,0 +1,519 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/RozmiDan/url_shortener/internal/storage"
+)
+
+type link struct {
+	id         int64
+	url        string
+	maxClicks  int64
+	clicksLeft int64
+
+	activeFrom  *time.Time
+	activeUntil *time.Time
+
+	rules    []storage.Rule
+	variants []storage.Variant
+	forward  storage.ForwardOptions
+	meta     storage.LinkMeta
+	preview  bool
+
+	check         *storage.CheckResult
+	checkingUntil time.Time
+
+	org          storage.Organization
+	redirectCode int
+	version      int64
+	updatedAt    time.Time
+}
+
+// touch отмечает изменение ссылки через API.
+func (l *link) touch() {
+	l.version++
+	l.updatedAt = time.Now()
+}
+
+// Storage - хранилище ссылок в памяти процесса, используется в тестах
+// и для локального запуска без базы данных.
+type Storage struct {
+	mu         sync.Mutex
+	lastID     int64
+	lastRuleID int64
+	lastVarID  int64
+	links      map[string]*link
+
+	lastWebhookID  int64
+	lastEventID    int64
+	lastDeliveryID int64
+	webhooks       map[int64]*storage.Webhook
+	deliveries     []*delivery
+
+	idempotency map[string]*idempotencyKey
+
+	lastAPIKeyID int64
+	apiKeys      map[int64]*storage.APIKey
+}
+
+func New() *Storage {
+	return &Storage{
+		links:    make(map[string]*link),
+		webhooks: make(map[int64]*storage.Webhook),
+
+		idempotency: make(map[string]*idempotencyKey),
+		apiKeys:     make(map[int64]*storage.APIKey),
+	}
+}
+
+func (s *Storage) SaveURL(ctx context.Context, urlToSave string, alias string, opts storage.URLOptions) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if l, ok := s.links[alias]; ok {
+		l.url = urlToSave
+		l.maxClicks = opts.MaxClicks
+		l.clicksLeft = opts.MaxClicks
+		l.activeFrom = opts.ActiveFrom
+		l.activeUntil = opts.ActiveUntil
+		l.variants = s.newVariants(opts.Variants)
+		l.forward = opts.Forward
+		l.meta = opts.Meta
+		l.preview = opts.Preview
+		l.check = nil
+		l.org = cloneOrganization(opts.Organization)
+		l.redirectCode = opts.RedirectCode
+		l.touch()
+		return l.id, nil
+	}
+
+	return s.insertLocked(urlToSave, alias, opts), nil
+}
+
+// CreateURL сохраняет новую ссылку, занятый alias - storage.ErrAliasExists.
+func (s *Storage) CreateURL(ctx context.Context, urlToSave string, alias string, opts storage.URLOptions) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.links[alias]; ok {
+		return 0, storage.ErrAliasExists
+	}
+
+	return s.insertLocked(urlToSave, alias, opts), nil
+}
+
+func (s *Storage) insertLocked(urlToSave string, alias string, opts storage.URLOptions) int64 {
+	s.lastID++
+	s.links[alias] = &link{
+		id:         s.lastID,
+		url:        urlToSave,
+		maxClicks:  opts.MaxClicks,
+		clicksLeft: opts.MaxClicks,
+
+		activeFrom:  opts.ActiveFrom,
+		activeUntil: opts.ActiveUntil,
+
+		variants: s.newVariants(opts.Variants),
+		forward:  opts.Forward,
+		meta:     opts.Meta,
+		preview:  opts.Preview,
+
+		org:          cloneOrganization(opts.Organization),
+		redirectCode: opts.RedirectCode,
+		version:      1,
+		updatedAt:    time.Now(),
+	}
+
+	s.enqueueLocked(storage.EventLinkCreated, storage.LinkEvent{Alias: alias, URL: urlToSave})
+
+	return s.lastID
+}
+
+func (s *Storage) newVariants(variants []storage.Variant) []storage.Variant {
+	result := make([]storage.Variant, 0, len(variants))
+	for _, v := range variants {
+		s.lastVarID++
+		result = append(result, storage.Variant{
+			ID:     s.lastVarID,
+			URL:    v.URL,
+			Weight: v.Weight,
+		})
+	}
+	return result
+}
+
+func (s *Storage) GetURL(ctx context.Context, alias string) (storage.Link, error) {
+	return s.getURL(alias, false)
+}
+
+// FollowURL засчитывает переход, как GetURL, в том числе по ссылке с
+// флагом preview: так считается кнопка "Continue" страницы предпросмотра.
+func (s *Storage) FollowURL(ctx context.Context, alias string) (storage.Link, error) {
+	return s.getURL(alias, true)
+}
+
+func (s *Storage) getURL(alias string, follow bool) (storage.Link, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, err := s.resolveLocked(alias)
+	if err != nil {
+		return storage.Link{}, err
+	}
+
+	// Ссылка с флагом preview отдаёт страницу предпросмотра, переход
+	// засчитывается только со страницы.
+	if !l.preview || follow {
+		if l.maxClicks > 0 {
+			l.clicksLeft--
+		}
+		s.enqueueLocked(storage.EventLinkClicked, storage.LinkEvent{Alias: alias, URL: l.url})
+	}
+
+	return l.toLink(), nil
+}
+
+// PeekURL возвращает ссылку, как GetURL, но не засчитывает переход.
+func (s *Storage) PeekURL(ctx context.Context, alias string) (storage.Link, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, err := s.resolveLocked(alias)
+	if err != nil {
+		return storage.Link{}, err
+	}
+	return l.toLink(), nil
+}
+
+// resolveLocked находит ссылку и проверяет, что по ней можно перейти.
+func (s *Storage) resolveLocked(alias string) (*link, error) {
+	l, ok := s.links[alias]
+	if !ok {
+		return nil, storage.ErrURLNotFound
+	}
+
+	if err := storage.CheckActiveWindow(time.Now(), l.activeFrom, l.activeUntil); err != nil {
+		return nil, err
+	}
+
+	if l.maxClicks > 0 && l.clicksLeft == 0 {
+		return nil, storage.ErrURLExhausted
+	}
+	return l, nil
+}
+
+func (l *link) toLink() storage.Link {
+	return storage.Link{
+		URL:      l.url,
+		Rules:    slices.Clone(l.rules),
+		Variants: slices.Clone(l.variants),
+		Forward:  l.forward,
+		Meta:     l.meta,
+		Preview:  l.preview,
+
+		RedirectCode: l.redirectCode,
+	}
+}
+
+// lookupLocked находит ссылку и проверяет её версию, если expectedVersion не 0.
+func (s *Storage) lookupLocked(alias string, expectedVersion int64) (*link, error) {
+	l, ok := s.links[alias]
+	if !ok {
+		return nil, storage.ErrAliasNotFound
+	}
+	if expectedVersion != 0 && l.version != expectedVersion {
+		return nil, storage.ErrVersionConflict
+	}
+	return l, nil
+}
+
+func (s *Storage) DeleteURL(ctx context.Context, alias string, expectedVersion int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, err := s.lookupLocked(alias, expectedVersion)
+	if err != nil {
+		return err
+	}
+	delete(s.links, alias)
+
+	s.enqueueLocked(storage.EventLinkDeleted, storage.LinkEvent{Alias: alias, URL: l.url})
+
+	return nil
+}
+
+func (s *Storage) GetLinkState(ctx context.Context, alias string) (storage.LinkState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.links[alias]
+	if !ok {
+		return storage.LinkState{}, storage.ErrAliasNotFound
+	}
+
+	return l.state(alias), nil
+}
+
+func (s *Storage) UpdateLink(ctx context.Context, alias string, state storage.LinkState, expectedVersion int64) (storage.LinkState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, err := s.lookupLocked(alias, expectedVersion)
+	if err != nil {
+		return storage.LinkState{}, err
+	}
+	if state.Alias != alias {
+		if _, ok := s.links[state.Alias]; ok {
+			return storage.LinkState{}, storage.ErrAliasExists
+		}
+	}
+
+	opts := state.Options
+	if l.maxClicks != opts.MaxClicks {
+		l.maxClicks = opts.MaxClicks
+		l.clicksLeft = opts.MaxClicks
+	}
+	if l.url != state.URL {
+		l.check = nil
+	}
+	l.url = state.URL
+	l.activeFrom = opts.ActiveFrom
+	l.activeUntil = opts.ActiveUntil
+	l.forward = opts.Forward
+	l.meta = opts.Meta
+	l.preview = opts.Preview
+	l.org = cloneOrganization(opts.Organization)
+	l.redirectCode = opts.RedirectCode
+	l.touch()
+
+	if state.Alias != alias {
+		delete(s.links, alias)
+		s.links[state.Alias] = l
+		s.enqueueLocked(storage.EventLinkRenamed, storage.LinkEvent{Alias: state.Alias, URL: l.url, OldAlias: alias})
+	}
+
+	return l.state(state.Alias), nil
+}
+
+func (l *link) state(alias string) storage.LinkState {
+	return storage.LinkState{
+		Alias: alias,
+		URL:   l.url,
+		Options: storage.URLOptions{
+			MaxClicks:    l.maxClicks,
+			ActiveFrom:   l.activeFrom,
+			ActiveUntil:  l.activeUntil,
+			Forward:      l.forward,
+			Meta:         l.meta,
+			Preview:      l.preview,
+			Organization: cloneOrganization(l.org),
+			RedirectCode: l.redirectCode,
+		},
+		Version:   l.version,
+		UpdatedAt: l.updatedAt,
+	}
+}
+
+func (s *Storage) ListRules(ctx context.Context, alias string) ([]storage.Rule, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.links[alias]
+	if !ok {
+		return nil, 0, storage.ErrAliasNotFound
+	}
+
+	return slices.Clone(l.rules), l.version, nil
+}
+
+func (s *Storage) AddRule(ctx context.Context, alias string, rule storage.Rule, expectedVersion int64) (storage.Rule, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, err := s.lookupLocked(alias, expectedVersion)
+	if err != nil {
+		return storage.Rule{}, 0, err
+	}
+
+	s.lastRuleID++
+	rule.ID = s.lastRuleID
+	l.rules = append(l.rules, rule)
+	l.touch()
+
+	return rule, l.version, nil
+}
+
+func (s *Storage) UpdateRule(ctx context.Context, alias string, rule storage.Rule, expectedVersion int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, err := s.lookupLocked(alias, expectedVersion)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range l.rules {
+		if l.rules[i].ID == rule.ID {
+			l.rules[i] = rule
+			l.touch()
+			return l.version, nil
+		}
+	}
+
+	return 0, storage.ErrRuleNotFound
+}
+
+func (s *Storage) DeleteRule(ctx context.Context, alias string, ruleID int64, expectedVersion int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, err := s.lookupLocked(alias, expectedVersion)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range l.rules {
+		if l.rules[i].ID == ruleID {
+			l.rules = slices.Delete(l.rules, i, i+1)
+			l.touch()
+			return l.version, nil
+		}
+	}
+
+	return 0, storage.ErrRuleNotFound
+}
+
+func (s *Storage) ReplaceRules(ctx context.Context, alias string, rules []storage.Rule, expectedVersion int64) ([]storage.Rule, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, err := s.lookupLocked(alias, expectedVersion)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	result := make([]storage.Rule, len(rules))
+	for i, rule := range rules {
+		s.lastRuleID++
+		rule.ID = s.lastRuleID
+		result[i] = rule
+	}
+	l.rules = slices.Clone(result)
+	l.touch()
+
+	return result, l.version, nil
+}
+
+func (s *Storage) ListVariants(ctx context.Context, alias string) ([]storage.Variant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.links[alias]
+	if !ok {
+		return nil, storage.ErrAliasNotFound
+	}
+
+	return slices.Clone(l.variants), nil
+}
+
+func (s *Storage) RecordVariantServed(ctx context.Context, variantID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, l := range s.links {
+		for i := range l.variants {
+			if l.variants[i].ID == variantID {
+				l.variants[i].Clicks++
+				return nil
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) ClaimLinksToCheck(ctx context.Context, checkedBefore time.Time, limit int, lease time.Duration) ([]storage.CheckTarget, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	var claimed []*link
+	for _, l := range s.links {
+		if l.checkingUntil.After(now) {
+			continue
+		}
+		if l.check == nil || l.check.CheckedAt.Before(checkedBefore) {
+			claimed = append(claimed, l)
+		}
+	}
+
+	slices.SortFunc(claimed, func(a, b *link) int { return cmp.Compare(a.id, b.id) })
+	if len(claimed) > limit {
+		claimed = claimed[:limit]
+	}
+
+	targets := make([]storage.CheckTarget, 0, len(claimed))
+	for _, l := range claimed {
+		l.checkingUntil = now.Add(lease)
+		targets = append(targets, storage.CheckTarget{ID: l.id, URL: l.url})
+	}
+
+	return targets, nil
+}
+
+func (s *Storage) SaveCheckResult(ctx context.Context, res storage.CheckResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, l := range s.links {
+		if l.id == res.ID {
+			l.check = &res
+			l.checkingUntil = time.Time{}
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) ListBroken(ctx context.Context) ([]storage.BrokenLink, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result []storage.BrokenLink
+	for alias, l := range s.links {
+		if l.check == nil || !l.check.IsBroken() {
+			continue
+		}
+		result = append(result, storage.BrokenLink{
+			Alias:      alias,
+			URL:        l.url,
+			LastStatus: l.check.Status,
+			LastError:  l.check.Error,
+			LatencyMs:  l.check.Latency.Milliseconds(),
+			CheckedAt:  l.check.CheckedAt,
+		})
+	}
+
+	slices.SortFunc(result, func(a, b storage.BrokenLink) int { return b.CheckedAt.Compare(a.CheckedAt) })
+
+	return result, nil
+}
+
+func (s *Storage) CountBroken(ctx context.Context) (int, error) {
+	broken, err := s.ListBroken(ctx)
+	return len(broken), err
+}
+
+// Ping всегда успешен: данные в памяти процесса.
+func (s *Storage) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (s *Storage) Close() {}
@@ -0,0 +1,212 @@
+package memory_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/RozmiDan/url_shortener/internal/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetURLConcurrentMaxClicks(t *testing.T) {
+	const (
+		maxClicks = 10
+		workers   = 100
+	)
+
+	st := memory.New()
+	_, err := st.SaveURL(context.Background(), "https://example.com", "invite", storage.URLOptions{MaxClicks: maxClicks})
+	require.NoError(t, err)
+
+	var served, exhausted atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := st.GetURL(context.Background(), "invite")
+			switch {
+			case err == nil:
+				served.Add(1)
+			case errors.Is(err, storage.ErrURLExhausted):
+				exhausted.Add(1)
+			default:
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(maxClicks), served.Load())
+	assert.Equal(t, int64(workers-maxClicks), exhausted.Load())
+}
+
+func TestGetURLUnlimited(t *testing.T) {
+	st := memory.New()
+	_, err := st.SaveURL(context.Background(), "https://example.com", "plain", storage.URLOptions{})
+	require.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		link, err := st.GetURL(context.Background(), "plain")
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com", link.URL)
+	}
+
+	_, err = st.GetURL(context.Background(), "missing")
+	assert.ErrorIs(t, err, storage.ErrURLNotFound)
+}
+
+func TestGetURLActiveWindow(t *testing.T) {
+	st := memory.New()
+
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	_, err := st.SaveURL(context.Background(), "https://example.com", "upcoming",
+		storage.URLOptions{ActiveFrom: &future, MaxClicks: 1})
+	require.NoError(t, err)
+
+	_, err = st.GetURL(context.Background(), "upcoming")
+	assert.ErrorIs(t, err, storage.ErrURLNotActive)
+
+	setWindow := func(from, until *time.Time) {
+		state, err := st.GetLinkState(context.Background(), "upcoming")
+		require.NoError(t, err)
+		state.Options.ActiveFrom, state.Options.ActiveUntil = from, until
+		_, err = st.UpdateLink(context.Background(), "upcoming", state, 0)
+		require.NoError(t, err)
+	}
+
+	setWindow(&past, &future)
+	_, err = st.GetURL(context.Background(), "upcoming")
+	assert.NoError(t, err, "clicks must not be spent before the window opens")
+
+	setWindow(nil, &past)
+	_, err = st.GetURL(context.Background(), "upcoming")
+	assert.ErrorIs(t, err, storage.ErrURLExpired)
+
+	_, err = st.UpdateLink(context.Background(), "missing", storage.LinkState{Alias: "missing"}, 0)
+	assert.ErrorIs(t, err, storage.ErrAliasNotFound)
+}
+
+func TestLinkVersion(t *testing.T) {
+	st := memory.New()
+	ctx := context.Background()
+
+	_, err := st.SaveURL(ctx, "https://example.com", "abc", storage.URLOptions{})
+	require.NoError(t, err)
+
+	state, err := st.GetLinkState(ctx, "abc")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, state.Version)
+
+	state.Alias = "renamed"
+	state, err = st.UpdateLink(ctx, "abc", state, 1)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, state.Version)
+
+	// Второй администратор работает со старой версией и не должен
+	// затереть переименование.
+	state.Alias = "other"
+	_, err = st.UpdateLink(ctx, "renamed", state, 1)
+	assert.ErrorIs(t, err, storage.ErrVersionConflict)
+	assert.ErrorIs(t, st.DeleteURL(ctx, "renamed", 1), storage.ErrVersionConflict)
+
+	version, err := st.SetOrganization(ctx, "renamed", storage.OrganizationPatch{}, 0)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, version)
+
+	// Переходы по ссылке версию не меняют.
+	_, err = st.GetURL(ctx, "renamed")
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, st.DeleteURL(ctx, "missing", 3), storage.ErrAliasNotFound)
+	require.NoError(t, st.DeleteURL(ctx, "renamed", 3))
+}
+
+func TestTagsRenameAndMerge(t *testing.T) {
+	st := memory.New()
+	ctx := context.Background()
+
+	save := func(alias string, tags ...string) {
+		_, err := st.SaveURL(ctx, "https://example.com/"+alias, alias, storage.URLOptions{
+			Organization: storage.Organization{Tags: tags, Folder: "spring"},
+		})
+		require.NoError(t, err)
+	}
+	save("a", "promo", "sale")
+	save("b", "sale")
+	save("c", "news")
+
+	assert.ErrorIs(t, st.RenameTag(ctx, "missing", "x"), storage.ErrTagNotFound)
+	assert.ErrorIs(t, st.RenameTag(ctx, "news", "sale"), storage.ErrTagExists)
+	require.NoError(t, st.RenameTag(ctx, "news", "blog"))
+
+	require.NoError(t, st.MergeTags(ctx, []string{"promo", "sale"}, "marketing"))
+
+	tags, err := st.ListTags(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []storage.Tag{{Name: "blog", Links: 1}, {Name: "marketing", Links: 2}}, tags)
+
+	links, err := st.ListLinks(ctx, storage.ListFilter{Tags: []string{"marketing"}, Folder: "spring"})
+	require.NoError(t, err)
+	require.Len(t, links, 2)
+	assert.Equal(t, "a", links[0].Alias)
+	assert.Equal(t, []string{"marketing"}, links[0].Tags)
+
+	// Ссылка "a" изменена слиянием один раз, "c" - переименованием.
+	assert.EqualValues(t, 2, links[0].Version)
+	state, err := st.GetLinkState(ctx, "c")
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, state.Version)
+}
+
+func TestRulesBumpVersion(t *testing.T) {
+	st := memory.New()
+	ctx := context.Background()
+
+	_, err := st.SaveURL(ctx, "https://example.com", "abc", storage.URLOptions{})
+	require.NoError(t, err)
+
+	version := func() int64 {
+		state, err := st.GetLinkState(ctx, "abc")
+		require.NoError(t, err)
+		return state.Version
+	}
+
+	rule, v, err := st.AddRule(ctx, "abc", storage.Rule{TargetURL: "https://example.com/a"}, 0)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, v)
+	assert.EqualValues(t, 2, version())
+
+	rule.TargetURL = "https://example.com/b"
+	v, err = st.UpdateRule(ctx, "abc", rule, 2)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, v)
+
+	_, v, err = st.ReplaceRules(ctx, "abc", []storage.Rule{rule}, 0)
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, v)
+
+	rules, v, err := st.ListRules(ctx, "abc")
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, v)
+
+	v, err = st.DeleteRule(ctx, "abc", rules[0].ID, 4)
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, v)
+	assert.EqualValues(t, 5, version())
+
+	// Неудачное изменение версию не меняет.
+	_, err = st.DeleteRule(ctx, "abc", rules[0].ID, 0)
+	assert.ErrorIs(t, err, storage.ErrRuleNotFound)
+	_, _, err = st.AddRule(ctx, "abc", rule, 4)
+	assert.ErrorIs(t, err, storage.ErrVersionConflict)
+	assert.EqualValues(t, 5, version())
+}
@@ -0,0 +1,193 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/RozmiDan/url_shortener/internal/storage"
+)
+
+func cloneOrganization(org storage.Organization) storage.Organization {
+	return storage.Organization{
+		Tags:     slices.Clone(org.Tags),
+		Folder:   org.Folder,
+		Metadata: maps.Clone(org.Metadata),
+	}
+}
+
+func (s *Storage) SetOrganization(ctx context.Context, alias string, patch storage.OrganizationPatch, expectedVersion int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, err := s.lookupLocked(alias, expectedVersion)
+	if err != nil {
+		return 0, err
+	}
+
+	if patch.Tags != nil {
+		l.org.Tags = slices.Clone(*patch.Tags)
+	}
+	if patch.Folder != nil {
+		l.org.Folder = *patch.Folder
+	}
+	if patch.Metadata != nil {
+		l.org.Metadata = maps.Clone(*patch.Metadata)
+	}
+	l.touch()
+
+	return l.version, nil
+}
+
+func (s *Storage) ListLinks(ctx context.Context, filter storage.ListFilter) ([]storage.LinkInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	type found struct {
+		id   int64
+		info storage.LinkInfo
+	}
+
+	var matched []found
+	for alias, l := range s.links {
+		if !matches(alias, l, filter) {
+			continue
+		}
+
+		info := storage.LinkInfo{
+			Alias:        alias,
+			URL:          l.url,
+			Organization: cloneOrganization(l.org),
+			Version:      l.version,
+			UpdatedAt:    l.updatedAt,
+		}
+		if info.Tags == nil {
+			info.Tags = []string{}
+		}
+		matched = append(matched, found{id: l.id, info: info})
+	}
+
+	slices.SortFunc(matched, func(a, b found) int { return cmp.Compare(a.id, b.id) })
+
+	if filter.Offset >= len(matched) {
+		return nil, nil
+	}
+	matched = matched[filter.Offset:]
+	if filter.Limit > 0 && len(matched) > filter.Limit {
+		matched = matched[:filter.Limit]
+	}
+
+	result := make([]storage.LinkInfo, len(matched))
+	for i, m := range matched {
+		result[i] = m.info
+	}
+
+	return result, nil
+}
+
+func matches(alias string, l *link, filter storage.ListFilter) bool {
+	if filter.Folder != "" && l.org.Folder != filter.Folder {
+		return false
+	}
+
+	for _, tag := range filter.Tags {
+		if !slices.Contains(l.org.Tags, tag) {
+			return false
+		}
+	}
+
+	for key, value := range filter.Metadata {
+		actual, ok := l.org.Metadata[key].(string)
+		if !ok || actual != value {
+			return false
+		}
+	}
+
+	if filter.Query != "" {
+		query := strings.ToLower(filter.Query)
+		if !strings.Contains(strings.ToLower(alias), query) && !strings.Contains(strings.ToLower(l.url), query) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *Storage) ListTags(ctx context.Context) ([]storage.Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make(map[string]int64)
+	for _, l := range s.links {
+		for _, tag := range l.org.Tags {
+			counts[tag]++
+		}
+	}
+
+	result := make([]storage.Tag, 0, len(counts))
+	for name, links := range counts {
+		result = append(result, storage.Tag{Name: name, Links: links})
+	}
+	slices.SortFunc(result, func(a, b storage.Tag) int { return cmp.Compare(a.Name, b.Name) })
+
+	return result, nil
+}
+
+func (s *Storage) RenameTag(ctx context.Context, oldName, newName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.tagExistsLocked(oldName) {
+		return storage.ErrTagNotFound
+	}
+	if s.tagExistsLocked(newName) {
+		return storage.ErrTagExists
+	}
+
+	s.replaceTagsLocked([]string{oldName}, newName)
+
+	return nil
+}
+
+func (s *Storage) MergeTags(ctx context.Context, sources []string, target string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sources = slices.DeleteFunc(slices.Clone(sources), func(tag string) bool {
+		return tag == target || !s.tagExistsLocked(tag)
+	})
+	if len(sources) == 0 {
+		return storage.ErrTagNotFound
+	}
+
+	s.replaceTagsLocked(sources, target)
+
+	return nil
+}
+
+func (s *Storage) tagExistsLocked(name string) bool {
+	for _, l := range s.links {
+		if slices.Contains(l.org.Tags, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Storage) replaceTagsLocked(sources []string, target string) {
+	for _, l := range s.links {
+		replaced := false
+		for i, tag := range l.org.Tags {
+			if slices.Contains(sources, tag) {
+				l.org.Tags[i] = target
+				replaced = true
+			}
+		}
+		if replaced {
+			l.org.Tags = storage.NormalizeTags(l.org.Tags)
+			l.touch()
+		}
+	}
+}
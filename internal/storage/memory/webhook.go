@@ -0,0 +1,226 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/RozmiDan/url_shortener/internal/storage"
+)
+
+type delivery struct {
+	storage.Delivery
+	nextAttemptAt time.Time
+}
+
+// enqueueLocked создаёт доставки события всем подходящим подпискам.
+// Вызывается под s.mu вместе с изменением ссылки.
+func (s *Storage) enqueueLocked(eventType string, event storage.LinkEvent) {
+	var subscribers []*storage.Webhook
+	for _, w := range s.webhooks {
+		if w.Wants(eventType) {
+			subscribers = append(subscribers, w)
+		}
+	}
+	if len(subscribers) == 0 {
+		return
+	}
+
+	payload, _ := json.Marshal(event)
+	now := time.Now()
+
+	s.lastEventID++
+	for _, w := range subscribers {
+		s.lastDeliveryID++
+		s.deliveries = append(s.deliveries, &delivery{
+			Delivery: storage.Delivery{
+				ID:        s.lastDeliveryID,
+				WebhookID: w.ID,
+				EventID:   s.lastEventID,
+				EventType: eventType,
+				Payload:   payload,
+				State:     storage.DeliveryPending,
+				CreatedAt: now,
+				UpdatedAt: now,
+			},
+			nextAttemptAt: now,
+		})
+	}
+}
+
+func (s *Storage) CreateWebhook(ctx context.Context, webhook storage.Webhook) (storage.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastWebhookID++
+	webhook.ID = s.lastWebhookID
+	webhook.Events = slices.Clone(webhook.Events)
+	webhook.CreatedAt = time.Now()
+	s.webhooks[webhook.ID] = &webhook
+
+	return webhook, nil
+}
+
+func (s *Storage) ListWebhooks(ctx context.Context) ([]storage.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]storage.Webhook, 0, len(s.webhooks))
+	for _, w := range s.webhooks {
+		result = append(result, *w)
+	}
+	slices.SortFunc(result, func(a, b storage.Webhook) int { return cmp.Compare(a.ID, b.ID) })
+
+	return result, nil
+}
+
+func (s *Storage) GetWebhook(ctx context.Context, id int64) (storage.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w, ok := s.webhooks[id]
+	if !ok {
+		return storage.Webhook{}, storage.ErrWebhookNotFound
+	}
+
+	return *w, nil
+}
+
+func (s *Storage) UpdateWebhook(ctx context.Context, webhook storage.Webhook) (storage.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w, ok := s.webhooks[webhook.ID]
+	if !ok {
+		return storage.Webhook{}, storage.ErrWebhookNotFound
+	}
+
+	w.URL = webhook.URL
+	if webhook.Secret != "" {
+		w.Secret = webhook.Secret
+	}
+	w.Events = slices.Clone(webhook.Events)
+	w.Active = webhook.Active
+
+	return *w, nil
+}
+
+func (s *Storage) DeleteWebhook(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.webhooks[id]; !ok {
+		return storage.ErrWebhookNotFound
+	}
+	delete(s.webhooks, id)
+
+	s.deliveries = slices.DeleteFunc(s.deliveries, func(d *delivery) bool { return d.WebhookID == id })
+
+	return nil
+}
+
+func (s *Storage) ClaimDeliveries(ctx context.Context, dueBefore time.Time, limit int, lease time.Duration) ([]storage.Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	var result []storage.Delivery
+	for _, d := range s.deliveries {
+		if len(result) >= limit {
+			break
+		}
+		if d.State != storage.DeliveryPending || d.nextAttemptAt.After(dueBefore) {
+			continue
+		}
+
+		d.nextAttemptAt = now.Add(lease)
+		claimed := d.Delivery
+		claimed.URL = s.webhooks[d.WebhookID].URL
+		claimed.Secret = s.webhooks[d.WebhookID].Secret
+		result = append(result, claimed)
+	}
+
+	return result, nil
+}
+
+func (s *Storage) SaveDeliveryResult(ctx context.Context, res storage.DeliveryResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, d := range s.deliveries {
+		if d.ID == res.ID {
+			d.State = res.State
+			d.Attempts++
+			d.LastStatus = res.Status
+			d.LastError = res.Error
+			d.UpdatedAt = time.Now()
+			d.nextAttemptAt = res.NextAttemptAt
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) ListDeadDeliveries(ctx context.Context) ([]storage.Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result []storage.Delivery
+	for _, d := range s.deliveries {
+		if d.State != storage.DeliveryDead {
+			continue
+		}
+		dead := d.Delivery
+		dead.URL = s.webhooks[d.WebhookID].URL
+		result = append(result, dead)
+	}
+	slices.SortFunc(result, func(a, b storage.Delivery) int { return b.UpdatedAt.Compare(a.UpdatedAt) })
+
+	return result, nil
+}
+
+func (s *Storage) RetryDelivery(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, d := range s.deliveries {
+		if d.ID == id && d.State == storage.DeliveryDead {
+			d.State = storage.DeliveryPending
+			d.Attempts = 0
+			d.UpdatedAt = time.Now()
+			d.nextAttemptAt = d.UpdatedAt
+			return nil
+		}
+	}
+
+	return storage.ErrDeliveryNotFound
+}
+
+// PurgeDeliveries удаляет события, созданные до before, все доставки
+// которых завершились успешно до before.
+func (s *Storage) PurgeDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keep := make(map[int64]bool)
+	for _, d := range s.deliveries {
+		if !d.CreatedAt.Before(before) || d.State != storage.DeliveryDelivered || !d.UpdatedAt.Before(before) {
+			keep[d.EventID] = true
+		}
+	}
+
+	purged := make(map[int64]bool)
+	s.deliveries = slices.DeleteFunc(s.deliveries, func(d *delivery) bool {
+		if keep[d.EventID] {
+			return false
+		}
+		purged[d.EventID] = true
+		return true
+	})
+
+	return int64(len(purged)), nil
+}
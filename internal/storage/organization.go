@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Organization - теги, папка (кампания) и произвольные метаданные ссылки.
+type Organization struct {
+	Tags     []string       `json:"tags"`
+	Folder   string         `json:"folder,omitempty"`
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+// OrganizationPatch - частичное изменение Organization, nil-поле не меняется.
+type OrganizationPatch struct {
+	Tags     *[]string
+	Folder   *string
+	Metadata *map[string]any
+}
+
+// LinkInfo - ссылка в списке и результатах поиска.
+type LinkInfo struct {
+	Alias string `json:"alias"`
+	URL   string `json:"url"`
+	Organization
+	Version   int64     `json:"version"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ListFilter - условия выборки ссылок, пустое поле не ограничивает выборку.
+type ListFilter struct {
+	// Tags - ссылка должна иметь все перечисленные теги.
+	Tags   []string
+	Folder string
+	// Metadata - ссылка должна содержать все пары с такими строковыми значениями.
+	Metadata map[string]string
+	// Query ищет подстроку в alias и url без учёта регистра.
+	Query  string
+	Limit  int
+	Offset int
+}
+
+// Tag - тег и число ссылок с ним.
+type Tag struct {
+	Name  string `json:"name"`
+	Links int64  `json:"links"`
+}
+
+// NormalizeTags приводит теги к нижнему регистру, убирает пробелы по краям,
+// пустые значения и повторы. Результат отсортирован, nil - если тегов нет.
+func NormalizeTags(tags []string) []string {
+	var result []string
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" {
+			result = append(result, tag)
+		}
+	}
+	slices.Sort(result)
+	return slices.Compact(result)
+}
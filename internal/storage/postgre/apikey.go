@@ -0,0 +1,94 @@
+package postgre
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/jackc/pgx/v5"
+)
+
+func (s *Storage) CreateAPIKey(ctx context.Context, key storage.APIKey) (storage.APIKey, error) {
+	const op = "storage.postgre.CreateAPIKey"
+
+	ctx, cancel := s.writeCtx(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO api_key(name, prefix, key_hash)
+		VALUES($1, $2, $3)
+		RETURNING id, created_at;
+	`
+
+	err := s.pool.QueryRow(ctx, query, key.Name, key.Prefix, key.Hash).Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		return storage.APIKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return key, nil
+}
+
+// RevokeAPIKey отзывает ключ. Повторный отзыв не меняет время отзыва.
+func (s *Storage) RevokeAPIKey(ctx context.Context, id int64) (storage.APIKey, error) {
+	const op = "storage.postgre.RevokeAPIKey"
+
+	ctx, cancel := s.writeCtx(ctx)
+	defer cancel()
+
+	query := `
+		UPDATE api_key
+		SET revoked_at = COALESCE(revoked_at, now())
+		WHERE id = $1
+		RETURNING id, name, prefix, key_hash, created_at, revoked_at;
+	`
+
+	rows, err := s.pool.Query(ctx, query, id)
+	if err != nil {
+		return storage.APIKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	key, err := pgx.CollectExactlyOneRow(rows, scanAPIKey)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return storage.APIKey{}, storage.ErrAPIKeyNotFound
+		}
+		return storage.APIKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return key, nil
+}
+
+// FindAPIKey ищет действующий ключ по хешу. Ключ читается с основной базы:
+// отозванный ключ не должен работать, пока реплика отстаёт.
+func (s *Storage) FindAPIKey(ctx context.Context, hash string) (storage.APIKey, error) {
+	const op = "storage.postgre.FindAPIKey"
+
+	ctx, cancel := s.readCtx(ctx)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, name, prefix, key_hash, created_at, revoked_at
+		FROM api_key
+		WHERE key_hash = $1 AND revoked_at IS NULL
+	`, hash)
+	if err != nil {
+		return storage.APIKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	key, err := pgx.CollectExactlyOneRow(rows, scanAPIKey)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return storage.APIKey{}, storage.ErrAPIKeyNotFound
+		}
+		return storage.APIKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return key, nil
+}
+
+func scanAPIKey(row pgx.CollectableRow) (storage.APIKey, error) {
+	var k storage.APIKey
+	err := row.Scan(&k.ID, &k.Name, &k.Prefix, &k.Hash, &k.CreatedAt, &k.RevokedAt)
+	return k, err
+}
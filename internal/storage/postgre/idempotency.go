@@ -0,0 +1,119 @@
+package postgre
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/jackc/pgx/v5"
+)
+
+// ReserveIdempotencyKey занимает ключ под выполнение запроса. Если ключ
+// свободен, истёк или брошен упавшим экземпляром после lockTimeout,
+// возвращается reserved = true. Иначе возвращается уже сохранённая запись:
+// готовый ответ или запрос, который ещё выполняется.
+func (s *Storage) ReserveIdempotencyKey(ctx context.Context, key, requestHash string, ttl, lockTimeout time.Duration) (storage.IdempotencyRecord, bool, error) {
+	const op = "storage.postgre.ReserveIdempotencyKey"
+
+	ctx, cancel := s.writeCtx(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO idempotency_key(key, request_hash, locked_until, expires_at)
+		VALUES($1, $2, now() + $4::interval, now() + $3::interval)
+		ON CONFLICT(key) DO UPDATE
+			SET request_hash = EXCLUDED.request_hash,
+				status = 0,
+				content_type = '',
+				body = NULL,
+				locked_until = EXCLUDED.locked_until,
+				expires_at = EXCLUDED.expires_at,
+				created_at = now()
+			WHERE idempotency_key.expires_at <= now()
+				OR (idempotency_key.status = 0 AND idempotency_key.locked_until <= now())
+		RETURNING expires_at;
+	`
+
+	rec := storage.IdempotencyRecord{Key: key, RequestHash: requestHash}
+
+	err := s.pool.QueryRow(ctx, query, key, requestHash, ttl, lockTimeout).Scan(&rec.ExpiresAt)
+	if err == nil {
+		return rec, true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return storage.IdempotencyRecord{}, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Ключ занят другим запросом. Если запись успели удалить между двумя
+	// запросами, считаем, что запрос ещё выполняется: клиент повторит его.
+	err = s.pool.QueryRow(ctx, `
+		SELECT request_hash, status, content_type, COALESCE(body, ''), expires_at
+		FROM idempotency_key
+		WHERE key = $1
+	`, key).Scan(&rec.RequestHash, &rec.Status, &rec.ContentType, &rec.Body, &rec.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return rec, false, nil
+		}
+		return storage.IdempotencyRecord{}, false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return rec, false, nil
+}
+
+// CompleteIdempotencyKey сохраняет ответ на запрос, занявший ключ.
+func (s *Storage) CompleteIdempotencyKey(ctx context.Context, rec storage.IdempotencyRecord) error {
+	const op = "storage.postgre.CompleteIdempotencyKey"
+
+	ctx, cancel := s.writeCtx(ctx)
+	defer cancel()
+
+	query := `
+		UPDATE idempotency_key
+		SET status = $3, content_type = $4, body = $5
+		WHERE key = $1 AND request_hash = $2 AND status = 0;
+	`
+
+	_, err := s.pool.Exec(ctx, query, rec.Key, rec.RequestHash, rec.Status, rec.ContentType, rec.Body)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ReleaseIdempotencyKey освобождает ключ, если запрос завершился ошибкой
+// сервера и его можно выполнить заново.
+func (s *Storage) ReleaseIdempotencyKey(ctx context.Context, key, requestHash string) error {
+	const op = "storage.postgre.ReleaseIdempotencyKey"
+
+	ctx, cancel := s.writeCtx(ctx)
+	defer cancel()
+
+	_, err := s.pool.Exec(ctx, `
+		DELETE FROM idempotency_key
+		WHERE key = $1 AND request_hash = $2 AND status = 0
+	`, key, requestHash)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// PurgeIdempotencyKeys удаляет истёкшие ключи и возвращает их число.
+func (s *Storage) PurgeIdempotencyKeys(ctx context.Context) (int64, error) {
+	const op = "storage.postgre.PurgeIdempotencyKeys"
+
+	ctx, cancel := s.writeCtx(ctx)
+	defer cancel()
+
+	cmdTag, err := s.pool.Exec(ctx, `DELETE FROM idempotency_key WHERE expires_at <= now()`)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return cmdTag.RowsAffected(), nil
+}
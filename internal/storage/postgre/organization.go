@@ -0,0 +1,299 @@
+package postgre
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// setTags заменяет теги ссылки, недостающие теги создаются.
+func setTags(ctx context.Context, tx pgx.Tx, urlID int64, tags []string) error {
+	const op = "storage.postgre.setTags"
+
+	if tags == nil {
+		tags = []string{}
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM url_tag WHERE url_id = $1`, urlID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(tags) == 0 {
+		return nil
+	}
+
+	_, err := tx.Exec(ctx, `
+		INSERT INTO tag(name)
+		SELECT unnest($1::text[])
+		ON CONFLICT (name) DO NOTHING
+	`, tags)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO url_tag(url_id, tag_id)
+		SELECT $1, id FROM tag WHERE name = ANY($2)
+	`, urlID, tags)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func metadataOrEmpty(metadata map[string]any) map[string]any {
+	if metadata == nil {
+		return map[string]any{}
+	}
+	return metadata
+}
+
+// SetOrganization меняет теги, папку и метаданные ссылки одной транзакцией
+// и возвращает новую версию ссылки. Если expectedVersion не 0, изменение
+// выполняется только в этой версии.
+func (s *Storage) SetOrganization(ctx context.Context, alias string, patch storage.OrganizationPatch, expectedVersion int64) (int64, error) {
+	const op = "storage.postgre.SetOrganization"
+
+	ctx, cancel := s.writeCtx(ctx)
+	defer cancel()
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	var metadata map[string]any
+	if patch.Metadata != nil {
+		metadata = metadataOrEmpty(*patch.Metadata)
+	}
+
+	query := `
+		UPDATE url
+		SET folder = COALESCE($2, folder),
+			metadata = CASE WHEN $3 THEN $4 ELSE metadata END,
+			version = version + 1
+		WHERE alias = $1 AND ($5::bigint = 0 OR version = $5)
+		RETURNING id, version;
+	`
+
+	var urlID, version int64
+	err = tx.QueryRow(ctx, query, alias, patch.Folder, patch.Metadata != nil, metadata, expectedVersion).
+		Scan(&urlID, &version)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, s.versionConflict(ctx, tx, alias)
+		}
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if patch.Tags != nil {
+		if err := setTags(ctx, tx, urlID, *patch.Tags); err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return version, nil
+}
+
+func (s *Storage) ListLinks(ctx context.Context, filter storage.ListFilter) ([]storage.LinkInfo, error) {
+	const op = "storage.postgre.ListLinks"
+
+	ctx, cancel := s.readCtx(ctx)
+	defer cancel()
+
+	query := `
+		SELECT u.alias, u.url, u.folder, u.metadata, u.version, u.updated_at,
+			COALESCE((
+				SELECT array_agg(t.name ORDER BY t.name)
+				FROM url_tag ut
+				JOIN tag t ON t.id = ut.tag_id
+				WHERE ut.url_id = u.id
+			), '{}')
+		FROM url u
+		WHERE ($1 = '' OR u.folder = $1)
+			AND u.metadata @> $2
+			AND ($3 = '' OR strpos(lower(u.alias), lower($3)) > 0 OR strpos(lower(u.url), lower($3)) > 0)
+			AND cardinality($4::text[]) = (
+				SELECT count(*)
+				FROM url_tag ut
+				JOIN tag t ON t.id = ut.tag_id
+				WHERE ut.url_id = u.id AND t.name = ANY($4)
+			)
+		ORDER BY u.id
+		LIMIT NULLIF($5, 0) OFFSET $6
+	`
+
+	metadata := make(map[string]any, len(filter.Metadata))
+	for key, value := range filter.Metadata {
+		metadata[key] = value
+	}
+
+	tags := filter.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	var links []storage.LinkInfo
+	err := s.read(ctx, func(db reader) error {
+		rows, err := db.Query(ctx, query, filter.Folder, metadata, filter.Query, tags, filter.Limit, filter.Offset)
+		if err != nil {
+			return err
+		}
+
+		links, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (storage.LinkInfo, error) {
+			var l storage.LinkInfo
+			err := row.Scan(&l.Alias, &l.URL, &l.Folder, &l.Metadata, &l.Version, &l.UpdatedAt, &l.Tags)
+			return l, err
+		})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return links, nil
+}
+
+func (s *Storage) ListTags(ctx context.Context) ([]storage.Tag, error) {
+	const op = "storage.postgre.ListTags"
+
+	ctx, cancel := s.readCtx(ctx)
+	defer cancel()
+
+	var tags []storage.Tag
+	err := s.read(ctx, func(db reader) error {
+		rows, err := db.Query(ctx, `
+			SELECT t.name, count(*)
+			FROM tag t
+			JOIN url_tag ut ON ut.tag_id = t.id
+			GROUP BY t.name
+			ORDER BY t.name
+		`)
+		if err != nil {
+			return err
+		}
+
+		tags, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (storage.Tag, error) {
+			var t storage.Tag
+			err := row.Scan(&t.Name, &t.Links)
+			return t, err
+		})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tags, nil
+}
+
+// RenameTag переименовывает тег сразу у всех ссылок.
+func (s *Storage) RenameTag(ctx context.Context, oldName, newName string) error {
+	const op = "storage.postgre.RenameTag"
+
+	ctx, cancel := s.writeCtx(ctx)
+	defer cancel()
+
+	// Переименование меняет теги ссылок, поэтому их версии увеличиваются.
+	query := `
+		WITH renamed AS (
+			UPDATE tag SET name = $2 WHERE name = $1
+			RETURNING id
+		), bumped AS (
+			UPDATE url SET version = version + 1
+			WHERE id IN (SELECT ut.url_id FROM url_tag ut JOIN renamed ON ut.tag_id = renamed.id)
+		)
+		SELECT count(*) FROM renamed;
+	`
+
+	var renamed int
+	err := s.pool.QueryRow(ctx, query, oldName, newName).Scan(&renamed)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return storage.ErrTagExists
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if renamed == 0 {
+		return storage.ErrTagNotFound
+	}
+
+	return nil
+}
+
+// MergeTags атомарно заменяет теги sources на target у всех ссылок и
+// удаляет исходные теги.
+func (s *Storage) MergeTags(ctx context.Context, sources []string, target string) error {
+	const op = "storage.postgre.MergeTags"
+
+	ctx, cancel := s.writeCtx(ctx)
+	defer cancel()
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `SELECT id FROM tag WHERE name = ANY($1) AND name <> $2 FOR UPDATE`, sources, target)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	sourceIDs, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if len(sourceIDs) == 0 {
+		return storage.ErrTagNotFound
+	}
+
+	var targetID int64
+	err = tx.QueryRow(ctx, `
+		INSERT INTO tag(name) VALUES($1)
+		ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+		RETURNING id
+	`, target).Scan(&targetID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO url_tag(url_id, tag_id)
+		SELECT DISTINCT url_id, $2 FROM url_tag WHERE tag_id = ANY($1)
+		ON CONFLICT DO NOTHING
+	`, sourceIDs, targetID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE url SET version = version + 1
+		WHERE id IN (SELECT url_id FROM url_tag WHERE tag_id = ANY($1))
+	`, sourceIDs)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM tag WHERE id = ANY($1)`, sourceIDs); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package postgre
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PoolConfig - настройки пула соединений. Нулевые значения оставляют
+// значения pgxpool по умолчанию.
+type PoolConfig struct {
+	MaxConns          int32
+	MinConns          int32
+	MaxConnLifetime   time.Duration
+	MaxConnIdleTime   time.Duration
+	HealthCheckPeriod time.Duration
+
+	// StatementCacheMode - режим выполнения запросов pgx: cache_statement,
+	// cache_describe, describe_exec, exec или simple_protocol. За PgBouncer
+	// в режиме transaction нужен exec или simple_protocol.
+	StatementCacheMode string
+
+	// ConnectAttempts - сколько раз проверять доступность базы при запуске.
+	// Пауза между попытками начинается с ConnectBackoff и удваивается, но
+	// не больше ConnectMaxBackoff.
+	ConnectAttempts   int
+	ConnectBackoff    time.Duration
+	ConnectMaxBackoff time.Duration
+}
+
+var execModes = map[string]pgx.QueryExecMode{
+	"cache_statement": pgx.QueryExecModeCacheStatement,
+	"cache_describe":  pgx.QueryExecModeCacheDescribe,
+	"describe_exec":   pgx.QueryExecModeDescribeExec,
+	"exec":            pgx.QueryExecModeExec,
+	"simple_protocol": pgx.QueryExecModeSimpleProtocol,
+}
+
+func (c PoolConfig) parse(url string) (*pgxpool.Config, error) {
+	config, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.MaxConns > 0 {
+		config.MaxConns = c.MaxConns
+	}
+	if c.MinConns > 0 {
+		config.MinConns = c.MinConns
+	}
+	if config.MinConns > config.MaxConns {
+		return nil, fmt.Errorf("min conns %d is greater than max conns %d", config.MinConns, config.MaxConns)
+	}
+	if c.MaxConnLifetime > 0 {
+		config.MaxConnLifetime = c.MaxConnLifetime
+	}
+	if c.MaxConnIdleTime > 0 {
+		config.MaxConnIdleTime = c.MaxConnIdleTime
+	}
+	if c.HealthCheckPeriod > 0 {
+		config.HealthCheckPeriod = c.HealthCheckPeriod
+	}
+
+	if c.StatementCacheMode != "" {
+		mode, ok := execModes[c.StatementCacheMode]
+		if !ok {
+			return nil, fmt.Errorf("unknown statement cache mode %q", c.StatementCacheMode)
+		}
+		config.ConnConfig.DefaultQueryExecMode = mode
+	}
+
+	return config, nil
+}
+
+// ping проверяет соединение с базой, повторяя попытки с экспоненциальной
+// паузой, пока база не ответит, не кончатся попытки или не отменят ctx.
+func ping(ctx context.Context, pool *pgxpool.Pool, c PoolConfig) error {
+	attempts := max(c.ConnectAttempts, 1)
+	backoff := c.ConnectBackoff
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = pool.Ping(ctx); err == nil {
+			return nil
+		}
+		if attempt >= attempts {
+			return fmt.Errorf("ping failed after %d attempts: %w", attempt, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("ping: %w (last error: %v)", ctx.Err(), err)
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if c.ConnectMaxBackoff > 0 && backoff > c.ConnectMaxBackoff {
+			backoff = c.ConnectMaxBackoff
+		}
+	}
+}
+
+// Stat - статистика пула соединений для метрик.
+func (s *Storage) Stat() *pgxpool.Stat {
+	return s.pool.Stat()
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/RozmiDan/url_shortener/internal/storage"
 	"github.com/jackc/pgx/v5"
@@ -12,127 +13,1038 @@ import (
 )
 
 type Storage struct {
-	pool *pgxpool.Pool
+	pool     *pgxpool.Pool
+	replicas *replicaSet
+	cfg      Config
 }
 
-func New(DBurl string) (*Storage, error) {
+// Config - параметры подключения к Postgres.
+type Config struct {
+	URL string
+	// ReadTimeout и WriteTimeout ограничивают время одной операции чтения
+	// и изменения данных, 0 - без ограничения. Переход по ссылке считается
+	// чтением, хотя и уменьшает счётчик переходов.
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+
+	Pool PoolConfig
+	// Replicas - реплики для чтения ссылок и списков. Изменения всегда
+	// идут на основную базу.
+	Replicas ReplicaConfig
+}
+
+// New открывает пул соединений и проверяет, что база доступна. Пока база
+// не отвечает, попытки повторяются согласно cfg.Pool, поэтому неверный
+// адрес или недоступная база обнаруживаются при запуске, а не на первом
+// запросе.
+func New(ctx context.Context, cfg Config) (*Storage, error) {
 	const op = "storage.postgre.New"
 
-	// Создаем конфигурацию пула
-	config, err := pgxpool.ParseConfig(DBurl)
+	config, err := cfg.Pool.parse(cfg.URL)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	config.MaxConns = 50
-	config.MinConns = 10
-
-	pool, err := pgxpool.NewWithConfig(context.Background(), config)
+	pool, err := pgxpool.NewWithConfig(ctx, config)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return &Storage{pool: pool}, nil
+	if err := ping(ctx, pool, cfg.Pool); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	st := &Storage{pool: pool, cfg: cfg}
+
+	if len(cfg.Replicas.URLs) > 0 {
+		st.replicas, err = newReplicaSet(ctx, cfg.Replicas, cfg.Pool, pool)
+		if err != nil {
+			pool.Close()
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return st, nil
 }
 
-func (s *Storage) SaveURL(ctx context.Context, urlToSave string, alias string) (int64, error) {
+func (s *Storage) readCtx(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, s.cfg.ReadTimeout)
+}
+
+func (s *Storage) writeCtx(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, s.cfg.WriteTimeout)
+}
+
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// insertURL - вставка ссылки, к которой saveURL добавляет ON CONFLICT.
+const insertURL = `
+		INSERT INTO url(alias, url, max_clicks, clicks_left, active_from, active_until,
+			query_mode, utm, forward_path, title, description, image_url, preview,
+			folder, metadata, redirect_code)
+		VALUES($1, $2, NULLIF($3, 0), NULLIF($3, 0), $4, $5,
+			COALESCE(NULLIF($6, ''), 'drop'), $7, $8, $9, $10, $11, $12,
+			$13, $14, $15)`
+
+// SaveURL сохраняет ссылку, заменяя существующую с тем же alias.
+func (s *Storage) SaveURL(ctx context.Context, urlToSave string, alias string, opts storage.URLOptions) (int64, error) {
 	const op = "storage.postgre.SaveURL"
 
-	query := `
-		INSERT INTO url(alias, url)
-		VALUES($1, $2)
+	id, err := s.saveURL(ctx, urlToSave, alias, opts, true)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return id, nil
+}
+
+// CreateURL сохраняет новую ссылку, занятый alias - storage.ErrAliasExists.
+func (s *Storage) CreateURL(ctx context.Context, urlToSave string, alias string, opts storage.URLOptions) (int64, error) {
+	const op = "storage.postgre.CreateURL"
+
+	id, err := s.saveURL(ctx, urlToSave, alias, opts, false)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return id, nil
+}
+
+func (s *Storage) saveURL(ctx context.Context, urlToSave string, alias string, opts storage.URLOptions, overwrite bool) (int64, error) {
+	ctx, cancel := s.writeCtx(ctx)
+	defer cancel()
+
+	query := insertURL + `
+		ON CONFLICT(alias) DO NOTHING
+		RETURNING id, true;
+	`
+	if overwrite {
+		query = insertURL + `
 		ON CONFLICT(alias) DO UPDATE
-			SET url = EXCLUDED.url
-		RETURNING id;
+			SET url = EXCLUDED.url,
+				max_clicks = EXCLUDED.max_clicks,
+				clicks_left = EXCLUDED.clicks_left,
+				active_from = EXCLUDED.active_from,
+				active_until = EXCLUDED.active_until,
+				query_mode = EXCLUDED.query_mode,
+				utm = EXCLUDED.utm,
+				forward_path = EXCLUDED.forward_path,
+				title = EXCLUDED.title,
+				description = EXCLUDED.description,
+				image_url = EXCLUDED.image_url,
+				preview = EXCLUDED.preview,
+				folder = EXCLUDED.folder,
+				metadata = EXCLUDED.metadata,
+				redirect_code = EXCLUDED.redirect_code,
+				checked_at = NULL,
+				version = url.version + 1
+		RETURNING id, xmax = 0;
 	`
+	}
 
-	var id int64
-	err := s.pool.QueryRow(ctx, query, alias, urlToSave).Scan(&id)
+	tx, err := s.pool.Begin(ctx)
 	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	// xmax = 0 только у только что вставленной строки, так отличаем
+	// создание ссылки от перезаписи существующей.
+	var (
+		id       int64
+		inserted bool
+	)
+	err = tx.QueryRow(ctx, query, alias, urlToSave, opts.MaxClicks,
+		opts.ActiveFrom, opts.ActiveUntil,
+		opts.Forward.QueryMode, opts.Forward.UTM, opts.Forward.ForwardPath,
+		opts.Meta.Title, opts.Meta.Description, opts.Meta.ImageURL, opts.Preview,
+		opts.Organization.Folder, metadataOrEmpty(opts.Organization.Metadata), opts.RedirectCode).Scan(&id, &inserted)
+	if err != nil {
+		// Без перезаписи занятый alias не возвращает строку.
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, storage.ErrAliasExists
+		}
 		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) {
-			// TODO
-			if pgErr.Code == "23505" {
-				return 0, storage.ErrAliasExists
-			}
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return 0, storage.ErrAliasExists
 		}
-		return 0, fmt.Errorf("%s: %w", op, err)
+		return 0, err
+	}
+
+	// Варианты A/B-теста перезаписываются вместе со ссылкой.
+	if _, err := tx.Exec(ctx, `DELETE FROM url_variant WHERE url_id = $1`, id); err != nil {
+		return 0, err
+	}
+
+	for _, v := range opts.Variants {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO url_variant(url_id, target_url, weight)
+			VALUES($1, $2, $3)
+		`, id, v.URL, v.Weight)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err := setTags(ctx, tx, id, opts.Organization.Tags); err != nil {
+		return 0, err
+	}
+
+	if inserted {
+		event := storage.LinkEvent{Alias: alias, URL: urlToSave}
+		if err := enqueueEvent(ctx, tx, storage.EventLinkCreated, event); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
 	}
 
 	return id, nil
 }
 
-func (s *Storage) GetURL(alias string) (string, error) {
+// linkTargets - правила и варианты ссылки из CTE link в виде JSON.
+const linkTargets = `COALESCE((
+				SELECT json_agg(json_build_object(
+					'id', r.id,
+					'conditions', r.conditions,
+					'target_url', r.target_url
+				) ORDER BY r.position, r.id)
+				FROM url_rule r
+				WHERE r.url_id = link.id
+			), '[]'),
+			COALESCE((
+				SELECT json_agg(json_build_object(
+					'id', v.id,
+					'url', v.target_url,
+					'weight', v.weight
+				) ORDER BY v.id)
+				FROM url_variant v
+				WHERE v.url_id = link.id
+			), '[]')`
+
+// errNeedsPrimary - переход по ссылке меняет данные, и реплика его
+// обслужить не может.
+var errNeedsPrimary = errors.New("link requires primary")
+
+// readMode - как чтение ссылки учитывает переход.
+type readMode int
+
+const (
+	// readClick засчитывает переход. Ссылка с флагом preview отдаёт
+	// страницу предпросмотра, а не переход, и не засчитывается.
+	readClick readMode = iota
+	// readFollow засчитывает переход со страницы предпросмотра.
+	readFollow
+	// readPeek не засчитывает переход.
+	readPeek
+)
+
+func (s *Storage) GetURL(ctx context.Context, alias string) (storage.Link, error) {
 	const op = "storage.postgre.GetURL"
 
+	link, err := s.getURL(ctx, alias, readClick)
+	if err != nil {
+		return storage.Link{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return link, nil
+}
+
+// FollowURL засчитывает переход, как GetURL, в том числе по ссылке с
+// флагом preview: так считается кнопка "Continue" страницы предпросмотра.
+func (s *Storage) FollowURL(ctx context.Context, alias string) (storage.Link, error) {
+	const op = "storage.postgre.FollowURL"
+
+	link, err := s.getURL(ctx, alias, readFollow)
+	if err != nil {
+		return storage.Link{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return link, nil
+}
+
+func (s *Storage) getURL(ctx context.Context, alias string, mode readMode) (storage.Link, error) {
+	ctx, cancel := s.readCtx(ctx)
+	defer cancel()
+
+	if r := s.clickReplica(ctx); r != nil {
+		link, err := getURLReadOnly(ctx, r.pool, alias, mode)
+		switch {
+		case err == nil, errors.Is(err, storage.ErrURLNotActive), errors.Is(err, storage.ErrURLExpired):
+			return link, err
+		case ctx.Err() != nil:
+			return storage.Link{}, err
+		case errors.Is(err, errNeedsPrimary), errors.Is(err, storage.ErrURLNotFound):
+			// Ссылка с лимитом переходов, с подписчиками на переходы или ещё
+			// не дошедшая до реплики - обрабатывает основная база.
+		case connectionError(err):
+			r.healthy.Store(false)
+		}
+	}
+
+	// Счётчик уменьшается только у активных ссылок с лимитом переходов.
+	// UPDATE перепроверяет clicks_left > 0 на актуальной версии строки,
+	// поэтому параллельные переходы не могут превысить лимит.
+	// Событие перехода пишется в outbox тем же запросом и только если
+	// на него кто-то подписан. Ссылка с флагом preview засчитывается
+	// только при переходе со страницы предпросмотра ($2).
+	query := `
+		WITH link AS (
+			SELECT id, url, clicks_left, query_mode, utm, forward_path,
+				title, description, image_url, preview, redirect_code,
+				active_from IS NOT NULL AND active_from > now() AS pending,
+				active_until IS NOT NULL AND active_until <= now() AS expired,
+				NOT preview OR $2 AS counted
+			FROM url
+			WHERE alias = $1
+		), spent AS (
+			UPDATE url SET clicks_left = url.clicks_left - 1
+			FROM link
+			WHERE url.id = link.id AND url.clicks_left > 0
+				AND NOT link.pending AND NOT link.expired AND link.counted
+			RETURNING url.id
+		), click_event AS (
+			INSERT INTO webhook_outbox(event_type, payload)
+			SELECT 'link.clicked', json_build_object('alias', $1::text, 'url', link.url)
+			FROM link
+			WHERE NOT link.pending AND NOT link.expired AND link.counted
+				AND (link.clicks_left IS NULL OR EXISTS (SELECT 1 FROM spent))
+				AND EXISTS (
+					SELECT 1 FROM webhook w
+					WHERE w.active AND (cardinality(w.events) = 0 OR 'link.clicked' = ANY(w.events))
+				)
+			RETURNING id
+		), click_delivery AS (
+			INSERT INTO webhook_delivery(webhook_id, event_id)
+			SELECT w.id, click_event.id
+			FROM click_event, webhook w
+			WHERE w.active AND (cardinality(w.events) = 0 OR 'link.clicked' = ANY(w.events))
+		)
+		SELECT link.url, link.query_mode, link.utm, link.forward_path,
+			link.title, link.description, link.image_url, link.preview, link.redirect_code,
+			link.pending, link.expired,
+			link.clicks_left IS NULL OR (NOT link.counted AND link.clicks_left > 0)
+				OR EXISTS (SELECT 1 FROM spent),
+			` + linkTargets + `
+		FROM link
+	`
+	var (
+		result           storage.Link
+		pending, expired bool
+		allowed          bool
+	)
+
+	err := s.pool.QueryRow(ctx, query, alias, mode == readFollow).
+		Scan(&result.URL, &result.Forward.QueryMode, &result.Forward.UTM, &result.Forward.ForwardPath,
+			&result.Meta.Title, &result.Meta.Description, &result.Meta.ImageURL, &result.Preview, &result.RedirectCode,
+			&pending, &expired, &allowed, &result.Rules, &result.Variants)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return storage.Link{}, storage.ErrURLNotFound
+		}
+		return storage.Link{}, err
+	}
+
+	if pending {
+		return storage.Link{}, storage.ErrURLNotActive
+	}
+
+	if expired {
+		return storage.Link{}, storage.ErrURLExpired
+	}
+
+	if !allowed {
+		return storage.Link{}, storage.ErrURLExhausted
+	}
+
+	return result, nil
+}
+
+// PeekURL возвращает ссылку, как GetURL, но не засчитывает переход: для
+// страницы предпросмотра счётчик и события вебхуков не меняются.
+func (s *Storage) PeekURL(ctx context.Context, alias string) (storage.Link, error) {
+	const op = "storage.postgre.PeekURL"
+
+	ctx, cancel := s.readCtx(ctx)
+	defer cancel()
+
+	if r := s.replica(ctx); r != nil {
+		link, err := getURLReadOnly(ctx, r.pool, alias, readPeek)
+		switch {
+		case err == nil, errors.Is(err, storage.ErrURLNotActive), errors.Is(err, storage.ErrURLExpired),
+			errors.Is(err, storage.ErrURLExhausted):
+			return link, err
+		case ctx.Err() != nil:
+			return storage.Link{}, fmt.Errorf("%s: %w", op, err)
+		case connectionError(err):
+			r.healthy.Store(false)
+		}
+	}
+
+	return getURLReadOnly(ctx, s.pool, alias, readPeek)
+}
+
+// getURLReadOnly читает ссылку без изменений в базе, поэтому подходит для
+// реплики. Если переход должен уменьшить счётчик или записать событие для
+// вебхуков, возвращается errNeedsPrimary. С readPeek переход не
+// засчитывается, и ссылка проверяется только на исчерпанный лимит.
+func getURLReadOnly(ctx context.Context, db reader, alias string, mode readMode) (storage.Link, error) {
+	const op = "storage.postgre.getURLReadOnly"
+
 	query := `
-		SELECT url FROM url 
-		WHERE alias = $1
+		WITH link AS (
+			SELECT id, url, query_mode, utm, forward_path,
+				title, description, image_url, preview, redirect_code,
+				active_from IS NOT NULL AND active_from > now() AS pending,
+				active_until IS NOT NULL AND active_until <= now() AS expired,
+				clicks_left IS NOT NULL AND clicks_left <= 0 AS exhausted,
+				(NOT preview OR $2) AND (clicks_left IS NOT NULL OR EXISTS (
+					SELECT 1 FROM webhook w
+					WHERE w.active AND (cardinality(w.events) = 0 OR 'link.clicked' = ANY(w.events))
+				)) AS needs_write
+			FROM url
+			WHERE alias = $1
+		)
+		SELECT link.url, link.query_mode, link.utm, link.forward_path,
+			link.title, link.description, link.image_url, link.preview, link.redirect_code,
+			link.pending, link.expired, link.exhausted, link.needs_write,
+			` + linkTargets + `
+		FROM link
 	`
-	var result string
+	var (
+		result                      storage.Link
+		pending, expired, exhausted bool
+		needsWrite                  bool
+	)
 
-	err := s.pool.QueryRow(context.Background(), query, alias).Scan(&result)
+	err := db.QueryRow(ctx, query, alias, mode == readFollow).
+		Scan(&result.URL, &result.Forward.QueryMode, &result.Forward.UTM, &result.Forward.ForwardPath,
+			&result.Meta.Title, &result.Meta.Description, &result.Meta.ImageURL, &result.Preview, &result.RedirectCode,
+			&pending, &expired, &exhausted, &needsWrite, &result.Rules, &result.Variants)
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return "", storage.ErrURLNotFound
+			return storage.Link{}, storage.ErrURLNotFound
 		}
-		return "", fmt.Errorf("%s: %w", op, err)
+		return storage.Link{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	switch {
+	case pending:
+		return storage.Link{}, storage.ErrURLNotActive
+	case expired:
+		return storage.Link{}, storage.ErrURLExpired
+	case needsWrite && mode != readPeek:
+		return storage.Link{}, errNeedsPrimary
+	case exhausted:
+		return storage.Link{}, storage.ErrURLExhausted
 	}
 
 	return result, nil
 }
 
-func (s *Storage) DeleteURL(alias string) error {
+// DeleteURL удаляет ссылку. Если expectedVersion не 0, ссылка удаляется
+// только в этой версии, иначе возвращается ErrVersionConflict.
+func (s *Storage) DeleteURL(ctx context.Context, alias string, expectedVersion int64) error {
 	const op = "storage.postgre.DeleteURL"
 
+	ctx, cancel := s.writeCtx(ctx)
+	defer cancel()
+
 	query := `
 		DELETE FROM url
-		WHERE alias = $1;
+		WHERE alias = $1 AND ($2::bigint = 0 OR version = $2)
+		RETURNING url;
 	`
 
-	cmdTag, err := s.pool.Exec(context.Background(), query, alias)
+	tx, err := s.pool.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
+	defer tx.Rollback(ctx)
 
-	if cmdTag.RowsAffected() == 0 {
-		return storage.ErrAliasNotFound
+	var deletedURL string
+	if err := tx.QueryRow(ctx, query, alias, expectedVersion).Scan(&deletedURL); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return s.versionConflict(ctx, tx, alias)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	event := storage.LinkEvent{Alias: alias, URL: deletedURL}
+	if err := enqueueEvent(ctx, tx, storage.EventLinkDeleted, event); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
 	}
 
 	return nil
 }
 
-func (s *Storage) UpdateURL(currAlias string, newAlias string) error {
-	const op = "storage.postgre.UpdateURL"
+// GetLinkState возвращает изменяемые поля ссылки для частичного обновления.
+func (s *Storage) GetLinkState(ctx context.Context, alias string) (storage.LinkState, error) {
+	const op = "storage.postgre.GetLinkState"
+
+	ctx, cancel := s.readCtx(ctx)
+	defer cancel()
+
+	query := `
+		SELECT u.alias, u.url, COALESCE(u.max_clicks, 0), u.active_from, u.active_until,
+			u.query_mode, u.utm, u.forward_path, u.title, u.description, u.image_url, u.preview,
+			u.folder, u.metadata, u.redirect_code, u.version, u.updated_at,
+			COALESCE((
+				SELECT array_agg(t.name ORDER BY t.name)
+				FROM url_tag ut
+				JOIN tag t ON t.id = ut.tag_id
+				WHERE ut.url_id = u.id
+			), '{}')
+		FROM url u
+		WHERE u.alias = $1
+	`
+
+	var st storage.LinkState
+	opts := &st.Options
+	err := s.pool.QueryRow(ctx, query, alias).Scan(&st.Alias, &st.URL, &opts.MaxClicks,
+		&opts.ActiveFrom, &opts.ActiveUntil,
+		&opts.Forward.QueryMode, &opts.Forward.UTM, &opts.Forward.ForwardPath,
+		&opts.Meta.Title, &opts.Meta.Description, &opts.Meta.ImageURL, &opts.Preview,
+		&opts.Organization.Folder, &opts.Organization.Metadata, &opts.RedirectCode, &st.Version, &st.UpdatedAt,
+		&opts.Organization.Tags)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return storage.LinkState{}, storage.ErrAliasNotFound
+		}
+		return storage.LinkState{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return st, nil
+}
+
+// UpdateLink одной транзакцией заменяет изменяемые поля ссылки alias на
+// значения из state. Если expectedVersion не 0, обновление выполняется
+// только в этой версии, иначе возвращается ErrVersionConflict.
+// Смена лимита переходов сбрасывает счётчик, смена адреса - результат
+// проверки доступности.
+func (s *Storage) UpdateLink(ctx context.Context, alias string, state storage.LinkState, expectedVersion int64) (storage.LinkState, error) {
+	const op = "storage.postgre.UpdateLink"
+
+	ctx, cancel := s.writeCtx(ctx)
+	defer cancel()
 
 	query := `
-		UPDATE url 
-		SET alias = $1
-		WHERE alias = $2;
+		UPDATE url
+		SET alias = $2, url = $3,
+			clicks_left = CASE WHEN max_clicks IS DISTINCT FROM NULLIF($4, 0)
+				THEN NULLIF($4, 0) ELSE clicks_left END,
+			max_clicks = NULLIF($4, 0),
+			active_from = $5, active_until = $6,
+			query_mode = COALESCE(NULLIF($7, ''), 'drop'), utm = $8, forward_path = $9,
+			title = $10, description = $11, image_url = $12, preview = $13,
+			folder = $14, metadata = $15, redirect_code = $16,
+			checked_at = CASE WHEN url = $3 THEN checked_at END,
+			version = version + 1
+		WHERE alias = $1 AND ($17::bigint = 0 OR version = $17)
+		RETURNING id, version, updated_at;
 	`
 
-	cmdTag, err := s.pool.Exec(context.Background(), query, newAlias, currAlias)
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return storage.LinkState{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	opts := state.Options
+
+	var urlID int64
+	err = tx.QueryRow(ctx, query, alias, state.Alias, state.URL, opts.MaxClicks,
+		opts.ActiveFrom, opts.ActiveUntil,
+		opts.Forward.QueryMode, opts.Forward.UTM, opts.Forward.ForwardPath,
+		opts.Meta.Title, opts.Meta.Description, opts.Meta.ImageURL, opts.Preview,
+		opts.Organization.Folder, metadataOrEmpty(opts.Organization.Metadata), opts.RedirectCode,
+		expectedVersion).Scan(&urlID, &state.Version, &state.UpdatedAt)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return storage.LinkState{}, s.versionConflict(ctx, tx, alias)
+		}
+
 		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return storage.LinkState{}, storage.ErrAliasExists
+		}
+
+		return storage.LinkState{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := setTags(ctx, tx, urlID, opts.Organization.Tags); err != nil {
+		return storage.LinkState{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if state.Alias != alias {
+		event := storage.LinkEvent{Alias: state.Alias, URL: state.URL, OldAlias: alias}
+		if err := enqueueEvent(ctx, tx, storage.EventLinkRenamed, event); err != nil {
+			return storage.LinkState{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return storage.LinkState{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return state, nil
+}
+
+// ListRules возвращает правила ссылки по порядку и версию ссылки, которой
+// они соответствуют.
+func (s *Storage) ListRules(ctx context.Context, alias string) ([]storage.Rule, int64, error) {
+	const op = "storage.postgre.ListRules"
 
-		if errors.As(err, &pgErr) {
-			if pgErr.Code == "23505" {
-				return storage.ErrAliasExists
+	ctx, cancel := s.readCtx(ctx)
+	defer cancel()
+
+	// Правила и версия читаются одним запросом, чтобы ETag списка
+	// соответствовал именно этим правилам.
+	query := `
+		SELECT u.version, COALESCE((
+			SELECT json_agg(json_build_object(
+				'id', r.id,
+				'conditions', r.conditions,
+				'target_url', r.target_url
+			) ORDER BY r.position, r.id)
+			FROM url_rule r
+			WHERE r.url_id = u.id
+		), '[]')
+		FROM url u
+		WHERE u.alias = $1
+	`
+
+	var (
+		rules   []storage.Rule
+		version int64
+	)
+	err := s.read(ctx, func(db reader) error {
+		err := db.QueryRow(ctx, query, alias).Scan(&version, &rules)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return storage.ErrAliasNotFound
 			}
+			return fmt.Errorf("%s: %w", op, err)
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
 
-		return fmt.Errorf("%s: %w", op, err)
+	return rules, version, nil
+}
+
+// bumpVersion увеличивает версию ссылки при изменении её правил и
+// блокирует строку до конца транзакции. Если $2 не 0, версия ссылки должна
+// ему совпадать.
+const bumpVersion = `
+	UPDATE url SET version = version + 1
+	WHERE alias = $1 AND ($2::bigint = 0 OR version = $2)
+	RETURNING id, version
+`
+
+// lockRules увеличивает версию ссылки в транзакции tx и возвращает её id и
+// новую версию. Ссылки нет - ErrAliasNotFound, другая версия -
+// ErrVersionConflict.
+func (s *Storage) lockRules(ctx context.Context, tx pgx.Tx, alias string, expectedVersion int64) (int64, int64, error) {
+	var urlID, version int64
+	err := tx.QueryRow(ctx, bumpVersion, alias, expectedVersion).Scan(&urlID, &version)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, 0, s.versionConflict(ctx, tx, alias)
+		}
+		return 0, 0, err
+	}
+	return urlID, version, nil
+}
+
+// AddRule добавляет правило в конец списка и возвращает его вместе с новой
+// версией ссылки. Увеличение версии блокирует строку ссылки до вычисления
+// позиции, поэтому параллельные добавления не получают одну и ту же позицию.
+func (s *Storage) AddRule(ctx context.Context, alias string, rule storage.Rule, expectedVersion int64) (storage.Rule, int64, error) {
+	const op = "storage.postgre.AddRule"
+
+	ctx, cancel := s.writeCtx(ctx)
+	defer cancel()
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return storage.Rule{}, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	urlID, version, err := s.lockRules(ctx, tx, alias, expectedVersion)
+	if err != nil {
+		return storage.Rule{}, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	query := `
+		INSERT INTO url_rule(url_id, position, conditions, target_url)
+		SELECT $1, COALESCE(max(position) + 1, 0), $2, $3
+		FROM url_rule
+		WHERE url_id = $1
+		RETURNING id;
+	`
+
+	err = tx.QueryRow(ctx, query, urlID, rule.Conditions, rule.TargetURL).Scan(&rule.ID)
+	if err != nil {
+		return storage.Rule{}, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return storage.Rule{}, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return rule, version, nil
+}
+
+// UpdateRule меняет условия и адрес правила и возвращает новую версию ссылки.
+func (s *Storage) UpdateRule(ctx context.Context, alias string, rule storage.Rule, expectedVersion int64) (int64, error) {
+	const op = "storage.postgre.UpdateRule"
+
+	ctx, cancel := s.writeCtx(ctx)
+	defer cancel()
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	urlID, version, err := s.lockRules(ctx, tx, alias, expectedVersion)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	cmdTag, err := tx.Exec(ctx,
+		`UPDATE url_rule SET conditions = $3, target_url = $4 WHERE url_id = $1 AND id = $2`,
+		urlID, rule.ID, rule.Conditions, rule.TargetURL)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return 0, storage.ErrRuleNotFound
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return version, nil
+}
+
+// DeleteRule удаляет правило и возвращает новую версию ссылки.
+func (s *Storage) DeleteRule(ctx context.Context, alias string, ruleID int64, expectedVersion int64) (int64, error) {
+	const op = "storage.postgre.DeleteRule"
+
+	ctx, cancel := s.writeCtx(ctx)
+	defer cancel()
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	urlID, version, err := s.lockRules(ctx, tx, alias, expectedVersion)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	cmdTag, err := tx.Exec(ctx, `DELETE FROM url_rule WHERE url_id = $1 AND id = $2`, urlID, ruleID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 
 	if cmdTag.RowsAffected() == 0 {
+		return 0, storage.ErrRuleNotFound
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return version, nil
+}
+
+// ReplaceRules атомарно заменяет все правила ссылки новым упорядоченным
+// списком и возвращает новую версию ссылки.
+func (s *Storage) ReplaceRules(ctx context.Context, alias string, rules []storage.Rule, expectedVersion int64) ([]storage.Rule, int64, error) {
+	const op = "storage.postgre.ReplaceRules"
+
+	ctx, cancel := s.writeCtx(ctx)
+	defer cancel()
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback(ctx)
+
+	urlID, version, err := s.lockRules(ctx, tx, alias, expectedVersion)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM url_rule WHERE url_id = $1`, urlID); err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	insertQuery := `
+		INSERT INTO url_rule(url_id, position, conditions, target_url)
+		VALUES($1, $2, $3, $4)
+		RETURNING id;
+	`
+
+	result := make([]storage.Rule, len(rules))
+	for i, rule := range rules {
+		err := tx.QueryRow(ctx, insertQuery, urlID, i, rule.Conditions, rule.TargetURL).Scan(&rule.ID)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s: %w", op, err)
+		}
+		result[i] = rule
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return result, version, nil
+}
+
+func (s *Storage) ListVariants(ctx context.Context, alias string) ([]storage.Variant, error) {
+	const op = "storage.postgre.ListVariants"
+
+	ctx, cancel := s.readCtx(ctx)
+	defer cancel()
+
+	query := `
+		SELECT v.id, v.target_url, v.weight, v.clicks
+		FROM url u
+		JOIN url_variant v ON v.url_id = u.id
+		WHERE u.alias = $1
+		ORDER BY v.id
+	`
+
+	var variants []storage.Variant
+	err := s.read(ctx, func(db reader) error {
+		rows, err := db.Query(ctx, query, alias)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		variants, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (storage.Variant, error) {
+			var v storage.Variant
+			err := row.Scan(&v.ID, &v.URL, &v.Weight, &v.Clicks)
+			return v, err
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		if len(variants) == 0 {
+			return s.aliasExists(ctx, db, alias)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return variants, nil
+}
+
+func (s *Storage) RecordVariantServed(ctx context.Context, variantID int64) error {
+	const op = "storage.postgre.RecordVariantServed"
+
+	ctx, cancel := s.writeCtx(ctx)
+	defer cancel()
+
+	_, err := s.pool.Exec(ctx,
+		`UPDATE url_variant SET clicks = clicks + 1 WHERE id = $1`, variantID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ClaimLinksToCheck забирает до limit ссылок, не проверявшихся с
+// checkedBefore, и закрепляет их за вызывающим на lease: другие экземпляры
+// сервиса эти ссылки не возьмут, пока не истечёт lease или не сохранится
+// результат проверки.
+func (s *Storage) ClaimLinksToCheck(ctx context.Context, checkedBefore time.Time, limit int, lease time.Duration) ([]storage.CheckTarget, error) {
+	const op = "storage.postgre.ClaimLinksToCheck"
+
+	ctx, cancel := s.writeCtx(ctx)
+	defer cancel()
+
+	query := `
+		WITH due AS (
+			SELECT id FROM url
+			WHERE (checked_at IS NULL OR checked_at < $1)
+				AND (checking_until IS NULL OR checking_until <= now())
+			ORDER BY checked_at NULLS FIRST, id
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE url SET checking_until = now() + $3::interval
+		FROM due
+		WHERE url.id = due.id
+		RETURNING url.id, url.url
+	`
+
+	rows, err := s.pool.Query(ctx, query, checkedBefore, limit, lease)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	targets, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (storage.CheckTarget, error) {
+		var t storage.CheckTarget
+		err := row.Scan(&t.ID, &t.URL)
+		return t, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return targets, nil
+}
+
+func (s *Storage) SaveCheckResult(ctx context.Context, res storage.CheckResult) error {
+	const op = "storage.postgre.SaveCheckResult"
+
+	ctx, cancel := s.writeCtx(ctx)
+	defer cancel()
+
+	query := `
+		UPDATE url
+		SET last_status = $2, last_latency_ms = $3, last_error = $4, checked_at = $5,
+			checking_until = NULL
+		WHERE id = $1;
+	`
+
+	_, err := s.pool.Exec(ctx, query, res.ID, res.Status, res.Latency.Milliseconds(), res.Error, res.CheckedAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) ListBroken(ctx context.Context) ([]storage.BrokenLink, error) {
+	const op = "storage.postgre.ListBroken"
+
+	ctx, cancel := s.readCtx(ctx)
+	defer cancel()
+
+	query := `
+		SELECT alias, url, COALESCE(last_status, 0), last_error,
+			COALESCE(last_latency_ms, 0), checked_at
+		FROM url
+		WHERE checked_at IS NOT NULL AND (last_error <> '' OR last_status >= 400)
+		ORDER BY checked_at DESC
+	`
+
+	var links []storage.BrokenLink
+	err := s.read(ctx, func(db reader) error {
+		rows, err := db.Query(ctx, query)
+		if err != nil {
+			return err
+		}
+
+		links, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (storage.BrokenLink, error) {
+			var l storage.BrokenLink
+			err := row.Scan(&l.Alias, &l.URL, &l.LastStatus, &l.LastError, &l.LatencyMs, &l.CheckedAt)
+			return l, err
+		})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return links, nil
+}
+
+func (s *Storage) CountBroken(ctx context.Context) (int, error) {
+	const op = "storage.postgre.CountBroken"
+
+	ctx, cancel := s.readCtx(ctx)
+	defer cancel()
+
+	query := `
+		SELECT count(*) FROM url
+		WHERE checked_at IS NOT NULL AND (last_error <> '' OR last_status >= 400)
+	`
+
+	var count int
+	err := s.read(ctx, func(db reader) error {
+		return db.QueryRow(ctx, query).Scan(&count)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return count, nil
+}
+
+type querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func (s *Storage) aliasExists(ctx context.Context, q querier, alias string) error {
+	const op = "storage.postgre.aliasExists"
+
+	var exists bool
+	err := q.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM url WHERE alias = $1)`, alias).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !exists {
 		return storage.ErrAliasNotFound
 	}
 
 	return nil
 }
 
+// versionConflict объясняет, почему условное изменение ссылки не затронуло
+// ни одной строки: ссылки нет (ErrAliasNotFound) или у неё другая версия
+// (ErrVersionConflict).
+func (s *Storage) versionConflict(ctx context.Context, q querier, alias string) error {
+	if err := s.aliasExists(ctx, q, alias); err != nil {
+		return err
+	}
+	return storage.ErrVersionConflict
+}
+
+// Ping проверяет соединение с основной базой.
+func (s *Storage) Ping(ctx context.Context) error {
+	const op = "storage.postgre.Ping"
+
+	if err := s.pool.Ping(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
 func (s *Storage) Close() {
+	if s.replicas != nil {
+		s.replicas.close()
+	}
 	if s.pool != nil {
 		s.pool.Close()
 	}
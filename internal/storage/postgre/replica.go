@@ -0,0 +1,248 @@
+package postgre
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ReplicaConfig - реплики только для чтения.
+type ReplicaConfig struct {
+	URLs []string
+	// HealthCheckPeriod - как часто проверять недоступные реплики и
+	// доступность исправных.
+	HealthCheckPeriod time.Duration
+}
+
+type reader interface {
+	querier
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+type replica struct {
+	pool    *pgxpool.Pool
+	healthy atomic.Bool
+}
+
+// replicaSet раздаёт чтения исправным репликам по кругу.
+type replicaSet struct {
+	replicas []*replica
+	next     atomic.Uint64
+
+	// primary - основная база, по которой проверяются подписки на переходы.
+	primary querier
+	// clicksWatched - есть подписки на переходы: тогда каждый переход
+	// пишет событие, и переходы сразу идут на основную базу.
+	clicksWatched atomic.Bool
+
+	stop context.CancelFunc
+	wg   sync.WaitGroup
+}
+
+func newReplicaSet(ctx context.Context, cfg ReplicaConfig, pool PoolConfig, primary querier) (*replicaSet, error) {
+	rs := &replicaSet{primary: primary}
+
+	for i, url := range cfg.URLs {
+		config, err := pool.parse(url)
+		if err != nil {
+			rs.close()
+			return nil, fmt.Errorf("replica %d: %w", i, err)
+		}
+
+		p, err := pgxpool.NewWithConfig(ctx, config)
+		if err != nil {
+			rs.close()
+			return nil, fmt.Errorf("replica %d: %w", i, err)
+		}
+
+		// Недоступная при запуске реплика не мешает работе: чтения идут на
+		// остальные реплики или на основную базу, пока она не поднимется.
+		r := &replica{pool: p}
+		r.healthy.Store(p.Ping(ctx) == nil)
+		rs.replicas = append(rs.replicas, r)
+	}
+
+	period := cfg.HealthCheckPeriod
+	if period <= 0 {
+		period = 5 * time.Second
+	}
+
+	rs.watchClicks(ctx)
+
+	checkCtx, stop := context.WithCancel(context.Background())
+	rs.stop = stop
+	rs.wg.Add(1)
+	go rs.healthCheck(checkCtx, period)
+
+	return rs, nil
+}
+
+// pick возвращает следующую исправную реплику или nil, если исправных нет.
+func (rs *replicaSet) pick() *replica {
+	if rs == nil {
+		return nil
+	}
+
+	// Очередь считается только по исправным репликам, иначе реплика,
+	// стоящая за неисправной, получала бы и её долю чтений.
+	var healthy uint64
+	for _, r := range rs.replicas {
+		if r.healthy.Load() {
+			healthy++
+		}
+	}
+	if healthy == 0 {
+		return nil
+	}
+
+	n := rs.next.Add(1) % healthy
+	for _, r := range rs.replicas {
+		if !r.healthy.Load() {
+			continue
+		}
+		if n == 0 {
+			return r
+		}
+		n--
+	}
+	// Реплика успела стать неисправной между проходами.
+	return nil
+}
+
+func (rs *replicaSet) healthCheck(ctx context.Context, period time.Duration) {
+	defer rs.wg.Done()
+
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, r := range rs.replicas {
+			pingCtx, cancel := context.WithTimeout(ctx, period)
+			r.healthy.Store(r.pool.Ping(pingCtx) == nil)
+			cancel()
+		}
+
+		watchCtx, cancel := context.WithTimeout(ctx, period)
+		rs.watchClicks(watchCtx)
+		cancel()
+	}
+}
+
+// watchClicks обновляет clicksWatched по основной базе. Если проверить не
+// удалось, переходы идут на основную базу. Устаревшее значение не влияет на
+// результат: запрос на реплике сам отказывается от перехода, которому
+// нужна запись.
+func (rs *replicaSet) watchClicks(ctx context.Context) {
+	if rs.primary == nil {
+		return
+	}
+
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM webhook w
+			WHERE w.active AND (cardinality(w.events) = 0 OR 'link.clicked' = ANY(w.events))
+		)
+	`
+
+	var watched bool
+	if err := rs.primary.QueryRow(ctx, query).Scan(&watched); err != nil {
+		watched = true
+	}
+	rs.clicksWatched.Store(watched)
+}
+
+func (rs *replicaSet) close() {
+	if rs.stop != nil {
+		rs.stop()
+		rs.wg.Wait()
+	}
+	for _, r := range rs.replicas {
+		r.pool.Close()
+	}
+}
+
+// read выполняет чтение на реплике, а если реплик нет, они недоступны
+// или контекст помечен storage.WithPrimary - на основной базе. Любая
+// ошибка на реплике, кроме отмены контекста, приводит к повтору на
+// основной базе: в том числе "не найдено", потому что реплика могла ещё
+// не получить только что созданную ссылку.
+func (s *Storage) read(ctx context.Context, fn func(db reader) error) error {
+	r := s.replica(ctx)
+	if r == nil {
+		return fn(s.pool)
+	}
+
+	err := fn(r.pool)
+	if err == nil || ctx.Err() != nil {
+		return err
+	}
+
+	if connectionError(err) {
+		r.healthy.Store(false)
+	}
+
+	return fn(s.pool)
+}
+
+func (s *Storage) replica(ctx context.Context) *replica {
+	if storage.UsePrimary(ctx) {
+		return nil
+	}
+	return s.replicas.pick()
+}
+
+// clickReplica - реплика для перехода по ссылке. Если на переходы есть
+// подписки, переход всё равно запишет событие, поэтому реплика не
+// спрашивается.
+func (s *Storage) clickReplica(ctx context.Context) *replica {
+	if s.replicas == nil || s.replicas.clicksWatched.Load() {
+		return nil
+	}
+	return s.replica(ctx)
+}
+
+// connectionError отличает недоступность реплики от ошибок самого запроса:
+// ответ сервера или "не найдено" означают, что реплика работает.
+func connectionError(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) || errors.Is(err, pgx.ErrNoRows) {
+		return false
+	}
+
+	for _, domain := range []error{
+		storage.ErrURLNotFound, storage.ErrAliasNotFound,
+		storage.ErrURLNotActive, storage.ErrURLExpired,
+	} {
+		if errors.Is(err, domain) {
+			return false
+		}
+	}
+	return true
+}
+
+// ReplicaPools - пулы реплик для метрик.
+func (s *Storage) ReplicaPools() []*pgxpool.Pool {
+	if s.replicas == nil {
+		return nil
+	}
+
+	pools := make([]*pgxpool.Pool, 0, len(s.replicas.replicas))
+	for _, r := range s.replicas.replicas {
+		pools = append(pools, r.pool)
+	}
+	return pools
+}
@@ -0,0 +1,130 @@
+package postgre
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestReplicas(t *testing.T, n int) *replicaSet {
+	urls := make([]string, n)
+	for i := range urls {
+		urls[i] = testURL
+	}
+
+	rs, err := newReplicaSet(context.Background(), ReplicaConfig{URLs: urls, HealthCheckPeriod: time.Hour}, PoolConfig{}, nil)
+	require.NoError(t, err)
+	t.Cleanup(rs.close)
+	return rs
+}
+
+func TestReplicaPick(t *testing.T) {
+	rs := newTestReplicas(t, 3)
+
+	// Недоступные при запуске реплики помечены неисправными.
+	assert.Nil(t, rs.pick())
+
+	rs.replicas[0].healthy.Store(true)
+	rs.replicas[2].healthy.Store(true)
+
+	seen := map[*replica]int{}
+	for i := 0; i < 10; i++ {
+		seen[rs.pick()]++
+	}
+	assert.Len(t, seen, 2)
+	assert.Equal(t, 5, seen[rs.replicas[0]])
+	assert.Equal(t, 5, seen[rs.replicas[2]])
+}
+
+func TestReadFallback(t *testing.T) {
+	rs := newTestReplicas(t, 1)
+	r := rs.replicas[0]
+	s := &Storage{replicas: rs}
+
+	var calls []bool
+	call := func(errOnReplica error) func(db reader) error {
+		return func(db reader) error {
+			onReplica := db == reader(r.pool)
+			calls = append(calls, onReplica)
+			if onReplica {
+				return errOnReplica
+			}
+			return nil
+		}
+	}
+
+	// Ссылка могла ещё не дойти до реплики: повтор на основной базе,
+	// но реплика остаётся исправной.
+	r.healthy.Store(true)
+	calls = nil
+	require.NoError(t, s.read(context.Background(), call(storage.ErrAliasNotFound)))
+	assert.Equal(t, []bool{true, false}, calls)
+	assert.True(t, r.healthy.Load())
+
+	// Ошибка соединения выводит реплику из ротации.
+	calls = nil
+	require.NoError(t, s.read(context.Background(), call(errors.New("connection refused"))))
+	assert.Equal(t, []bool{true, false}, calls)
+	assert.False(t, r.healthy.Load())
+
+	calls = nil
+	require.NoError(t, s.read(context.Background(), call(nil)))
+	assert.Equal(t, []bool{false}, calls)
+
+	// Read-your-writes: помеченный контекст читает с основной базы.
+	r.healthy.Store(true)
+	calls = nil
+	require.NoError(t, s.read(storage.WithPrimary(context.Background()), call(nil)))
+	assert.Equal(t, []bool{false}, calls)
+}
+
+// clicksQuerier отвечает на проверку подписок на переходы.
+type clicksQuerier struct {
+	watched bool
+	err     error
+}
+
+func (q clicksQuerier) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return clicksRow(q)
+}
+
+type clicksRow clicksQuerier
+
+func (r clicksRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	*dest[0].(*bool) = r.watched
+	return nil
+}
+
+func TestClickReplica(t *testing.T) {
+	rs := newTestReplicas(t, 1)
+	r := rs.replicas[0]
+	r.healthy.Store(true)
+	s := &Storage{replicas: rs}
+
+	rs.primary = clicksQuerier{watched: false}
+	rs.watchClicks(context.Background())
+	assert.Same(t, r, s.clickReplica(context.Background()))
+
+	// С подписками на переходы реплика не спрашивается.
+	rs.primary = clicksQuerier{watched: true}
+	rs.watchClicks(context.Background())
+	assert.Nil(t, s.clickReplica(context.Background()))
+
+	// Основная база не ответила - переходы идут на неё.
+	rs.primary = clicksQuerier{watched: false}
+	rs.watchClicks(context.Background())
+	rs.primary = clicksQuerier{err: errors.New("connection refused")}
+	rs.watchClicks(context.Background())
+	assert.Nil(t, s.clickReplica(context.Background()))
+
+	assert.Nil(t, (&Storage{}).clickReplica(context.Background()))
+}
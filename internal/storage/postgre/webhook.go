@@ -0,0 +1,332 @@
+package postgre
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// enqueueEvent пишет событие в outbox и создаёт доставки для всех
+// подходящих подписок. Вызывается в транзакции изменения ссылки, поэтому
+// событие появляется тогда и только тогда, когда изменение зафиксировано.
+func enqueueEvent(ctx context.Context, q execer, eventType string, event storage.LinkEvent) error {
+	const op = "storage.postgre.enqueueEvent"
+
+	query := `
+		WITH event AS (
+			INSERT INTO webhook_outbox(event_type, payload)
+			SELECT $1::text, $2
+			WHERE EXISTS (
+				SELECT 1 FROM webhook w
+				WHERE w.active AND (cardinality(w.events) = 0 OR $1::text = ANY(w.events))
+			)
+			RETURNING id
+		)
+		INSERT INTO webhook_delivery(webhook_id, event_id)
+		SELECT w.id, event.id
+		FROM event, webhook w
+		WHERE w.active AND (cardinality(w.events) = 0 OR $1::text = ANY(w.events));
+	`
+
+	if _, err := q.Exec(ctx, query, eventType, event); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) CreateWebhook(ctx context.Context, webhook storage.Webhook) (storage.Webhook, error) {
+	const op = "storage.postgre.CreateWebhook"
+
+	ctx, cancel := s.writeCtx(ctx)
+	defer cancel()
+
+	query := `
+		INSERT INTO webhook(url, secret, events, active)
+		VALUES($1, $2, $3, $4)
+		RETURNING id, created_at;
+	`
+
+	if webhook.Events == nil {
+		webhook.Events = []string{}
+	}
+
+	err := s.pool.QueryRow(ctx, query, webhook.URL, webhook.Secret, webhook.Events, webhook.Active).
+		Scan(&webhook.ID, &webhook.CreatedAt)
+	if err != nil {
+		return storage.Webhook{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return webhook, nil
+}
+
+func (s *Storage) ListWebhooks(ctx context.Context) ([]storage.Webhook, error) {
+	const op = "storage.postgre.ListWebhooks"
+
+	ctx, cancel := s.readCtx(ctx)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, url, secret, events, active, created_at
+		FROM webhook
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	webhooks, err := pgx.CollectRows(rows, scanWebhook)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return webhooks, nil
+}
+
+func (s *Storage) GetWebhook(ctx context.Context, id int64) (storage.Webhook, error) {
+	const op = "storage.postgre.GetWebhook"
+
+	ctx, cancel := s.readCtx(ctx)
+	defer cancel()
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, url, secret, events, active, created_at
+		FROM webhook
+		WHERE id = $1
+	`, id)
+	if err != nil {
+		return storage.Webhook{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	webhook, err := pgx.CollectExactlyOneRow(rows, scanWebhook)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return storage.Webhook{}, storage.ErrWebhookNotFound
+		}
+		return storage.Webhook{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return webhook, nil
+}
+
+// UpdateWebhook заменяет настройки подписки. Пустой Secret оставляет
+// прежний секрет.
+func (s *Storage) UpdateWebhook(ctx context.Context, webhook storage.Webhook) (storage.Webhook, error) {
+	const op = "storage.postgre.UpdateWebhook"
+
+	ctx, cancel := s.writeCtx(ctx)
+	defer cancel()
+
+	query := `
+		UPDATE webhook
+		SET url = $2, secret = COALESCE(NULLIF($3, ''), secret), events = $4, active = $5
+		WHERE id = $1
+		RETURNING id, url, secret, events, active, created_at;
+	`
+
+	if webhook.Events == nil {
+		webhook.Events = []string{}
+	}
+
+	rows, err := s.pool.Query(ctx, query, webhook.ID, webhook.URL, webhook.Secret, webhook.Events, webhook.Active)
+	if err != nil {
+		return storage.Webhook{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	updated, err := pgx.CollectExactlyOneRow(rows, scanWebhook)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return storage.Webhook{}, storage.ErrWebhookNotFound
+		}
+		return storage.Webhook{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return updated, nil
+}
+
+func (s *Storage) DeleteWebhook(ctx context.Context, id int64) error {
+	const op = "storage.postgre.DeleteWebhook"
+
+	ctx, cancel := s.writeCtx(ctx)
+	defer cancel()
+
+	cmdTag, err := s.pool.Exec(ctx, `DELETE FROM webhook WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return storage.ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+// ClaimDeliveries забирает до limit доставок, время которых пришло не
+// позже dueBefore, и сдвигает им next_attempt_at на lease, чтобы другие
+// экземпляры сервиса не взяли те же доставки, пока эта попытка не завершится.
+func (s *Storage) ClaimDeliveries(ctx context.Context, dueBefore time.Time, limit int, lease time.Duration) ([]storage.Delivery, error) {
+	const op = "storage.postgre.ClaimDeliveries"
+
+	ctx, cancel := s.writeCtx(ctx)
+	defer cancel()
+
+	query := `
+		WITH due AS (
+			SELECT id FROM webhook_delivery
+			WHERE state = 'pending' AND next_attempt_at <= $1
+			ORDER BY next_attempt_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		), claimed AS (
+			UPDATE webhook_delivery d
+			SET next_attempt_at = now() + $3::interval
+			FROM due
+			WHERE d.id = due.id
+			RETURNING d.*
+		)
+		SELECT d.id, d.webhook_id, d.event_id, e.event_type, e.payload, w.url, w.secret,
+			d.state, d.attempts, COALESCE(d.last_status, 0), d.last_error, e.created_at, d.updated_at
+		FROM claimed d
+		JOIN webhook w ON w.id = d.webhook_id
+		JOIN webhook_outbox e ON e.id = d.event_id
+		ORDER BY d.id
+	`
+
+	rows, err := s.pool.Query(ctx, query, dueBefore, limit, lease)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	deliveries, err := pgx.CollectRows(rows, scanDelivery)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return deliveries, nil
+}
+
+func (s *Storage) SaveDeliveryResult(ctx context.Context, res storage.DeliveryResult) error {
+	const op = "storage.postgre.SaveDeliveryResult"
+
+	ctx, cancel := s.writeCtx(ctx)
+	defer cancel()
+
+	query := `
+		UPDATE webhook_delivery
+		SET state = $2, attempts = attempts + 1, last_status = NULLIF($3, 0),
+			last_error = $4, next_attempt_at = $5, updated_at = now()
+		WHERE id = $1;
+	`
+
+	_, err := s.pool.Exec(ctx, query, res.ID, res.State, res.Status, res.Error, res.NextAttemptAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) ListDeadDeliveries(ctx context.Context) ([]storage.Delivery, error) {
+	const op = "storage.postgre.ListDeadDeliveries"
+
+	ctx, cancel := s.readCtx(ctx)
+	defer cancel()
+
+	query := `
+		SELECT d.id, d.webhook_id, d.event_id, e.event_type, e.payload, w.url, w.secret,
+			d.state, d.attempts, COALESCE(d.last_status, 0), d.last_error, e.created_at, d.updated_at
+		FROM webhook_delivery d
+		JOIN webhook w ON w.id = d.webhook_id
+		JOIN webhook_outbox e ON e.id = d.event_id
+		WHERE d.state = 'dead'
+		ORDER BY d.updated_at DESC
+	`
+
+	rows, err := s.pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	deliveries, err := pgx.CollectRows(rows, scanDelivery)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return deliveries, nil
+}
+
+// RetryDelivery возвращает доставку из dead letter в очередь с обнулённым
+// счётчиком попыток.
+func (s *Storage) RetryDelivery(ctx context.Context, id int64) error {
+	const op = "storage.postgre.RetryDelivery"
+
+	ctx, cancel := s.writeCtx(ctx)
+	defer cancel()
+
+	query := `
+		UPDATE webhook_delivery
+		SET state = 'pending', attempts = 0, next_attempt_at = now(), updated_at = now()
+		WHERE id = $1 AND state = 'dead';
+	`
+
+	cmdTag, err := s.pool.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return storage.ErrDeliveryNotFound
+	}
+
+	return nil
+}
+
+// PurgeDeliveries удаляет события, созданные до before, все доставки
+// которых завершились успешно до before, вместе с этими доставками.
+// События с доставками в очереди или в dead letter остаются.
+func (s *Storage) PurgeDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	const op = "storage.postgre.PurgeDeliveries"
+
+	ctx, cancel := s.writeCtx(ctx)
+	defer cancel()
+
+	query := `
+		DELETE FROM webhook_outbox e
+		WHERE e.created_at < $1
+			AND NOT EXISTS (
+				SELECT 1 FROM webhook_delivery d
+				WHERE d.event_id = e.id
+					AND NOT (d.state = 'delivered' AND d.updated_at < $1)
+			);
+	`
+
+	cmdTag, err := s.pool.Exec(ctx, query, before)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return cmdTag.RowsAffected(), nil
+}
+
+func scanWebhook(row pgx.CollectableRow) (storage.Webhook, error) {
+	var w storage.Webhook
+	err := row.Scan(&w.ID, &w.URL, &w.Secret, &w.Events, &w.Active, &w.CreatedAt)
+	return w, err
+}
+
+func scanDelivery(row pgx.CollectableRow) (storage.Delivery, error) {
+	var d storage.Delivery
+	err := row.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Payload, &d.URL, &d.Secret,
+		&d.State, &d.Attempts, &d.LastStatus, &d.LastError, &d.CreatedAt, &d.UpdatedAt)
+	return d, err
+}
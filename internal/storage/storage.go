@@ -1,10 +1,168 @@
 package storage
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var (
 	ErrURLNotFound   = errors.New("url not found")
 	ErrAliasNotFound = errors.New("alias not found")
 	ErrURLExists     = errors.New("url exist")
 	ErrAliasExists   = errors.New("alias already exist")
+	ErrURLExhausted  = errors.New("url clicks limit exhausted")
+	ErrURLNotActive  = errors.New("url is not active yet")
+	ErrURLExpired    = errors.New("url has expired")
+	ErrRuleNotFound  = errors.New("rule not found")
+
+	ErrVersionConflict = errors.New("link was modified concurrently")
+
+	ErrTagNotFound = errors.New("tag not found")
+	ErrTagExists   = errors.New("tag already exist")
+
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("delivery not found")
+
+	ErrAPIKeyNotFound = errors.New("api key not found")
 )
+
+// URLOptions - необязательные параметры сохраняемой ссылки.
+type URLOptions struct {
+	// MaxClicks - сколько раз ссылку можно открыть, 0 - без ограничений.
+	MaxClicks int64
+	// ActiveFrom и ActiveUntil ограничивают окно, в котором ссылка работает,
+	// nil - граница не задана.
+	ActiveFrom  *time.Time
+	ActiveUntil *time.Time
+	// Variants распределяют переходы между несколькими адресами по весам.
+	Variants []Variant
+	Forward  ForwardOptions
+	Meta     LinkMeta
+	// Preview включает промежуточную страницу вместо прямого перехода.
+	Preview      bool
+	Organization Organization
+	// RedirectCode - код HTTP-перенаправления (301, 302, 307, 308), 0 - вместо
+	// перенаправления адрес возвращается в JSON.
+	RedirectCode int
+}
+
+// LinkState - изменяемые поля ссылки и её версия.
+// Варианты A/B-теста в LinkState не входят и при обновлении не меняются.
+type LinkState struct {
+	Alias   string
+	URL     string
+	Options URLOptions
+	// Version увеличивается на единицу при каждом изменении ссылки.
+	Version   int64
+	UpdatedAt time.Time
+}
+
+// LinkMeta - описание ссылки для страницы предпросмотра и Open Graph.
+type LinkMeta struct {
+	Title       string
+	Description string
+	ImageURL    string
+}
+
+// Режимы обработки query-параметров входящего запроса.
+const (
+	QueryDrop     = "drop"
+	QueryMerge    = "merge"
+	QueryOverride = "override"
+)
+
+// ForwardOptions - что из входящего запроса переносится в адрес перехода.
+type ForwardOptions struct {
+	// QueryMode - один из Query*, пустое значение равносильно QueryDrop.
+	QueryMode string
+	// UTM - фиксированные utm-параметры, добавляемые к каждому переходу.
+	UTM map[string]string
+	// ForwardPath разрешает переходы вида /{alias}/rest -> target/rest.
+	ForwardPath bool
+}
+
+// Link - данные ссылки, необходимые для перехода по ней.
+type Link struct {
+	URL string
+	// Rules проверяются по порядку, первое совпадение заменяет URL.
+	Rules []Rule
+	// Variants используются для A/B-теста, если ни одно правило не сработало.
+	Variants []Variant
+	Forward  ForwardOptions
+	Meta     LinkMeta
+	Preview  bool
+
+	RedirectCode int
+}
+
+// Variant - вариант адреса A/B-теста с весом и числом выданных переходов.
+type Variant struct {
+	ID     int64  `json:"id"`
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+	Clicks int64  `json:"clicks"`
+}
+
+// Rule - правило умного перехода: если запрос удовлетворяет всем
+// условиям, клиент получает TargetURL вместо основного адреса ссылки.
+type Rule struct {
+	ID         int64          `json:"id"`
+	Conditions RuleConditions `json:"conditions"`
+	TargetURL  string         `json:"target_url"`
+}
+
+// RuleConditions - условия правила, пустое поле не ограничивает запрос.
+type RuleConditions struct {
+	Devices   []string          `json:"devices,omitempty"`
+	OS        []string          `json:"os,omitempty"`
+	Languages []string          `json:"languages,omitempty"`
+	Countries []string          `json:"countries,omitempty"`
+	Query     map[string]string `json:"query,omitempty"`
+	// TimeFrom и TimeTo - ежедневный интервал в формате "15:04" по UTC.
+	TimeFrom string `json:"time_from,omitempty"`
+	TimeTo   string `json:"time_to,omitempty"`
+}
+
+// CheckTarget - ссылка, адрес которой нужно проверить на доступность.
+type CheckTarget struct {
+	ID  int64
+	URL string
+}
+
+// CheckResult - результат проверки адреса ссылки. Status равен 0, если
+// ответ не был получен, причина тогда записана в Error.
+type CheckResult struct {
+	ID        int64
+	Status    int
+	Latency   time.Duration
+	Error     string
+	CheckedAt time.Time
+}
+
+// BrokenLink - ссылка, адрес которой при последней проверке не ответил
+// или вернул код ошибки.
+type BrokenLink struct {
+	Alias      string    `json:"alias"`
+	URL        string    `json:"url"`
+	LastStatus int       `json:"last_status"`
+	LastError  string    `json:"last_error,omitempty"`
+	LatencyMs  int64     `json:"latency_ms"`
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+// IsBroken сообщает, считается ли результат проверки нерабочей ссылкой.
+func (r CheckResult) IsBroken() bool {
+	return r.Error != "" || r.Status >= 400
+}
+
+// CheckActiveWindow возвращает ErrURLNotActive или ErrURLExpired, если момент
+// now не попадает в окно [activeFrom, activeUntil).
+func CheckActiveWindow(now time.Time, activeFrom, activeUntil *time.Time) error {
+	if activeFrom != nil && now.Before(*activeFrom) {
+		return ErrURLNotActive
+	}
+	if activeUntil != nil && !now.Before(*activeUntil) {
+		return ErrURLExpired
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+)
+
+// События жизненного цикла ссылки, на которые можно подписаться.
+const (
+	EventLinkCreated = "link.created"
+	EventLinkRenamed = "link.renamed"
+	EventLinkDeleted = "link.deleted"
+	EventLinkClicked = "link.clicked"
+)
+
+// Events - все поддерживаемые типы событий.
+var Events = []string{EventLinkCreated, EventLinkRenamed, EventLinkDeleted, EventLinkClicked}
+
+// Состояния доставки события подписчику.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+// Webhook - подписка внешней системы на события ссылок. Пустой Events
+// означает подписку на все события.
+type Webhook struct {
+	ID        int64     `json:"id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"-"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Wants сообщает, нужно ли доставлять подписке событие eventType.
+func (w Webhook) Wants(eventType string) bool {
+	return w.Active && (len(w.Events) == 0 || slices.Contains(w.Events, eventType))
+}
+
+// Delivery - доставка одного события из outbox одной подписке.
+type Delivery struct {
+	ID         int64           `json:"id"`
+	WebhookID  int64           `json:"webhook_id"`
+	EventID    int64           `json:"event_id"`
+	EventType  string          `json:"event_type"`
+	Payload    json.RawMessage `json:"payload" swaggertype:"object"`
+	URL        string          `json:"url"`
+	Secret     string          `json:"-"`
+	State      string          `json:"state"`
+	Attempts   int             `json:"attempts"`
+	LastStatus int             `json:"last_status,omitempty"`
+	LastError  string          `json:"last_error,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
+
+// DeliveryResult - итог очередной попытки доставки. Для State = pending
+// NextAttemptAt задаёт время следующей попытки.
+type DeliveryResult struct {
+	ID            int64
+	State         string
+	Status        int
+	Error         string
+	NextAttemptAt time.Time
+}
+
+// LinkEvent - данные события ссылки, которые попадают в outbox.
+type LinkEvent struct {
+	Alias    string `json:"alias"`
+	URL      string `json:"url,omitempty"`
+	OldAlias string `json:"old_alias,omitempty"`
+}
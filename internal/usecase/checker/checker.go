@@ -0,0 +1,273 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	metric "github.com/RozmiDan/url_shortener/internal/metrics"
+	"github.com/RozmiDan/url_shortener/internal/netguard"
+	"github.com/RozmiDan/url_shortener/internal/storage"
+)
+
+const userAgent = "url_shortener-link-checker/1.0"
+
+type Storage interface {
+	ClaimLinksToCheck(ctx context.Context, checkedBefore time.Time, limit int, lease time.Duration) ([]storage.CheckTarget, error)
+	SaveCheckResult(ctx context.Context, res storage.CheckResult) error
+	CountBroken(ctx context.Context) (int, error)
+}
+
+type Config struct {
+	// Interval - пауза между проходами по ссылкам.
+	Interval time.Duration
+	// RecheckAfter - через сколько времени ссылку снова нужно проверить.
+	RecheckAfter time.Duration
+	// Timeout - таймаут одного запроса к адресу.
+	Timeout time.Duration
+	// PerHostInterval - минимальный интервал между запросами к одному хосту.
+	PerHostInterval time.Duration
+	Workers         int
+	BatchSize       int
+	// AllowPrivate разрешает проверять адреса во внутренней сети. По
+	// умолчанию такие подключения отклоняются, чтобы ссылка не
+	// превращала проверку в запрос к внутренним сервисам.
+	AllowPrivate bool
+	// Heartbeat, если задан, вызывается после каждого прохода: по нему
+	// /readyz видит, что процесс не завис.
+	Heartbeat func()
+}
+
+// Checker периодически проверяет адреса, на которые ведут ссылки, и
+// сохраняет код ответа, время ответа и момент проверки.
+type Checker struct {
+	logger  *slog.Logger
+	st      Storage
+	cfg     Config
+	lease   time.Duration
+	client  *http.Client
+	limiter *hostLimiter
+}
+
+func New(logger *slog.Logger, st Storage, cfg Config) *Checker {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+
+	// Пачка закрепляется за экземпляром на время, за которое её точно
+	// успеют проверить: на каждого воркера - HEAD и GET на ссылку и пауза
+	// между запросами к одному хосту на всю пачку.
+	perWorker := (cfg.BatchSize + cfg.Workers - 1) / cfg.Workers
+	lease := time.Duration(perWorker)*2*cfg.Timeout +
+		time.Duration(cfg.BatchSize)*cfg.PerHostInterval + time.Minute
+
+	return &Checker{
+		logger: logger.With(slog.String("component", "usecase/checker")),
+		st:     st,
+		cfg:    cfg,
+		lease:  lease,
+		// Перенаправления проходят ту же проверку адреса при подключении.
+		client:  netguard.Client(cfg.Timeout, cfg.AllowPrivate),
+		limiter: newHostLimiter(cfg.PerHostInterval),
+	}
+}
+
+// Run проверяет ссылки каждые cfg.Interval, пока не отменён ctx.
+func (c *Checker) Run(ctx context.Context) {
+	c.logger.Info("link checker started", slog.Int("workers", c.cfg.Workers))
+
+	ticker := time.NewTicker(c.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.RunOnce(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			c.logger.Error("link check pass failed", slog.Any("err", err))
+		}
+		if c.cfg.Heartbeat != nil {
+			c.cfg.Heartbeat()
+		}
+
+		select {
+		case <-ctx.Done():
+			c.logger.Info("link checker stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce проверяет одну пачку ссылок, давно не проверявшихся, и
+// обновляет метрику числа нерабочих ссылок. Пачка закрепляется за
+// экземпляром, поэтому реплики сервиса проверяют разные ссылки.
+func (c *Checker) RunOnce(ctx context.Context) error {
+	const op = "usecase.checker.RunOnce"
+
+	targets, err := c.st.ClaimLinksToCheck(ctx, time.Now().Add(-c.cfg.RecheckAfter), c.cfg.BatchSize, c.lease)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	c.limiter.prune()
+
+	jobs := make(chan storage.CheckTarget)
+	var wg sync.WaitGroup
+	for i := 0; i < c.cfg.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for target := range jobs {
+				res := c.check(ctx, target)
+				if ctx.Err() != nil {
+					return
+				}
+				if err := c.st.SaveCheckResult(ctx, res); err != nil {
+					c.logger.Error("cant save check result", slog.Int64("id", target.ID), slog.Any("err", err))
+				}
+			}
+		}()
+	}
+
+send:
+	for _, target := range targets {
+		select {
+		case jobs <- target:
+		case <-ctx.Done():
+			break send
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	broken, err := c.st.CountBroken(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	metric.BrokenLinks.Set(float64(broken))
+
+	return nil
+}
+
+func (c *Checker) check(ctx context.Context, target storage.CheckTarget) storage.CheckResult {
+	res := storage.CheckResult{ID: target.ID}
+
+	u, err := url.Parse(target.URL)
+	if err != nil {
+		res.Error = "invalid url"
+		res.CheckedAt = time.Now()
+		return res
+	}
+
+	if err := c.limiter.wait(ctx, u.Host); err != nil {
+		res.Error = err.Error()
+		res.CheckedAt = time.Now()
+		return res
+	}
+
+	start := time.Now()
+	status, err := c.do(ctx, http.MethodHead, target.URL)
+	// Часть серверов не поддерживает HEAD, для них повторяем запрос GET.
+	if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented) {
+		status, err = c.do(ctx, http.MethodGet, target.URL)
+	}
+
+	res.Latency = time.Since(start)
+	res.CheckedAt = time.Now()
+	res.Status = status
+	if err != nil {
+		res.Error = err.Error()
+	}
+
+	return res
+}
+
+func (c *Checker) do(ctx context.Context, method, target string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// Тело не нужно, но читаем немного, чтобы соединение вернулось в пул.
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	return resp.StatusCode, nil
+}
+
+// hostLimiter выдаёт запросам к одному хосту слоты не чаще, чем раз в interval.
+type hostLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     map[string]time.Time
+}
+
+func newHostLimiter(interval time.Duration) *hostLimiter {
+	return &hostLimiter{interval: interval, next: make(map[string]time.Time)}
+}
+
+func (l *hostLimiter) wait(ctx context.Context, host string) error {
+	if l.interval <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	slot := l.next[host]
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next[host] = slot.Add(l.interval)
+	l.mu.Unlock()
+
+	delay := time.Until(slot)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// prune удаляет хосты, слоты которых уже прошли, чтобы карта не росла.
+func (l *hostLimiter) prune() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	for host, slot := range l.next {
+		if slot.Before(now) {
+			delete(l.next, host)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package checker_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/RozmiDan/url_shortener/internal/storage/memory"
+	"github.com/RozmiDan/url_shortener/internal/usecase/checker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunOnce(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("/get-only", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	st := memory.New()
+	ctx := context.Background()
+	for alias, path := range map[string]string{"ok": "/ok", "gone": "/gone", "getonly": "/get-only"} {
+		_, err := st.SaveURL(ctx, srv.URL+path, alias, storage.URLOptions{})
+		require.NoError(t, err)
+	}
+	_, err := st.SaveURL(ctx, "http://127.0.0.1:1/down", "down", storage.URLOptions{})
+	require.NoError(t, err)
+
+	c := checker.New(logger, st, checker.Config{
+		RecheckAfter: time.Hour,
+		Timeout:      2 * time.Second,
+		Workers:      2,
+		AllowPrivate: true,
+	})
+
+	require.NoError(t, c.RunOnce(ctx))
+
+	broken, err := st.ListBroken(ctx)
+	require.NoError(t, err)
+
+	byAlias := make(map[string]storage.BrokenLink)
+	for _, b := range broken {
+		byAlias[b.Alias] = b
+	}
+
+	assert.Len(t, byAlias, 2)
+	assert.Equal(t, http.StatusNotFound, byAlias["gone"].LastStatus)
+	assert.NotEmpty(t, byAlias["down"].LastError)
+	assert.NotContains(t, byAlias, "getonly")
+
+	// Только что проверенные ссылки повторно не берутся.
+	targets, err := st.ClaimLinksToCheck(ctx, time.Now().Add(-time.Hour), 100, time.Minute)
+	require.NoError(t, err)
+	assert.Empty(t, targets)
+}
+
+func TestClaimLinksToCheck(t *testing.T) {
+	st := memory.New()
+	ctx := context.Background()
+	for _, alias := range []string{"a", "b", "c"} {
+		_, err := st.SaveURL(ctx, "https://example.com/"+alias, alias, storage.URLOptions{})
+		require.NoError(t, err)
+	}
+
+	// Пока пачка закреплена за одним экземпляром, другой её не получает.
+	first, err := st.ClaimLinksToCheck(ctx, time.Now(), 2, time.Minute)
+	require.NoError(t, err)
+	assert.Len(t, first, 2)
+
+	second, err := st.ClaimLinksToCheck(ctx, time.Now(), 2, -time.Second)
+	require.NoError(t, err)
+	require.Len(t, second, 1)
+	assert.Equal(t, "https://example.com/c", second[0].URL)
+
+	// Истёкшая аренда возвращает ссылку в очередь.
+	third, err := st.ClaimLinksToCheck(ctx, time.Now(), 10, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, second, third)
+}
+
+func TestRunZeroInterval(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	c := checker.New(logger, memory.New(), checker.Config{})
+	assert.NotPanics(t, func() { c.Run(ctx) })
+}
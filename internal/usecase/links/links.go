@@ -0,0 +1,344 @@
+// Package links - правила работы со ссылками: проверка, генерация alias,
+// поведение при занятом alias и изменения с проверкой версии.
+// HTTP-обработчики и команды CLI работают со ссылками только через Service.
+//
+// Окно активности и лимит переходов при переходе проверяет хранилище
+// одним запросом с уменьшением счётчика, а события для вебхуков пишутся
+// в outbox в той же транзакции, что и изменение ссылки, поэтому эти
+// правила остаются в Repository.
+package links
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/RozmiDan/url_shortener/internal/usecase/random"
+)
+
+// ErrAliasesExhausted - за Config.AliasAttempts попыток не нашлось
+// свободного alias.
+var ErrAliasesExhausted = errors.New("cant generate free alias")
+
+// Repository - хранилище ссылок.
+type Repository interface {
+	// CreateURL сохраняет новую ссылку, занятый alias - storage.ErrAliasExists.
+	CreateURL(ctx context.Context, urlToSave string, alias string, opts storage.URLOptions) (int64, error)
+	// SaveURL сохраняет ссылку, заменяя существующую с тем же alias.
+	SaveURL(ctx context.Context, urlToSave string, alias string, opts storage.URLOptions) (int64, error)
+	GetURL(ctx context.Context, alias string) (storage.Link, error)
+	// FollowURL - как GetURL, но засчитывает и переход по ссылке с флагом
+	// preview.
+	FollowURL(ctx context.Context, alias string) (storage.Link, error)
+	// PeekURL - как GetURL, но без учёта перехода.
+	PeekURL(ctx context.Context, alias string) (storage.Link, error)
+	GetLinkState(ctx context.Context, alias string) (storage.LinkState, error)
+	UpdateLink(ctx context.Context, alias string, state storage.LinkState, expectedVersion int64) (storage.LinkState, error)
+	DeleteURL(ctx context.Context, alias string, expectedVersion int64) error
+	RecordVariantServed(ctx context.Context, variantID int64) error
+	ListLinks(ctx context.Context, filter storage.ListFilter) ([]storage.LinkInfo, error)
+	ListVariants(ctx context.Context, alias string) ([]storage.Variant, error)
+	ListBroken(ctx context.Context) ([]storage.BrokenLink, error)
+}
+
+// Conflict - что делать, если alias новой ссылки уже занят.
+type Conflict int
+
+const (
+	// ConflictFail возвращает storage.ErrAliasExists.
+	ConflictFail Conflict = iota
+	// ConflictOverwrite заменяет существующую ссылку.
+	ConflictOverwrite
+)
+
+// Draft - ссылка до сохранения. Пустой Alias генерируется.
+type Draft struct {
+	Alias   string
+	URL     string
+	Options storage.URLOptions
+}
+
+// Change - изменение ссылки по частям, пустое поле не меняется.
+type Change struct {
+	NewAlias     string
+	Window       *Window
+	Organization storage.OrganizationPatch
+}
+
+// Window заменяет окно активности целиком, nil-граница снимает ограничение.
+type Window struct {
+	ActiveFrom  *time.Time
+	ActiveUntil *time.Time
+}
+
+type Config struct {
+	// AliasLength - длина генерируемого alias.
+	AliasLength int
+	// AliasAttempts - сколько alias попробовать, прежде чем вернуть
+	// ErrAliasesExhausted.
+	AliasAttempts int
+	// NewAlias генерирует alias заданной длины.
+	NewAlias func(length int) string
+	// ReservedAliases - alias, совпадающие с путями сервиса, их нельзя
+	// занять ни при создании, ни при переименовании.
+	ReservedAliases []string
+}
+
+type Service struct {
+	repo Repository
+	cfg  Config
+}
+
+func New(repo Repository, cfg Config) *Service {
+	if cfg.AliasLength <= 0 {
+		cfg.AliasLength = 6
+	}
+	if cfg.AliasAttempts <= 0 {
+		cfg.AliasAttempts = 5
+	}
+	if cfg.NewAlias == nil {
+		cfg.NewAlias = random.NewAliasForURL
+	}
+
+	return &Service{repo: repo, cfg: cfg}
+}
+
+// Create проверяет и сохраняет ссылку и возвращает её alias. Сгенерированный
+// alias никогда не заменяет существующую ссылку: при совпадении берётся
+// следующий.
+func (s *Service) Create(ctx context.Context, draft Draft, conflict Conflict) (string, error) {
+	const op = "usecase.links.Create"
+
+	if err := validateDraft(draft, s.cfg.ReservedAliases); err != nil {
+		return "", err
+	}
+	draft.Options.Organization.Tags = storage.NormalizeTags(draft.Options.Organization.Tags)
+
+	if draft.Alias != "" {
+		save := s.repo.CreateURL
+		if conflict == ConflictOverwrite {
+			save = s.repo.SaveURL
+		}
+
+		if _, err := save(ctx, draft.URL, draft.Alias, draft.Options); err != nil {
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
+		return draft.Alias, nil
+	}
+
+	for range s.cfg.AliasAttempts {
+		alias := s.cfg.NewAlias(s.cfg.AliasLength)
+		if slices.Contains(s.cfg.ReservedAliases, alias) {
+			continue
+		}
+
+		_, err := s.repo.CreateURL(ctx, draft.URL, alias, draft.Options)
+		if errors.Is(err, storage.ErrAliasExists) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
+		return alias, nil
+	}
+
+	return "", fmt.Errorf("%s: %w", op, ErrAliasesExhausted)
+}
+
+func (s *Service) Get(ctx context.Context, alias string) (storage.LinkState, error) {
+	const op = "usecase.links.Get"
+
+	state, err := s.repo.GetLinkState(ctx, alias)
+	if err != nil {
+		return storage.LinkState{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return state, nil
+}
+
+// Update заменяет все изменяемые поля ссылки, включая alias. Варианты
+// A/B-теста не меняются.
+func (s *Service) Update(ctx context.Context, alias string, draft Draft, expectedVersion int64) (storage.LinkState, error) {
+	const op = "usecase.links.Update"
+
+	if draft.Alias == "" {
+		return storage.LinkState{}, invalid("alias", "alias cannot be removed")
+	}
+	if err := validateDraft(draft, s.cfg.ReservedAliases); err != nil {
+		return storage.LinkState{}, err
+	}
+	draft.Options.Organization.Tags = storage.NormalizeTags(draft.Options.Organization.Tags)
+
+	state, err := s.repo.UpdateLink(ctx, alias, storage.LinkState{
+		Alias:   draft.Alias,
+		URL:     draft.URL,
+		Options: draft.Options,
+	}, expectedVersion)
+	if err != nil {
+		return storage.LinkState{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return state, nil
+}
+
+// Apply проверяет изменение целиком, включая занятость нового alias, и
+// применяет его одной записью: организация, окно активности и alias
+// меняются вместе, а версия увеличивается на единицу. Изменение ссылки,
+// сделанное после чтения, приводит к storage.ErrVersionConflict.
+// Возвращает новую версию.
+func (s *Service) Apply(ctx context.Context, alias string, change Change, expectedVersion int64) (int64, error) {
+	const op = "usecase.links.Apply"
+
+	if err := validateChange(alias, change, s.cfg.ReservedAliases); err != nil {
+		return 0, err
+	}
+
+	state, err := s.repo.GetLinkState(ctx, alias)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if expectedVersion != 0 && state.Version != expectedVersion {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrVersionConflict)
+	}
+
+	if change.NewAlias != "" {
+		_, err := s.repo.GetLinkState(ctx, change.NewAlias)
+		switch {
+		case err == nil:
+			return 0, fmt.Errorf("%s: %w", op, storage.ErrAliasExists)
+		case !errors.Is(err, storage.ErrAliasNotFound):
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+		state.Alias = change.NewAlias
+	}
+
+	org := &state.Options.Organization
+	if patch := change.Organization; patch.Tags != nil {
+		org.Tags = storage.NormalizeTags(*patch.Tags)
+	}
+	if patch := change.Organization; patch.Folder != nil {
+		org.Folder = *patch.Folder
+	}
+	if patch := change.Organization; patch.Metadata != nil {
+		org.Metadata = *patch.Metadata
+	}
+
+	if win := change.Window; win != nil {
+		state.Options.ActiveFrom = win.ActiveFrom
+		state.Options.ActiveUntil = win.ActiveUntil
+	}
+
+	updated, err := s.repo.UpdateLink(ctx, alias, state, state.Version)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return updated.Version, nil
+}
+
+// Rename меняет alias ссылки и возвращает новую версию.
+func (s *Service) Rename(ctx context.Context, alias, newAlias string, expectedVersion int64) (int64, error) {
+	if newAlias == "" {
+		return 0, invalid("newAlias", "new alias is required")
+	}
+	return s.Apply(ctx, alias, Change{NewAlias: newAlias}, expectedVersion)
+}
+
+func (s *Service) Delete(ctx context.Context, alias string, expectedVersion int64) error {
+	const op = "usecase.links.Delete"
+
+	if err := s.repo.DeleteURL(ctx, alias, expectedVersion); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// Resolve засчитывает переход по ссылке и возвращает данные для него.
+// Неактивная ссылка возвращает storage.ErrURLNotActive, истёкшая -
+// storage.ErrURLExpired, исчерпавшая лимит - storage.ErrURLExhausted.
+func (s *Service) Resolve(ctx context.Context, alias string) (storage.Link, error) {
+	const op = "usecase.links.Resolve"
+
+	link, err := s.repo.GetURL(ctx, alias)
+	if err != nil {
+		return storage.Link{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return link, nil
+}
+
+// Follow засчитывает переход со страницы предпросмотра. В отличие от
+// Resolve, переход по ссылке с флагом preview тоже засчитывается.
+func (s *Service) Follow(ctx context.Context, alias string) (storage.Link, error) {
+	const op = "usecase.links.Follow"
+
+	link, err := s.repo.FollowURL(ctx, alias)
+	if err != nil {
+		return storage.Link{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return link, nil
+}
+
+// Peek возвращает данные для перехода по ссылке, не засчитывая его:
+// счётчик переходов и события вебхуков не меняются. Ошибки те же, что у
+// Resolve.
+func (s *Service) Peek(ctx context.Context, alias string) (storage.Link, error) {
+	const op = "usecase.links.Peek"
+
+	link, err := s.repo.PeekURL(ctx, alias)
+	if err != nil {
+		return storage.Link{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return link, nil
+}
+
+// RecordVariantServed учитывает переход на вариант A/B-теста.
+func (s *Service) RecordVariantServed(ctx context.Context, variantID int64) error {
+	const op = "usecase.links.RecordVariantServed"
+
+	if err := s.repo.RecordVariantServed(ctx, variantID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// List возвращает ссылки, подходящие под filter. Теги фильтра
+// нормализуются так же, как при сохранении ссылки.
+func (s *Service) List(ctx context.Context, filter storage.ListFilter) ([]storage.LinkInfo, error) {
+	const op = "usecase.links.List"
+
+	if filter.Limit < 0 {
+		return nil, invalid("limit", "limit must not be negative")
+	}
+	if filter.Offset < 0 {
+		return nil, invalid("offset", "offset must not be negative")
+	}
+	filter.Tags = storage.NormalizeTags(filter.Tags)
+
+	infos, err := s.repo.ListLinks(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return infos, nil
+}
+
+// ListBroken возвращает ссылки, адрес которых не прошёл последнюю проверку.
+func (s *Service) ListBroken(ctx context.Context) ([]storage.BrokenLink, error) {
+	const op = "usecase.links.ListBroken"
+
+	broken, err := s.repo.ListBroken(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return broken, nil
+}
+
+// Variants возвращает варианты A/B-теста ссылки с числом переходов на каждый.
+func (s *Service) Variants(ctx context.Context, alias string) ([]storage.Variant, error) {
+	const op = "usecase.links.Variants"
+
+	variants, err := s.repo.ListVariants(ctx, alias)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return variants, nil
+}
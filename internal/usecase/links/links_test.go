@@ -0,0 +1,275 @@
+package links_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/RozmiDan/url_shortener/internal/storage/memory"
+	"github.com/RozmiDan/url_shortener/internal/usecase/links"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sequence возвращает alias по порядку.
+func sequence(aliases ...string) func(int) string {
+	return func(int) string {
+		alias := aliases[0]
+		if len(aliases) > 1 {
+			aliases = aliases[1:]
+		}
+		return alias
+	}
+}
+
+func TestCreate(t *testing.T) {
+	ctx := context.Background()
+	st := memory.New()
+	svc := links.New(st, links.Config{})
+
+	alias, err := svc.Create(ctx, links.Draft{
+		Alias: "docs",
+		URL:   "https://example.com",
+		Options: storage.URLOptions{
+			Organization: storage.Organization{Tags: []string{" Docs ", "docs"}},
+		},
+	}, links.ConflictFail)
+	require.NoError(t, err)
+	assert.Equal(t, "docs", alias)
+
+	state, err := svc.Get(ctx, "docs")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"docs"}, state.Options.Organization.Tags)
+
+	_, err = svc.Create(ctx, links.Draft{Alias: "docs", URL: "https://example.org"}, links.ConflictFail)
+	assert.ErrorIs(t, err, storage.ErrAliasExists)
+
+	_, err = svc.Create(ctx, links.Draft{Alias: "docs", URL: "https://example.org"}, links.ConflictOverwrite)
+	require.NoError(t, err)
+
+	state, err = svc.Get(ctx, "docs")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.org", state.URL)
+}
+
+func TestCreateGeneratedAlias(t *testing.T) {
+	ctx := context.Background()
+	st := memory.New()
+
+	_, err := st.SaveURL(ctx, "https://example.com", "taken", storage.URLOptions{})
+	require.NoError(t, err)
+
+	svc := links.New(st, links.Config{NewAlias: sequence("taken", "free")})
+
+	alias, err := svc.Create(ctx, links.Draft{URL: "https://example.org"}, links.ConflictOverwrite)
+	require.NoError(t, err)
+	assert.Equal(t, "free", alias)
+
+	// Сгенерированный alias не заменяет существующую ссылку.
+	state, err := svc.Get(ctx, "taken")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", state.URL)
+
+	svc = links.New(st, links.Config{AliasAttempts: 3, NewAlias: sequence("taken")})
+
+	_, err = svc.Create(ctx, links.Draft{URL: "https://example.org"}, links.ConflictFail)
+	assert.ErrorIs(t, err, links.ErrAliasesExhausted)
+}
+
+func TestCreateInvalid(t *testing.T) {
+	from := time.Date(2030, 2, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name  string
+		draft links.Draft
+		field string
+	}{
+		{
+			name:  "plus in alias",
+			draft: links.Draft{Alias: "a+b", URL: "https://example.com"},
+			field: "alias",
+		},
+		{
+			name:  "relative url",
+			draft: links.Draft{URL: "example.com"},
+			field: "url",
+		},
+		{
+			name:  "negative max clicks",
+			draft: links.Draft{URL: "https://example.com", Options: storage.URLOptions{MaxClicks: -1}},
+			field: "max_clicks",
+		},
+		{
+			name: "until before from",
+			draft: links.Draft{URL: "https://example.com", Options: storage.URLOptions{
+				ActiveFrom:  &from,
+				ActiveUntil: &until,
+			}},
+			field: "active_until",
+		},
+		{
+			name: "variant weight",
+			draft: links.Draft{URL: "https://example.com", Options: storage.URLOptions{
+				Variants: []storage.Variant{{URL: "https://a.example.com", Weight: 1}, {URL: "https://b.example.com"}},
+			}},
+			field: "variants[1].weight",
+		},
+		{
+			name:  "redirect code",
+			draft: links.Draft{URL: "https://example.com", Options: storage.URLOptions{RedirectCode: 303}},
+			field: "redirect_code",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := links.New(memory.New(), links.Config{})
+
+			_, err := svc.Create(context.Background(), tc.draft, links.ConflictFail)
+			assert.ErrorIs(t, err, links.ErrInvalid)
+
+			var verr *links.ValidationError
+			require.ErrorAs(t, err, &verr)
+			assert.Equal(t, tc.field, verr.Field)
+		})
+	}
+}
+
+func TestReservedAliases(t *testing.T) {
+	ctx := context.Background()
+	svc := links.New(memory.New(), links.Config{
+		ReservedAliases: []string{"api", "metrics"},
+		NewAlias:        sequence("api", "free"),
+	})
+
+	_, err := svc.Create(ctx, links.Draft{Alias: "api", URL: "https://example.com"}, links.ConflictFail)
+	assert.ErrorIs(t, err, links.ErrInvalid)
+	assert.EqualError(t, err, `alias "api" is reserved`)
+
+	// Сгенерированный alias тоже не совпадает с зарезервированным.
+	alias, err := svc.Create(ctx, links.Draft{URL: "https://example.com"}, links.ConflictFail)
+	require.NoError(t, err)
+	assert.Equal(t, "free", alias)
+
+	_, err = svc.Rename(ctx, "free", "metrics", 0)
+	assert.EqualError(t, err, `new alias "metrics" is reserved`)
+}
+
+func TestApply(t *testing.T) {
+	ctx := context.Background()
+	st := memory.New()
+	svc := links.New(st, links.Config{})
+
+	_, err := svc.Create(ctx, links.Draft{Alias: "campaign", URL: "https://example.com"}, links.ConflictFail)
+	require.NoError(t, err)
+
+	folder := "spring"
+	version, err := svc.Apply(ctx, "campaign", links.Change{
+		NewAlias:     "renamed",
+		Window:       &links.Window{},
+		Organization: storage.OrganizationPatch{Folder: &folder},
+	}, 1)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, version)
+
+	state, err := svc.Get(ctx, "renamed")
+	require.NoError(t, err)
+	assert.Equal(t, version, state.Version)
+	assert.Equal(t, "spring", state.Options.Organization.Folder)
+
+	// Занятый alias отклоняет всё изменение, а не только переименование.
+	_, err = svc.Create(ctx, links.Draft{Alias: "taken", URL: "https://example.org"}, links.ConflictFail)
+	require.NoError(t, err)
+
+	autumn := "autumn"
+	_, err = svc.Apply(ctx, "renamed", links.Change{
+		NewAlias:     "taken",
+		Organization: storage.OrganizationPatch{Folder: &autumn},
+	}, 0)
+	assert.ErrorIs(t, err, storage.ErrAliasExists)
+
+	state, err = svc.Get(ctx, "renamed")
+	require.NoError(t, err)
+	assert.Equal(t, version, state.Version)
+	assert.Equal(t, "spring", state.Options.Organization.Folder)
+
+	_, err = svc.Apply(ctx, "renamed", links.Change{NewAlias: "again"}, 1)
+	assert.ErrorIs(t, err, storage.ErrVersionConflict)
+
+	_, err = svc.Apply(ctx, "missing", links.Change{NewAlias: "again"}, 0)
+	assert.ErrorIs(t, err, storage.ErrAliasNotFound)
+}
+
+func TestApplyInvalid(t *testing.T) {
+	svc := links.New(memory.New(), links.Config{})
+	ctx := context.Background()
+
+	from := time.Date(2030, 2, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name   string
+		change links.Change
+		err    string
+	}{
+		{
+			name: "empty change",
+			err:  "new alias, window, tags, folder or metadata is required",
+		},
+		{
+			name:   "same alias",
+			change: links.Change{NewAlias: "campaign"},
+			err:    "new alias must be different",
+		},
+		{
+			name:   "plus in alias",
+			change: links.Change{NewAlias: "a+"},
+			err:    `new alias must not contain "+"`,
+		},
+		{
+			name:   "until before from",
+			change: links.Change{Window: &links.Window{ActiveFrom: &from, ActiveUntil: &until}},
+			err:    "active_until must be after active_from",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := svc.Apply(ctx, "campaign", tc.change, 0)
+			assert.ErrorIs(t, err, links.ErrInvalid)
+			assert.EqualError(t, err, tc.err)
+		})
+	}
+
+	_, err := svc.Rename(ctx, "campaign", "", 0)
+	assert.EqualError(t, err, "new alias is required")
+
+	_, err = svc.Update(ctx, "campaign", links.Draft{URL: "https://example.com"}, 0)
+	assert.EqualError(t, err, "alias cannot be removed")
+}
+
+func TestResolve(t *testing.T) {
+	ctx := context.Background()
+	svc := links.New(memory.New(), links.Config{})
+
+	_, err := svc.Create(ctx, links.Draft{
+		Alias:   "once",
+		URL:     "https://example.com",
+		Options: storage.URLOptions{MaxClicks: 1},
+	}, links.ConflictFail)
+	require.NoError(t, err)
+
+	link, err := svc.Resolve(ctx, "once")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", link.URL)
+
+	_, err = svc.Resolve(ctx, "once")
+	assert.ErrorIs(t, err, storage.ErrURLExhausted)
+
+	require.NoError(t, svc.Delete(ctx, "once", 0))
+
+	_, err = svc.Resolve(ctx, "once")
+	assert.ErrorIs(t, err, storage.ErrURLNotFound)
+}
@@ -0,0 +1,121 @@
+package links
+
+import (
+	"errors"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RozmiDan/url_shortener/internal/storage"
+)
+
+// ErrInvalid - ссылка нарушает правило, подробности в *ValidationError.
+var ErrInvalid = errors.New("invalid link")
+
+// ValidationError - нарушенное правило и поле ссылки в формате POST /url.
+type ValidationError struct {
+	Field  string
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Reason
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalid
+}
+
+func invalid(field, reason string) error {
+	return &ValidationError{Field: field, Reason: reason}
+}
+
+func validateDraft(draft Draft, reserved []string) error {
+	opts := draft.Options
+
+	if err := validateAlias("alias", "alias", draft.Alias, reserved); err != nil {
+		return err
+	}
+	if !absoluteURL(draft.URL) {
+		return invalid("url", "url must be an absolute URL")
+	}
+	if opts.MaxClicks < 0 {
+		return invalid("max_clicks", "max_clicks must not be negative")
+	}
+	if err := validateWindow(opts.ActiveFrom, opts.ActiveUntil); err != nil {
+		return err
+	}
+
+	for i, v := range opts.Variants {
+		field := "variants[" + strconv.Itoa(i) + "]"
+		if !absoluteURL(v.URL) {
+			return invalid(field+".url", "variant url must be an absolute URL")
+		}
+		if v.Weight < 1 {
+			return invalid(field+".weight", "variant weight must be positive")
+		}
+	}
+
+	switch opts.Forward.QueryMode {
+	case "", storage.QueryDrop, storage.QueryMerge, storage.QueryOverride:
+	default:
+		return invalid("query_mode", "query_mode must be drop, merge or override")
+	}
+
+	switch opts.RedirectCode {
+	case 0, 301, 302, 307, 308:
+	default:
+		return invalid("redirect_code", "redirect_code must be 301, 302, 307 or 308")
+	}
+
+	return nil
+}
+
+func validateChange(alias string, change Change, reserved []string) error {
+	patch := change.Organization
+	if change.NewAlias == "" && change.Window == nil &&
+		patch.Tags == nil && patch.Folder == nil && patch.Metadata == nil {
+		return invalid("", "new alias, window, tags, folder or metadata is required")
+	}
+
+	if change.NewAlias != "" {
+		if change.NewAlias == alias {
+			return invalid("newAlias", "new alias must be different")
+		}
+		if err := validateAlias("newAlias", "new alias", change.NewAlias, reserved); err != nil {
+			return err
+		}
+	}
+
+	if win := change.Window; win != nil {
+		return validateWindow(win.ActiveFrom, win.ActiveUntil)
+	}
+
+	return nil
+}
+
+// validateAlias проверяет alias, пустой допускается.
+func validateAlias(field, name, alias string, reserved []string) error {
+	// "+" в конце пути зарезервирован под страницу предпросмотра.
+	if strings.Contains(alias, "+") {
+		return invalid(field, name+` must not contain "+"`)
+	}
+	if slices.Contains(reserved, alias) {
+		return invalid(field, name+` "`+alias+`" is reserved`)
+	}
+	return nil
+}
+
+func validateWindow(activeFrom, activeUntil *time.Time) error {
+	if activeFrom != nil && activeUntil != nil && !activeUntil.After(*activeFrom) {
+		return invalid("active_until", "active_until must be after active_from")
+	}
+	return nil
+}
+
+func absoluteURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && u.Scheme != "" && (u.Host != "" || u.Opaque != "")
+}
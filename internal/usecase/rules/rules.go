@@ -0,0 +1,235 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RozmiDan/url_shortener/internal/storage"
+)
+
+const timeLayout = "15:04"
+
+const (
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceDesktop = "desktop"
+	DeviceBot     = "bot"
+
+	OSIOS     = "ios"
+	OSAndroid = "android"
+	OSWindows = "windows"
+	OSMacOS   = "macos"
+	OSLinux   = "linux"
+)
+
+var (
+	knownDevices = []string{DeviceMobile, DeviceTablet, DeviceDesktop, DeviceBot}
+	knownOS      = []string{OSIOS, OSAndroid, OSWindows, OSMacOS, OSLinux}
+)
+
+// Visitor - признаки запроса, по которым проверяются условия правил.
+type Visitor struct {
+	Device   string
+	OS       string
+	Language string
+	Country  string
+	Query    url.Values
+	Now      time.Time
+}
+
+// VisitorFromRequest извлекает признаки посетителя из запроса. Страна
+// берётся из заголовка countryHeader, который выставляет балансировщик.
+func VisitorFromRequest(r *http.Request, countryHeader string) Visitor {
+	ua := strings.ToLower(r.UserAgent())
+
+	v := Visitor{
+		Device:   deviceFromUA(ua),
+		OS:       osFromUA(ua),
+		Language: preferredLanguage(r.Header.Get("Accept-Language")),
+		Query:    r.URL.Query(),
+		Now:      time.Now().UTC(),
+	}
+	if countryHeader != "" {
+		v.Country = strings.ToUpper(strings.TrimSpace(r.Header.Get(countryHeader)))
+	}
+
+	return v
+}
+
+// Match возвращает адрес первого правила, условиям которого
+// удовлетворяет посетитель.
+func Match(rules []storage.Rule, v Visitor) (string, bool) {
+	for _, rule := range rules {
+		if matches(rule.Conditions, v) {
+			return rule.TargetURL, true
+		}
+	}
+	return "", false
+}
+
+// Validate проверяет, что условия правила заданы корректно.
+func Validate(c storage.RuleConditions) error {
+	for _, d := range c.Devices {
+		if !slices.Contains(knownDevices, strings.ToLower(d)) {
+			return fmt.Errorf("unknown device %q", d)
+		}
+	}
+	for _, name := range c.OS {
+		if !slices.Contains(knownOS, strings.ToLower(name)) {
+			return fmt.Errorf("unknown os %q", name)
+		}
+	}
+	if (c.TimeFrom == "") != (c.TimeTo == "") {
+		return errors.New("time_from and time_to must be set together")
+	}
+	if c.TimeFrom != "" {
+		if _, err := time.Parse(timeLayout, c.TimeFrom); err != nil {
+			return fmt.Errorf("invalid time_from %q", c.TimeFrom)
+		}
+		if _, err := time.Parse(timeLayout, c.TimeTo); err != nil {
+			return fmt.Errorf("invalid time_to %q", c.TimeTo)
+		}
+	}
+	return nil
+}
+
+func matches(c storage.RuleConditions, v Visitor) bool {
+	if len(c.Devices) > 0 && !containsFold(c.Devices, v.Device) {
+		return false
+	}
+	if len(c.OS) > 0 && !containsFold(c.OS, v.OS) {
+		return false
+	}
+	if len(c.Languages) > 0 && !containsFold(c.Languages, v.Language) {
+		return false
+	}
+	if len(c.Countries) > 0 && !containsFold(c.Countries, v.Country) {
+		return false
+	}
+	for key, value := range c.Query {
+		if !v.Query.Has(key) || v.Query.Get(key) != value {
+			return false
+		}
+	}
+	if c.TimeFrom != "" && !inDailyWindow(v.Now, c.TimeFrom, c.TimeTo) {
+		return false
+	}
+	return true
+}
+
+func containsFold(list []string, value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// inDailyWindow поддерживает интервалы через полночь, например 22:00-06:00.
+func inDailyWindow(now time.Time, from, to string) bool {
+	fromT, err := time.Parse(timeLayout, from)
+	if err != nil {
+		return false
+	}
+	toT, err := time.Parse(timeLayout, to)
+	if err != nil {
+		return false
+	}
+
+	minutes := now.Hour()*60 + now.Minute()
+	start := fromT.Hour()*60 + fromT.Minute()
+	end := toT.Hour()*60 + toT.Minute()
+
+	if start <= end {
+		return minutes >= start && minutes < end
+	}
+	return minutes >= start || minutes < end
+}
+
+func deviceFromUA(ua string) string {
+	switch {
+	case ua == "":
+		return ""
+	case strings.Contains(ua, "bot"), strings.Contains(ua, "crawler"),
+		strings.Contains(ua, "spider"), strings.Contains(ua, "slurp"):
+		return DeviceBot
+	case strings.Contains(ua, "ipad"), strings.Contains(ua, "tablet"),
+		strings.Contains(ua, "android") && !strings.Contains(ua, "mobile"):
+		return DeviceTablet
+	case strings.Contains(ua, "mobi"), strings.Contains(ua, "iphone"),
+		strings.Contains(ua, "ipod"), strings.Contains(ua, "android"):
+		return DeviceMobile
+	default:
+		return DeviceDesktop
+	}
+}
+
+func osFromUA(ua string) string {
+	switch {
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"), strings.Contains(ua, "ipod"):
+		return OSIOS
+	case strings.Contains(ua, "android"):
+		return OSAndroid
+	case strings.Contains(ua, "windows"):
+		return OSWindows
+	case strings.Contains(ua, "mac os x"), strings.Contains(ua, "macintosh"):
+		return OSMacOS
+	case strings.Contains(ua, "linux"):
+		return OSLinux
+	default:
+		return ""
+	}
+}
+
+// preferredLanguage возвращает основной субтег языка с наибольшим весом
+// из заголовка Accept-Language: "ru-RU,ru;q=0.9,en;q=0.8" -> "ru".
+func preferredLanguage(header string) string {
+	type lang struct {
+		tag string
+		q   float64
+	}
+
+	var langs []lang
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if value, ok := strings.CutPrefix(param, "q="); ok {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+
+		primary, _, _ := strings.Cut(tag, "-")
+		langs = append(langs, lang{tag: primary, q: q})
+	}
+
+	if len(langs) == 0 {
+		return ""
+	}
+
+	sort.SliceStable(langs, func(i, j int) bool { return langs[i].q > langs[j].q })
+
+	return langs[0].tag
+}
@@ -0,0 +1,85 @@
+package rules
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/RozmiDan/url_shortener/internal/usecase/links"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreferredLanguage(t *testing.T) {
+	testCases := map[string]string{
+		"":                        "",
+		"ru":                      "ru",
+		"ru-RU,ru;q=0.9,en;q=0.8": "ru",
+		"en;q=0.5, de-DE;q=0.7":   "de",
+		"*, fr;q=0.1":             "fr",
+		"ru;q=0, en-GB":           "en",
+	}
+
+	for header, expected := range testCases {
+		assert.Equal(t, expected, preferredLanguage(header), header)
+	}
+}
+
+func TestInDailyWindow(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2030, 1, 1, hour, minute, 0, 0, time.UTC)
+	}
+
+	assert.True(t, inDailyWindow(at(10, 0), "09:00", "18:00"))
+	assert.False(t, inDailyWindow(at(18, 0), "09:00", "18:00"))
+	assert.True(t, inDailyWindow(at(23, 30), "22:00", "06:00"))
+	assert.True(t, inDailyWindow(at(5, 59), "22:00", "06:00"))
+	assert.False(t, inDailyWindow(at(12, 0), "22:00", "06:00"))
+}
+
+func TestMatchOrder(t *testing.T) {
+	list := []storage.Rule{
+		{Conditions: storage.RuleConditions{Devices: []string{DeviceMobile}, Query: map[string]string{"v": "2"}}, TargetURL: "first"},
+		{Conditions: storage.RuleConditions{Devices: []string{DeviceMobile}}, TargetURL: "second"},
+		{Conditions: storage.RuleConditions{}, TargetURL: "catch-all"},
+	}
+
+	target, ok := Match(list, Visitor{Device: DeviceMobile, Query: url.Values{"v": {"2"}}})
+	assert.True(t, ok)
+	assert.Equal(t, "first", target)
+
+	target, ok = Match(list, Visitor{Device: DeviceMobile, Query: url.Values{}})
+	assert.True(t, ok)
+	assert.Equal(t, "second", target)
+
+	target, ok = Match(list, Visitor{Device: DeviceDesktop})
+	assert.True(t, ok)
+	assert.Equal(t, "catch-all", target)
+
+	_, ok = Match(nil, Visitor{})
+	assert.False(t, ok)
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate(storage.RuleConditions{OS: []string{"iOS"}, TimeFrom: "09:00", TimeTo: "18:00"}))
+	assert.Error(t, Validate(storage.RuleConditions{Devices: []string{"fridge"}}))
+	assert.Error(t, Validate(storage.RuleConditions{OS: []string{"beos"}}))
+	assert.Error(t, Validate(storage.RuleConditions{TimeFrom: "09:00"}))
+	assert.Error(t, Validate(storage.RuleConditions{TimeFrom: "9am", TimeTo: "18:00"}))
+}
+
+func TestValidateList(t *testing.T) {
+	valid := storage.Rule{TargetURL: "https://example.com", Conditions: storage.RuleConditions{Devices: []string{"mobile"}}}
+	require.NoError(t, ValidateList([]storage.Rule{valid}))
+
+	err := ValidateList([]storage.Rule{valid, {TargetURL: "/relative"}})
+	var verr *links.ValidationError
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, "rules[1].target_url", verr.Field)
+	assert.ErrorIs(t, err, links.ErrInvalid)
+
+	err = ValidateList([]storage.Rule{{TargetURL: "https://example.com", Conditions: storage.RuleConditions{TimeFrom: "25:00"}}})
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, "rules[0].conditions", verr.Field)
+}
@@ -0,0 +1,119 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/RozmiDan/url_shortener/internal/usecase/links"
+)
+
+// Repository - хранилище правил. Каждое изменение увеличивает версию
+// ссылки и возвращает её, expectedVersion = 0 - без проверки версии.
+type Repository interface {
+	ListRules(ctx context.Context, alias string) ([]storage.Rule, int64, error)
+	AddRule(ctx context.Context, alias string, rule storage.Rule, expectedVersion int64) (storage.Rule, int64, error)
+	UpdateRule(ctx context.Context, alias string, rule storage.Rule, expectedVersion int64) (int64, error)
+	DeleteRule(ctx context.Context, alias string, ruleID int64, expectedVersion int64) (int64, error)
+	ReplaceRules(ctx context.Context, alias string, rules []storage.Rule, expectedVersion int64) ([]storage.Rule, int64, error)
+}
+
+// Service проверяет и сохраняет правила умного перенаправления ссылки.
+// Ошибки проверки - *links.ValidationError.
+type Service struct {
+	repo Repository
+}
+
+func New(repo Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// List возвращает правила ссылки по порядку и текущую версию ссылки.
+func (s *Service) List(ctx context.Context, alias string) ([]storage.Rule, int64, error) {
+	const op = "usecase.rules.List"
+
+	list, version, err := s.repo.ListRules(ctx, alias)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return list, version, nil
+}
+
+// Add добавляет правило в конец списка.
+func (s *Service) Add(ctx context.Context, alias string, rule storage.Rule, expectedVersion int64) (storage.Rule, int64, error) {
+	const op = "usecase.rules.Add"
+
+	if err := validateRule("", rule); err != nil {
+		return storage.Rule{}, 0, err
+	}
+
+	saved, version, err := s.repo.AddRule(ctx, alias, rule, expectedVersion)
+	if err != nil {
+		return storage.Rule{}, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return saved, version, nil
+}
+
+// Update заменяет условия и адрес правила rule.ID.
+func (s *Service) Update(ctx context.Context, alias string, rule storage.Rule, expectedVersion int64) (int64, error) {
+	const op = "usecase.rules.Update"
+
+	if err := validateRule("", rule); err != nil {
+		return 0, err
+	}
+
+	version, err := s.repo.UpdateRule(ctx, alias, rule, expectedVersion)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return version, nil
+}
+
+func (s *Service) Delete(ctx context.Context, alias string, ruleID int64, expectedVersion int64) (int64, error) {
+	const op = "usecase.rules.Delete"
+
+	version, err := s.repo.DeleteRule(ctx, alias, ruleID, expectedVersion)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return version, nil
+}
+
+// Replace заменяет все правила ссылки новым списком.
+func (s *Service) Replace(ctx context.Context, alias string, list []storage.Rule, expectedVersion int64) ([]storage.Rule, int64, error) {
+	const op = "usecase.rules.Replace"
+
+	if err := ValidateList(list); err != nil {
+		return nil, 0, err
+	}
+
+	saved, version, err := s.repo.ReplaceRules(ctx, alias, list, expectedVersion)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return saved, version, nil
+}
+
+// ValidateList проверяет список правил так же, как Replace, - например,
+// до создания ссылки, которой они будут назначены.
+func ValidateList(list []storage.Rule) error {
+	for i, rule := range list {
+		if err := validateRule("rules["+strconv.Itoa(i)+"].", rule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateRule проверяет правило, prefix - путь к нему в теле запроса.
+func validateRule(prefix string, rule storage.Rule) error {
+	if u, err := url.Parse(rule.TargetURL); err != nil || u.Scheme == "" || u.Host == "" && u.Opaque == "" {
+		return &links.ValidationError{Field: prefix + "target_url", Reason: "target_url must be an absolute URL"}
+	}
+	if err := Validate(rule.Conditions); err != nil {
+		return &links.ValidationError{Field: prefix + "conditions", Reason: err.Error()}
+	}
+	return nil
+}
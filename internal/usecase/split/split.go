@@ -0,0 +1,39 @@
+package split
+
+import (
+	"hash/fnv"
+
+	"github.com/RozmiDan/url_shortener/internal/storage"
+)
+
+// Pick детерминированно выбирает вариант по весам: один и тот же ключ
+// посетителя для одной ссылки всегда получает один и тот же вариант.
+func Pick(alias string, variants []storage.Variant, visitorKey string) (storage.Variant, bool) {
+	total := 0
+	for _, v := range variants {
+		if v.Weight > 0 {
+			total += v.Weight
+		}
+	}
+	if total == 0 {
+		return storage.Variant{}, false
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(alias))
+	h.Write([]byte{0})
+	h.Write([]byte(visitorKey))
+
+	point := int(h.Sum64() % uint64(total))
+	for _, v := range variants {
+		if v.Weight <= 0 {
+			continue
+		}
+		if point < v.Weight {
+			return v, true
+		}
+		point -= v.Weight
+	}
+
+	return storage.Variant{}, false
+}
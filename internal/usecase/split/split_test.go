@@ -0,0 +1,51 @@
+package split_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/RozmiDan/url_shortener/internal/usecase/split"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPickSticky(t *testing.T) {
+	variants := []storage.Variant{
+		{ID: 1, URL: "https://a.example.com", Weight: 50},
+		{ID: 2, URL: "https://b.example.com", Weight: 50},
+	}
+
+	first, ok := split.Pick("promo", variants, "visitor-1")
+	assert.True(t, ok)
+
+	for i := 0; i < 10; i++ {
+		again, _ := split.Pick("promo", variants, "visitor-1")
+		assert.Equal(t, first.ID, again.ID)
+	}
+}
+
+func TestPickWeights(t *testing.T) {
+	variants := []storage.Variant{
+		{ID: 1, URL: "https://a.example.com", Weight: 70},
+		{ID: 2, URL: "https://b.example.com", Weight: 30},
+	}
+
+	const visitors = 10000
+	counts := map[int64]int{}
+	for i := 0; i < visitors; i++ {
+		v, ok := split.Pick("promo", variants, strconv.Itoa(i))
+		assert.True(t, ok)
+		counts[v.ID]++
+	}
+
+	assert.InDelta(t, 0.7, float64(counts[1])/visitors, 0.03)
+	assert.InDelta(t, 0.3, float64(counts[2])/visitors, 0.03)
+}
+
+func TestPickEmpty(t *testing.T) {
+	_, ok := split.Pick("promo", nil, "visitor")
+	assert.False(t, ok)
+
+	_, ok = split.Pick("promo", []storage.Variant{{ID: 1, Weight: 0}}, "visitor")
+	assert.False(t, ok)
+}
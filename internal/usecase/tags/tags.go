@@ -0,0 +1,73 @@
+// Package tags - операции над тегами сразу у всех ссылок: список,
+// переименование и слияние. Имена тегов приводятся к тому же виду, что
+// и при сохранении ссылки.
+package tags
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/RozmiDan/url_shortener/internal/usecase/links"
+)
+
+type Repository interface {
+	ListTags(ctx context.Context) ([]storage.Tag, error)
+	RenameTag(ctx context.Context, oldName, newName string) error
+	MergeTags(ctx context.Context, sources []string, target string) error
+}
+
+type Service struct {
+	repo Repository
+}
+
+func New(repo Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// List возвращает теги с числом ссылок у каждого.
+func (s *Service) List(ctx context.Context) ([]storage.Tag, error) {
+	const op = "usecase.tags.List"
+
+	list, err := s.repo.ListTags(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return list, nil
+}
+
+// Rename переименовывает тег у всех ссылок. Если тег newName уже есть,
+// возвращается storage.ErrTagExists: такие теги нужно сливать через Merge.
+func (s *Service) Rename(ctx context.Context, oldName, newName string) error {
+	const op = "usecase.tags.Rename"
+
+	oldName, newName = normalize(oldName), normalize(newName)
+	if newName == "" || newName == oldName {
+		return &links.ValidationError{Field: "name", Reason: "new tag name must be different"}
+	}
+
+	if err := s.repo.RenameTag(ctx, oldName, newName); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// Merge заменяет теги sources на target у всех ссылок и удаляет sources.
+func (s *Service) Merge(ctx context.Context, sources []string, target string) error {
+	const op = "usecase.tags.Merge"
+
+	sources, target = storage.NormalizeTags(sources), normalize(target)
+	if len(sources) == 0 || target == "" {
+		return &links.ValidationError{Reason: "sources and target must not be empty"}
+	}
+
+	if err := s.repo.MergeTags(ctx, sources, target); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func normalize(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
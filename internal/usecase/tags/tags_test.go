@@ -0,0 +1,32 @@
+package tags
+
+import (
+	"context"
+	"testing"
+
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/RozmiDan/url_shortener/internal/storage/memory"
+	"github.com/RozmiDan/url_shortener/internal/usecase/links"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService(t *testing.T) {
+	ctx := context.Background()
+	st := memory.New()
+	svc := New(st)
+
+	_, err := st.SaveURL(ctx, "https://example.com", "one", storage.URLOptions{Organization: storage.Organization{Tags: []string{"promo", "old"}}})
+	require.NoError(t, err)
+
+	require.NoError(t, svc.Rename(ctx, " Promo ", "Sale"))
+	assert.ErrorIs(t, svc.Rename(ctx, "sale", "SALE"), links.ErrInvalid)
+
+	require.NoError(t, svc.Merge(ctx, []string{"OLD"}, " sale"))
+	assert.ErrorIs(t, svc.Merge(ctx, nil, "sale"), links.ErrInvalid)
+
+	list, err := svc.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "sale", list[0].Name)
+}
@@ -0,0 +1,84 @@
+package target
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/RozmiDan/url_shortener/internal/storage"
+)
+
+var (
+	ErrPathForwardingDisabled = errors.New("path forwarding is disabled")
+	ErrInvalidSuffix          = errors.New("invalid path suffix")
+)
+
+// Build собирает итоговый адрес перехода: к target добавляются
+// фиксированные utm-параметры ссылки, query входящего запроса по режиму
+// opts.QueryMode и экранированный суффикс пути escapedSuffix.
+//
+// Приоритет параметров: в режиме merge побеждают параметры target и utm,
+// в режиме override - параметры входящего запроса.
+func Build(target string, opts storage.ForwardOptions, incoming url.Values, escapedSuffix string) (string, error) {
+	const op = "usecase.target.Build"
+
+	if escapedSuffix == "" && len(opts.UTM) == 0 && (len(incoming) == 0 || isDrop(opts.QueryMode)) {
+		return target, nil
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if escapedSuffix != "" {
+		if !opts.ForwardPath {
+			return "", ErrPathForwardingDisabled
+		}
+		if err := checkSuffix(escapedSuffix); err != nil {
+			return "", err
+		}
+		u = u.JoinPath(escapedSuffix)
+	}
+
+	query := u.Query()
+	for key, value := range opts.UTM {
+		query.Set(key, value)
+	}
+
+	switch opts.QueryMode {
+	case storage.QueryMerge:
+		for key, values := range incoming {
+			if !query.Has(key) {
+				query[key] = values
+			}
+		}
+	case storage.QueryOverride:
+		for key, values := range incoming {
+			query[key] = values
+		}
+	}
+
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+func isDrop(mode string) bool {
+	return mode == "" || mode == storage.QueryDrop
+}
+
+// checkSuffix запрещает выход за пределы пути target через "..".
+func checkSuffix(escapedSuffix string) error {
+	for _, segment := range strings.Split(escapedSuffix, "/") {
+		decoded, err := url.PathUnescape(segment)
+		if err != nil {
+			return ErrInvalidSuffix
+		}
+		if decoded == ".." || decoded == "." {
+			return ErrInvalidSuffix
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package target_test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/RozmiDan/url_shortener/internal/usecase/target"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuild(t *testing.T) {
+	utm := map[string]string{"utm_source": "newsletter", "utm_medium": "email"}
+
+	testCases := []struct {
+		name     string
+		target   string
+		opts     storage.ForwardOptions
+		incoming url.Values
+		suffix   string
+		expected string
+		err      error
+	}{
+		{
+			name:     "drop by default",
+			target:   "https://example.com/page?a=1",
+			incoming: url.Values{"utm_source": {"x"}},
+			expected: "https://example.com/page?a=1",
+		},
+		{
+			name:     "merge keeps target params",
+			target:   "https://example.com/page?a=1",
+			opts:     storage.ForwardOptions{QueryMode: storage.QueryMerge},
+			incoming: url.Values{"a": {"2"}, "b": {"3"}},
+			expected: "https://example.com/page?a=1&b=3",
+		},
+		{
+			name:     "override replaces target params",
+			target:   "https://example.com/page?a=1",
+			opts:     storage.ForwardOptions{QueryMode: storage.QueryOverride},
+			incoming: url.Values{"a": {"2"}, "b": {"3"}},
+			expected: "https://example.com/page?a=2&b=3",
+		},
+		{
+			name:     "fixed utm appended",
+			target:   "https://example.com/page",
+			opts:     storage.ForwardOptions{UTM: utm},
+			expected: "https://example.com/page?utm_medium=email&utm_source=newsletter",
+		},
+		{
+			name:     "fixed utm wins in merge mode",
+			target:   "https://example.com/page",
+			opts:     storage.ForwardOptions{UTM: utm, QueryMode: storage.QueryMerge},
+			incoming: url.Values{"utm_source": {"x"}, "ref": {"y"}},
+			expected: "https://example.com/page?ref=y&utm_medium=email&utm_source=newsletter",
+		},
+		{
+			name:     "incoming utm wins in override mode",
+			target:   "https://example.com/page",
+			opts:     storage.ForwardOptions{UTM: utm, QueryMode: storage.QueryOverride},
+			incoming: url.Values{"utm_source": {"x"}},
+			expected: "https://example.com/page?utm_medium=email&utm_source=x",
+		},
+		{
+			name:     "values are encoded",
+			target:   "https://example.com/page",
+			opts:     storage.ForwardOptions{QueryMode: storage.QueryMerge},
+			incoming: url.Values{"q": {"a b&c=d"}},
+			expected: "https://example.com/page?q=a+b%26c%3Dd",
+		},
+		{
+			name:     "path suffix forwarded",
+			target:   "https://example.com/docs/",
+			opts:     storage.ForwardOptions{ForwardPath: true},
+			suffix:   "guide/intro%20page",
+			expected: "https://example.com/docs/guide/intro%20page",
+		},
+		{
+			name:     "path suffix with query",
+			target:   "https://example.com/docs?lang=en",
+			opts:     storage.ForwardOptions{ForwardPath: true, QueryMode: storage.QueryMerge},
+			incoming: url.Values{"page": {"2"}},
+			suffix:   "api/",
+			expected: "https://example.com/docs/api/?lang=en&page=2",
+		},
+		{
+			name:   "path forwarding disabled",
+			target: "https://example.com/docs",
+			suffix: "guide",
+			err:    target.ErrPathForwardingDisabled,
+		},
+		{
+			name:   "path traversal rejected",
+			target: "https://example.com/docs",
+			opts:   storage.ForwardOptions{ForwardPath: true},
+			suffix: "%2E%2E/admin",
+			err:    target.ErrInvalidSuffix,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := target.Build(tc.target, tc.opts, tc.incoming, tc.suffix)
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, result)
+		})
+	}
+}
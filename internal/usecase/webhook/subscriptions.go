@@ -0,0 +1,161 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"slices"
+
+	"github.com/RozmiDan/url_shortener/internal/netguard"
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/RozmiDan/url_shortener/internal/usecase/links"
+)
+
+// Repository - хранилище подписок и доставок.
+type Repository interface {
+	CreateWebhook(ctx context.Context, webhook storage.Webhook) (storage.Webhook, error)
+	ListWebhooks(ctx context.Context) ([]storage.Webhook, error)
+	GetWebhook(ctx context.Context, id int64) (storage.Webhook, error)
+	UpdateWebhook(ctx context.Context, webhook storage.Webhook) (storage.Webhook, error)
+	DeleteWebhook(ctx context.Context, id int64) error
+	ListDeadDeliveries(ctx context.Context) ([]storage.Delivery, error)
+	RetryDelivery(ctx context.Context, id int64) error
+}
+
+// Subscriptions - управление подписками на события ссылок и доставками,
+// которые исчерпали попытки. Ошибки проверки - *links.ValidationError.
+type Subscriptions struct {
+	repo Repository
+	// allowPrivate разрешает подписывать адреса во внутренней сети, как
+	// Config.AllowPrivate разрешает доставку на них.
+	allowPrivate bool
+}
+
+func NewSubscriptions(repo Repository, allowPrivate bool) *Subscriptions {
+	return &Subscriptions{repo: repo, allowPrivate: allowPrivate}
+}
+
+// Create проверяет и сохраняет подписку. Пустой Secret генерируется,
+// созданная подписка возвращается вместе с ним.
+func (s *Subscriptions) Create(ctx context.Context, webhook storage.Webhook) (storage.Webhook, error) {
+	const op = "usecase.webhook.Create"
+
+	if err := s.validate(ctx, webhook); err != nil {
+		return storage.Webhook{}, err
+	}
+
+	if webhook.Secret == "" {
+		secret, err := newSecret()
+		if err != nil {
+			return storage.Webhook{}, fmt.Errorf("%s: generate secret: %w", op, err)
+		}
+		webhook.Secret = secret
+	}
+
+	created, err := s.repo.CreateWebhook(ctx, webhook)
+	if err != nil {
+		return storage.Webhook{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return created, nil
+}
+
+func (s *Subscriptions) List(ctx context.Context) ([]storage.Webhook, error) {
+	const op = "usecase.webhook.List"
+
+	webhooks, err := s.repo.ListWebhooks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return webhooks, nil
+}
+
+func (s *Subscriptions) Get(ctx context.Context, id int64) (storage.Webhook, error) {
+	const op = "usecase.webhook.Get"
+
+	webhook, err := s.repo.GetWebhook(ctx, id)
+	if err != nil {
+		return storage.Webhook{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return webhook, nil
+}
+
+// Update заменяет адрес, события и состояние подписки. Пустой Secret
+// оставляет прежний секрет.
+func (s *Subscriptions) Update(ctx context.Context, webhook storage.Webhook) (storage.Webhook, error) {
+	const op = "usecase.webhook.Update"
+
+	if err := s.validate(ctx, webhook); err != nil {
+		return storage.Webhook{}, err
+	}
+
+	updated, err := s.repo.UpdateWebhook(ctx, webhook)
+	if err != nil {
+		return storage.Webhook{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return updated, nil
+}
+
+// Delete удаляет подписку вместе с её недоставленными событиями.
+func (s *Subscriptions) Delete(ctx context.Context, id int64) error {
+	const op = "usecase.webhook.Delete"
+
+	if err := s.repo.DeleteWebhook(ctx, id); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// DeadLetters возвращает доставки, исчерпавшие все попытки.
+func (s *Subscriptions) DeadLetters(ctx context.Context) ([]storage.Delivery, error) {
+	const op = "usecase.webhook.DeadLetters"
+
+	deliveries, err := s.repo.ListDeadDeliveries(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return deliveries, nil
+}
+
+// Retry возвращает доставку из dead letter в очередь.
+func (s *Subscriptions) Retry(ctx context.Context, id int64) error {
+	const op = "usecase.webhook.Retry"
+
+	if err := s.repo.RetryDelivery(ctx, id); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func (s *Subscriptions) validate(ctx context.Context, webhook storage.Webhook) error {
+	if len(webhook.Events) == 0 {
+		return &links.ValidationError{Field: "events", Reason: "at least one event is required"}
+	}
+	for _, event := range webhook.Events {
+		if !slices.Contains(storage.Events, event) {
+			return &links.ValidationError{Field: "events", Reason: fmt.Sprintf("unknown event %q", event)}
+		}
+	}
+
+	if u, err := url.Parse(webhook.URL); err != nil || u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return &links.ValidationError{Field: "url", Reason: "url must be an absolute http or https URL"}
+	}
+	if s.allowPrivate {
+		return nil
+	}
+
+	// Вебхук не должен превращаться в запрос к внутренним сервисам.
+	if err := netguard.CheckURL(ctx, webhook.URL); err != nil {
+		return &links.ValidationError{Field: "url", Reason: "url must point to a public http or https address"}
+	}
+	return nil
+}
+
+func newSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,279 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	metric "github.com/RozmiDan/url_shortener/internal/metrics"
+	"github.com/RozmiDan/url_shortener/internal/netguard"
+	"github.com/RozmiDan/url_shortener/internal/storage"
+)
+
+// Заголовки, которые получает подписчик вместе с событием.
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderID        = "X-Webhook-Id"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+type Storage interface {
+	ClaimDeliveries(ctx context.Context, dueBefore time.Time, limit int, lease time.Duration) ([]storage.Delivery, error)
+	SaveDeliveryResult(ctx context.Context, res storage.DeliveryResult) error
+	PurgeDeliveries(ctx context.Context, before time.Time) (int64, error)
+}
+
+type Config struct {
+	// Interval - пауза между опросами очереди доставок.
+	Interval time.Duration
+	// Timeout - таймаут одного запроса к подписчику.
+	Timeout time.Duration
+	// MaxAttempts - после стольких неудачных попыток доставка уходит в dead letter.
+	MaxAttempts int
+	// BaseBackoff и MaxBackoff задают экспоненциальную паузу между попытками.
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// BatchSize - сколько доставок выполняется за один опрос очереди.
+	BatchSize int
+	// AllowPrivate разрешает доставку на адреса во внутренней сети.
+	AllowPrivate bool
+	// Heartbeat вызывается после каждого опроса очереди, nil - не вызывается.
+	Heartbeat func()
+}
+
+// Event - тело запроса, которое отправляется подписчику.
+type Event struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Dispatcher доставляет события из outbox подписчикам с повторами.
+type Dispatcher struct {
+	logger *slog.Logger
+	st     Storage
+	cfg    Config
+	client *http.Client
+}
+
+func New(logger *slog.Logger, st Storage, cfg Config) *Dispatcher {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 5 * time.Second
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 1
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+
+	// Подписчик отвечает на событие сам, перенаправления не выполняются.
+	client := netguard.Client(cfg.Timeout, cfg.AllowPrivate)
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	return &Dispatcher{
+		logger: logger.With(slog.String("component", "usecase/webhook")),
+		st:     st,
+		cfg:    cfg,
+		client: client,
+	}
+}
+
+// Run опрашивает очередь доставок каждые cfg.Interval, пока не отменён ctx.
+func (d *Dispatcher) Run(ctx context.Context) {
+	d.logger.Info("webhook dispatcher started")
+
+	ticker := time.NewTicker(d.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.RunOnce(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			d.logger.Error("webhook dispatch failed", slog.Any("err", err))
+		}
+		if d.cfg.Heartbeat != nil {
+			d.cfg.Heartbeat()
+		}
+
+		select {
+		case <-ctx.Done():
+			d.logger.Info("webhook dispatcher stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce выполняет одну попытку для каждой доставки, время которой
+// пришло к началу прохода, но не больше cfg.BatchSize.
+func (d *Dispatcher) RunOnce(ctx context.Context) error {
+	const op = "usecase.webhook.RunOnce"
+
+	// Пока идёт попытка, доставка заблокирована для других экземпляров.
+	// Доставки забираются по одной: аренда покрывает только свою попытку,
+	// и пока идут предыдущие, её срок не расходуется. Повтор, время
+	// которого пришло уже во время прохода, ждёт следующего прохода.
+	lease := d.cfg.Timeout + time.Minute
+	start := time.Now()
+
+	for range d.cfg.BatchSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		deliveries, err := d.st.ClaimDeliveries(ctx, start, 1, lease)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if len(deliveries) == 0 {
+			return nil
+		}
+
+		res := d.deliver(ctx, deliveries[0])
+		metric.WebhookDeliveriesTotal.WithLabelValues(res.State).Inc()
+
+		if err := d.st.SaveDeliveryResult(ctx, res); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return nil
+}
+
+// RunPurge каждые interval удаляет доставленные события старше retention,
+// пока не отменён ctx.
+func RunPurge(ctx context.Context, logger *slog.Logger, st Storage, interval, retention time.Duration) {
+	log := logger.With(slog.String("component", "usecase/webhook"))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		purged, err := st.PurgeDeliveries(ctx, time.Now().Add(-retention))
+		if err != nil {
+			log.Error("failed to purge webhook deliveries", slog.Any("err", err))
+			continue
+		}
+		if purged > 0 {
+			log.Debug("webhook events purged", slog.Int64("count", purged))
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery storage.Delivery) storage.DeliveryResult {
+	res := storage.DeliveryResult{ID: delivery.ID}
+
+	status, err := d.send(ctx, delivery)
+	res.Status = status
+
+	if err == nil && status >= 200 && status < 300 {
+		res.State = storage.DeliveryDelivered
+		res.NextAttemptAt = time.Now()
+		return res
+	}
+
+	if err != nil {
+		res.Error = err.Error()
+	} else {
+		res.Error = "unexpected status " + strconv.Itoa(status)
+	}
+
+	attempt := delivery.Attempts + 1
+	if attempt >= d.cfg.MaxAttempts {
+		d.logger.Warn("webhook delivery moved to dead letter",
+			slog.Int64("delivery_id", delivery.ID),
+			slog.Int("attempts", attempt),
+			slog.String("err", res.Error),
+		)
+		res.State = storage.DeliveryDead
+		res.NextAttemptAt = time.Now()
+		return res
+	}
+
+	res.State = storage.DeliveryPending
+	res.NextAttemptAt = time.Now().Add(Backoff(attempt, d.cfg.BaseBackoff, d.cfg.MaxBackoff))
+
+	return res
+}
+
+func (d *Dispatcher) send(ctx context.Context, delivery storage.Delivery) (int, error) {
+	body, err := json.Marshal(Event{
+		ID:        delivery.EventID,
+		Type:      delivery.EventType,
+		CreatedAt: delivery.CreatedAt,
+		Data:      delivery.Payload,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderID, strconv.FormatInt(delivery.EventID, 10))
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(delivery.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	return resp.StatusCode, nil
+}
+
+// Sign возвращает подпись тела события в формате "sha256=<hex>". Подписывается
+// строка "<timestamp>.<body>", чтобы подписчик мог отбрасывать старые повторы.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff возвращает паузу перед попыткой attempt+1: base * 2^(attempt-1),
+// но не больше maxDelay.
+func Backoff(attempt int, base, maxDelay time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if maxDelay > 0 && delay >= maxDelay {
+			return maxDelay
+		}
+	}
+	if maxDelay > 0 && delay > maxDelay {
+		return maxDelay
+	}
+	return delay
+}
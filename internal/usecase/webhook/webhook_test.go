@@ -0,0 +1,249 @@
+package webhook_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/RozmiDan/url_shortener/internal/storage/memory"
+	"github.com/RozmiDan/url_shortener/internal/usecase/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDispatcherDeliversSignedEvent(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	const secret = "s3cret"
+
+	var received atomic.Pointer[webhook.Event]
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		expected := webhook.Sign(secret, r.Header.Get(webhook.HeaderTimestamp), body)
+		if r.Header.Get(webhook.HeaderSignature) != expected {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var event webhook.Event
+		_ = json.Unmarshal(body, &event)
+		received.Store(&event)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	st := memory.New()
+	ctx := context.Background()
+
+	_, err := st.CreateWebhook(ctx, storage.Webhook{
+		URL:    srv.URL,
+		Secret: secret,
+		Events: []string{storage.EventLinkCreated},
+		Active: true,
+	})
+	require.NoError(t, err)
+
+	_, err = st.SaveURL(ctx, "https://example.com", "promo", storage.URLOptions{})
+	require.NoError(t, err)
+	// На переходы подписки нет, событие не должно появиться.
+	_, err = st.GetURL(ctx, "promo")
+	require.NoError(t, err)
+
+	d := webhook.New(logger, st, webhook.Config{Timeout: time.Second, MaxAttempts: 3, AllowPrivate: true})
+	require.NoError(t, d.RunOnce(ctx))
+
+	event := received.Load()
+	require.NotNil(t, event)
+	assert.Equal(t, storage.EventLinkCreated, event.Type)
+	assert.JSONEq(t, `{"alias":"promo","url":"https://example.com"}`, string(event.Data))
+
+	pending, err := st.ClaimDeliveries(ctx, time.Now(), 10, time.Minute)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestDispatcherRetriesAndDeadLetters(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+
+	var calls atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	st := memory.New()
+	ctx := context.Background()
+
+	_, err := st.CreateWebhook(ctx, storage.Webhook{URL: srv.URL, Secret: "x", Active: true})
+	require.NoError(t, err)
+	_, err = st.SaveURL(ctx, "https://example.com", "promo", storage.URLOptions{})
+	require.NoError(t, err)
+
+	// Нулевая пауза: следующая попытка доступна сразу.
+	d := webhook.New(logger, st, webhook.Config{Timeout: time.Second, MaxAttempts: 2, AllowPrivate: true})
+
+	require.NoError(t, d.RunOnce(ctx))
+	assert.Equal(t, int64(1), calls.Load())
+
+	dead, err := st.ListDeadDeliveries(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, dead)
+
+	require.NoError(t, d.RunOnce(ctx))
+	assert.Equal(t, int64(2), calls.Load())
+
+	dead, err = st.ListDeadDeliveries(ctx)
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	assert.Equal(t, 2, dead[0].Attempts)
+	assert.Equal(t, http.StatusInternalServerError, dead[0].LastStatus)
+
+	// Из dead letter доставка больше не берётся, пока её не вернули в очередь.
+	require.NoError(t, d.RunOnce(ctx))
+	assert.Equal(t, int64(2), calls.Load())
+
+	require.NoError(t, st.RetryDelivery(ctx, dead[0].ID))
+	require.NoError(t, d.RunOnce(ctx))
+	assert.Equal(t, int64(3), calls.Load())
+}
+
+func TestDispatcherRejectsPrivateAddress(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+
+	var calls atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer srv.Close()
+
+	st := memory.New()
+	ctx := context.Background()
+
+	_, err := st.CreateWebhook(ctx, storage.Webhook{URL: srv.URL, Secret: "x", Active: true})
+	require.NoError(t, err)
+	_, err = st.SaveURL(ctx, "https://example.com", "promo", storage.URLOptions{})
+	require.NoError(t, err)
+
+	d := webhook.New(logger, st, webhook.Config{Timeout: time.Second, MaxAttempts: 1})
+	require.NoError(t, d.RunOnce(ctx))
+
+	assert.Zero(t, calls.Load())
+
+	dead, err := st.ListDeadDeliveries(ctx)
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	assert.Contains(t, dead[0].LastError, "address is not public")
+}
+
+// claimRecorder запоминает, сколько доставок забирает диспетчер за раз.
+type claimRecorder struct {
+	*memory.Storage
+	limits []int
+}
+
+func (c *claimRecorder) ClaimDeliveries(ctx context.Context, dueBefore time.Time, limit int, lease time.Duration) ([]storage.Delivery, error) {
+	c.limits = append(c.limits, limit)
+	return c.Storage.ClaimDeliveries(ctx, dueBefore, limit, lease)
+}
+
+func TestDispatcherClaimsOneAtATime(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+
+	var calls atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	st := &claimRecorder{Storage: memory.New()}
+	ctx := context.Background()
+
+	_, err := st.CreateWebhook(ctx, storage.Webhook{URL: srv.URL, Secret: "x", Active: true})
+	require.NoError(t, err)
+	for _, alias := range []string{"a", "b", "c"} {
+		_, err = st.SaveURL(ctx, "https://example.com", alias, storage.URLOptions{})
+		require.NoError(t, err)
+	}
+
+	// Каждая доставка закрепляется отдельно, а повтор без паузы ждёт
+	// следующего прохода.
+	d := webhook.New(logger, st, webhook.Config{Timeout: time.Second, MaxAttempts: 5, AllowPrivate: true})
+	require.NoError(t, d.RunOnce(ctx))
+
+	assert.Equal(t, int64(3), calls.Load())
+	assert.Equal(t, []int{1, 1, 1, 1}, st.limits)
+}
+
+func TestPurgeDeliveries(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+
+	var fail atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	st := memory.New()
+	ctx := context.Background()
+
+	_, err := st.CreateWebhook(ctx, storage.Webhook{URL: srv.URL, Secret: "x", Active: true})
+	require.NoError(t, err)
+
+	d := webhook.New(logger, st, webhook.Config{Timeout: time.Second, MaxAttempts: 1, AllowPrivate: true})
+
+	_, err = st.SaveURL(ctx, "https://example.com", "delivered", storage.URLOptions{})
+	require.NoError(t, err)
+	require.NoError(t, d.RunOnce(ctx))
+
+	fail.Store(true)
+	_, err = st.SaveURL(ctx, "https://example.com", "dead", storage.URLOptions{})
+	require.NoError(t, err)
+	require.NoError(t, d.RunOnce(ctx))
+
+	// Свежие события не удаляются.
+	purged, err := st.PurgeDeliveries(ctx, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, purged)
+
+	// Доставленное удаляется, dead letter остаётся.
+	purged, err = st.PurgeDeliveries(ctx, time.Now().Add(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	dead, err := st.ListDeadDeliveries(ctx)
+	require.NoError(t, err)
+	assert.Len(t, dead, 1)
+}
+
+func TestRunZeroInterval(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	d := webhook.New(logger, memory.New(), webhook.Config{})
+	assert.NotPanics(t, func() { d.Run(ctx) })
+}
+
+func TestBackoff(t *testing.T) {
+	base, maxDelay := time.Second, 10*time.Second
+
+	assert.Equal(t, time.Second, webhook.Backoff(1, base, maxDelay))
+	assert.Equal(t, 2*time.Second, webhook.Backoff(2, base, maxDelay))
+	assert.Equal(t, 8*time.Second, webhook.Backoff(4, base, maxDelay))
+	assert.Equal(t, maxDelay, webhook.Backoff(5, base, maxDelay))
+	assert.Equal(t, maxDelay, webhook.Backoff(50, base, maxDelay))
+}
@@ -0,0 +1,272 @@
+// Package client - типизированный клиент HTTP API сокращателя ссылок.
+//
+// Идемпотентные запросы (GET, PUT, PATCH, DELETE и создание ссылки с
+// Idempotency-Key) повторяются с экспоненциальной задержкой при сетевых
+// ошибках, 429, 502, 503, 504 и ответах с Retry-After. Ошибки API
+// возвращаются как *APIError и сравниваются с ErrNotFound, ErrConflict
+// и другими через errors.Is.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// apiPrefix - путь API управления ссылками, с которым работает клиент.
+// Перенаправление по alias остаётся в корне.
+const apiPrefix = "/api/v1"
+
+// RetryPolicy - правила повтора идемпотентных запросов.
+type RetryPolicy struct {
+	// MaxAttempts - число попыток вместе с первой, 1 отключает повторы.
+	MaxAttempts int
+	// BaseBackoff - задержка перед первым повтором, дальше она удваивается
+	// до MaxBackoff.
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+}
+
+// DefaultRetryPolicy используется, если WithRetry не указан.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseBackoff: 100 * time.Millisecond,
+	MaxBackoff:  2 * time.Second,
+}
+
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	retry      RetryPolicy
+	apiKey     string
+}
+
+type Option func(c *Client)
+
+// WithHTTPClient задаёт HTTP-клиент, по умолчанию http.DefaultClient.
+// Для Resolve переходы по перенаправлениям отключаются на копии клиента.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+func WithRetry(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// WithAPIKey задаёт ключ из keys create, он нужен, если сервер
+// настроен с api.require_key.
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// New создаёт клиент для сервера по адресу baseURL, например
+// "https://sho.rt" или "http://localhost:8080/prefix".
+func New(baseURL string, opts ...Option) (*Client, error) {
+	const op = "client.New"
+
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("%s: base URL must be absolute http(s) URL, got %q", op, baseURL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	u.RawPath = ""
+	u.RawQuery = ""
+	u.Fragment = ""
+
+	c := &Client{
+		baseURL:    u,
+		httpClient: http.DefaultClient,
+		retry:      DefaultRetryPolicy,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.retry.MaxAttempts < 1 {
+		c.retry.MaxAttempts = 1
+	}
+
+	return c, nil
+}
+
+// request - описание запроса к API.
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	header http.Header
+	body   any
+
+	// idempotent разрешает повтор запроса.
+	idempotent bool
+	// noRedirect возвращает ответ 3xx вместо перехода по Location.
+	noRedirect bool
+}
+
+// response - общие поля ответов API.
+type response struct {
+	Status string `json:"status"`
+}
+
+// do выполняет запрос с повторами и декодирует тело успешного ответа в
+// формате JSON в out, если он не nil. Ответ с кодом 4xx и 5xx возвращается как *APIError.
+func (c *Client) do(ctx context.Context, req request, out any) (*http.Response, error) {
+	var body []byte
+	if req.body != nil {
+		var err error
+		body, err = json.Marshal(req.body)
+		if err != nil {
+			return nil, fmt.Errorf("encode request: %w", err)
+		}
+	}
+
+	attempts := 1
+	if req.idempotent {
+		attempts = c.retry.MaxAttempts
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := c.send(ctx, req, body)
+		if err == nil && resp.StatusCode < http.StatusBadRequest {
+			defer resp.Body.Close()
+			if out != nil && isJSON(resp) {
+				if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+					return resp, fmt.Errorf("decode response: %w", err)
+				}
+			}
+			return resp, nil
+		}
+
+		if err == nil {
+			err = newAPIError(resp)
+		}
+
+		if attempt >= attempts || !retryable(ctx, err) {
+			return resp, err
+		}
+
+		if err := sleep(ctx, c.backoff(attempt, err)); err != nil {
+			return resp, err
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, req request, body []byte) (*http.Response, error) {
+	// Сегменты req.path уже экранированы.
+	target := c.baseURL.String() + req.path
+	if len(req.query) > 0 {
+		target += "?" + req.query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range req.header {
+		httpReq.Header[key] = values
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	if c.apiKey != "" {
+		httpReq.Header.Set("X-API-Key", c.apiKey)
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+
+	httpClient := c.httpClient
+	if req.noRedirect {
+		noRedirect := *httpClient
+		noRedirect.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+		httpClient = &noRedirect
+	}
+
+	return httpClient.Do(httpReq)
+}
+
+// retryable сообщает, имеет ли смысл повторить запрос после ошибки.
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		// Сетевая ошибка: запрос мог не дойти до сервера.
+		return true
+	}
+
+	switch apiErr.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	// Например, 409 на запрос с Idempotency-Key, который ещё выполняется.
+	return apiErr.RetryAfter > 0
+}
+
+// backoff возвращает задержку перед следующей попыткой: Retry-After
+// сервера или удвоенную задержку со случайным разбросом.
+func (c *Client) backoff(attempt int, err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		return apiErr.RetryAfter
+	}
+
+	delay := c.retry.BaseBackoff << (attempt - 1)
+	if delay <= 0 || c.retry.MaxBackoff > 0 && delay > c.retry.MaxBackoff {
+		delay = c.retry.MaxBackoff
+	}
+	if delay <= 0 {
+		return 0
+	}
+
+	// Разброс, чтобы клиенты не повторяли запросы одновременно.
+	return delay/2 + rand.N(delay/2+1)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func isJSON(resp *http.Response) bool {
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return err == nil && mediaType == "application/json"
+}
+
+// escape экранирует alias или другой сегмент пути.
+func escape(segment string) string {
+	return url.PathEscape(segment)
+}
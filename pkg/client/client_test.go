@@ -0,0 +1,362 @@
+package client_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RozmiDan/url_shortener/internal/config"
+	"github.com/RozmiDan/url_shortener/internal/http-server/server"
+	"github.com/RozmiDan/url_shortener/internal/storage"
+	"github.com/RozmiDan/url_shortener/internal/storage/memory"
+	"github.com/RozmiDan/url_shortener/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newHandler(t *testing.T) http.Handler {
+	t.Helper()
+
+	cnfg := &config.Config{}
+	cnfg.HttpInfo.Timeout = 5 * time.Second
+	cnfg.Redirect.NotActiveStatus = http.StatusForbidden
+	cnfg.Idempotency.TTL = time.Hour
+	cnfg.Idempotency.LockTimeout = time.Minute
+
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	return server.InitServer(cnfg, logger, memory.New()).Handler
+}
+
+func newClient(t *testing.T, handler http.Handler) *client.Client {
+	t.Helper()
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	c, err := client.New(srv.URL, client.WithHTTPClient(srv.Client()), client.WithRetry(client.RetryPolicy{
+		MaxAttempts: 3,
+		BaseBackoff: time.Millisecond,
+		MaxBackoff:  5 * time.Millisecond,
+	}))
+	require.NoError(t, err)
+	return c
+}
+
+func TestLinks(t *testing.T) {
+	c := newClient(t, newHandler(t))
+	ctx := context.Background()
+
+	alias, err := c.CreateLink(ctx, client.Link{
+		URL:    "https://example.com",
+		Alias:  "abc",
+		Tags:   []string{"docs"},
+		Folder: "team",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "abc", alias)
+
+	_, err = c.CreateLink(ctx, client.Link{URL: "https://example.org", Alias: "taken"})
+	require.NoError(t, err)
+
+	_, err = c.CreateLink(ctx, client.Link{URL: "not a url"})
+	assert.ErrorIs(t, err, client.ErrBadRequest)
+
+	var apiErr *client.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	assert.Equal(t, "validation_failed", apiErr.Code)
+	assert.Equal(t, []client.FieldError{{Field: "url", Reason: "must be a valid URL"}}, apiErr.Fields)
+
+	link, err := c.GetLink(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", link.Link.URL)
+	assert.Equal(t, []string{"docs"}, link.Link.Tags)
+	assert.EqualValues(t, 1, link.Version)
+
+	_, err = c.GetLink(ctx, "missing")
+	assert.ErrorIs(t, err, client.ErrNotFound)
+
+	patched, err := c.PatchLink(ctx, "abc", map[string]any{"title": "Example", "folder": nil}, link.Version)
+	require.NoError(t, err)
+	assert.Equal(t, "Example", patched.Link.Title)
+	assert.Empty(t, patched.Link.Folder)
+	assert.Greater(t, patched.Version, link.Version)
+
+	_, err = c.RenameLink(ctx, "abc", "xyz", link.Version)
+	assert.ErrorIs(t, err, client.ErrPreconditionFailed)
+
+	_, err = c.RenameLink(ctx, "abc", "taken", 0)
+	assert.ErrorIs(t, err, client.ErrConflict)
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, "alias_exists", apiErr.Code)
+
+	renamed, err := c.RenameLink(ctx, "abc", "xyz", patched.Version)
+	require.NoError(t, err)
+	assert.Equal(t, "xyz", renamed.Link.Alias)
+
+	links, err := c.ListLinks(ctx, client.ListOptions{Tags: []string{"docs"}})
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	assert.Equal(t, "xyz", links[0].Alias)
+
+	res, err := c.Resolve(ctx, "xyz")
+	require.NoError(t, err)
+	assert.Equal(t, client.Resolution{URL: "https://example.com", StatusCode: http.StatusOK}, res)
+
+	err = c.DeleteLink(ctx, "xyz", link.Version)
+	assert.ErrorIs(t, err, client.ErrPreconditionFailed)
+
+	require.NoError(t, c.DeleteLink(ctx, "xyz", 0))
+
+	_, err = c.Resolve(ctx, "xyz")
+	assert.ErrorIs(t, err, client.ErrNotFound)
+}
+
+func TestResolve(t *testing.T) {
+	c := newClient(t, newHandler(t))
+	ctx := context.Background()
+
+	aliases, err := c.CreateLinks(ctx, []client.Link{
+		{URL: "https://example.com", Alias: "redirect", RedirectCode: http.StatusMovedPermanently},
+		{URL: "https://example.org", Alias: "preview", Preview: true, Title: "Example"},
+		{URL: "https://example.net", Alias: "limited", MaxClicks: 1},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"redirect", "preview", "limited"}, aliases)
+
+	res, err := c.Resolve(ctx, "redirect")
+	require.NoError(t, err)
+	assert.Equal(t, client.Resolution{URL: "https://example.com", StatusCode: http.StatusMovedPermanently}, res)
+
+	res, err = c.Resolve(ctx, "preview")
+	require.NoError(t, err)
+	assert.True(t, res.Preview)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	_, err = c.Resolve(ctx, "limited")
+	require.NoError(t, err)
+	_, err = c.Resolve(ctx, "limited")
+	assert.ErrorIs(t, err, client.ErrGone)
+}
+
+func TestVariantsAndRules(t *testing.T) {
+	c := newClient(t, newHandler(t))
+	ctx := context.Background()
+
+	_, err := c.CreateLink(ctx, client.Link{
+		URL:      "https://example.com",
+		Alias:    "ab",
+		Variants: []client.Variant{{URL: "https://a.example.com", Weight: 1}},
+	})
+	require.NoError(t, err)
+
+	_, err = c.Resolve(ctx, "ab")
+	require.NoError(t, err)
+
+	variants, err := c.ListVariants(ctx, "ab")
+	require.NoError(t, err)
+	require.Len(t, variants, 1)
+	assert.EqualValues(t, 1, variants[0].Clicks)
+
+	rule, err := c.AddRule(ctx, "ab", client.Rule{
+		Conditions: client.RuleConditions{Countries: []string{"DE"}},
+		TargetURL:  "https://example.de",
+	})
+	require.NoError(t, err)
+	assert.NotZero(t, rule.ID)
+
+	rule.TargetURL = "https://example.de/new"
+	_, err = c.UpdateRule(ctx, "ab", rule)
+	require.NoError(t, err)
+
+	rules, err := c.ListRules(ctx, "ab")
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	assert.Equal(t, "https://example.de/new", rules[0].TargetURL)
+
+	require.NoError(t, c.DeleteRule(ctx, "ab", rule.ID))
+	assert.ErrorIs(t, c.DeleteRule(ctx, "ab", rule.ID), client.ErrNotFound)
+
+	rules, err = c.ReplaceRules(ctx, "ab", []client.Rule{
+		{Conditions: client.RuleConditions{OS: []string{"ios"}}, TargetURL: "https://apps.example.com"},
+	})
+	require.NoError(t, err)
+	assert.Len(t, rules, 1)
+}
+
+func TestTagsAndWebhooks(t *testing.T) {
+	c := newClient(t, newHandler(t))
+	ctx := context.Background()
+
+	_, err := c.CreateLinks(ctx, []client.Link{
+		{URL: "https://example.com", Tags: []string{"a"}},
+		{URL: "https://example.org", Tags: []string{"b"}},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, c.RenameTag(ctx, "a", "c"))
+	require.NoError(t, c.MergeTags(ctx, []string{"b"}, "c"))
+
+	tags, err := c.ListTags(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []client.Tag{{Name: "c", Links: 2}}, tags)
+
+	webhook, err := c.CreateWebhook(ctx, client.WebhookRequest{
+		URL:    "https://hooks.example.com",
+		Events: []string{"link.created"},
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, webhook.Secret)
+
+	inactive := false
+	updated, err := c.UpdateWebhook(ctx, webhook.ID, client.WebhookRequest{
+		URL:    "https://hooks.example.com/v2",
+		Events: []string{"link.created"},
+		Active: &inactive,
+	})
+	require.NoError(t, err)
+	assert.False(t, updated.Active)
+	assert.Empty(t, updated.Secret)
+
+	webhooks, err := c.ListWebhooks(ctx)
+	require.NoError(t, err)
+	assert.Len(t, webhooks, 1)
+
+	require.NoError(t, c.DeleteWebhook(ctx, webhook.ID))
+	_, err = c.GetWebhook(ctx, webhook.ID)
+	assert.ErrorIs(t, err, client.ErrNotFound)
+
+	deliveries, err := c.ListDeadLetters(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, deliveries)
+
+	assert.ErrorIs(t, c.RetryDelivery(ctx, 1), client.ErrNotFound)
+}
+
+// flaky отвечает 503 на первые failures запросов.
+func flaky(next http.Handler, failures int32) (http.Handler, *atomic.Int32) {
+	var calls atomic.Int32
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}), &calls
+}
+
+func TestRetry(t *testing.T) {
+	ctx := context.Background()
+
+	handler, calls := flaky(newHandler(t), 2)
+	c := newClient(t, handler)
+
+	// Создание с Idempotency-Key повторяется.
+	alias, err := c.CreateLink(ctx, client.Link{URL: "https://example.com", Alias: "abc"})
+	require.NoError(t, err)
+	assert.Equal(t, "abc", alias)
+	assert.EqualValues(t, 3, calls.Load())
+
+	// Resolve не повторяется: переход мог быть уже засчитан.
+	handler, calls = flaky(newHandler(t), 1)
+	c = newClient(t, handler)
+
+	_, err = c.Resolve(ctx, "abc")
+	assert.ErrorIs(t, err, client.ErrUnavailable)
+	assert.EqualValues(t, 1, calls.Load())
+
+	// Попытки заканчиваются.
+	handler, calls = flaky(newHandler(t), 10)
+	c = newClient(t, handler)
+
+	_, err = c.ListLinks(ctx, client.ListOptions{})
+	assert.ErrorIs(t, err, client.ErrUnavailable)
+	assert.EqualValues(t, 3, calls.Load())
+}
+
+func TestRetryContext(t *testing.T) {
+	handler, calls := flaky(newHandler(t), 10)
+
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	c, err := client.New(srv.URL, client.WithRetry(client.RetryPolicy{
+		MaxAttempts: 10,
+		BaseBackoff: time.Hour,
+		MaxBackoff:  time.Hour,
+	}))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = c.ListTags(ctx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), err)
+	assert.EqualValues(t, 1, calls.Load())
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	c := newClient(t, newHandler(t))
+	ctx := context.Background()
+
+	link := client.Link{URL: "https://example.com"}
+
+	first, err := c.CreateLinkWithKey(ctx, link, "key-1")
+	require.NoError(t, err)
+
+	second, err := c.CreateLinkWithKey(ctx, link, "key-1")
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+
+	_, err = c.CreateLinkWithKey(ctx, client.Link{URL: "https://example.org"}, "key-1")
+	assert.ErrorIs(t, err, client.ErrIdempotencyKeyReused)
+}
+
+func TestAPIKey(t *testing.T) {
+	ctx := context.Background()
+	st := memory.New()
+
+	key, apiKey, err := storage.NewAPIKey("tests")
+	require.NoError(t, err)
+	_, err = st.CreateAPIKey(ctx, apiKey)
+	require.NoError(t, err)
+
+	cnfg := &config.Config{}
+	cnfg.HttpInfo.Timeout = 5 * time.Second
+	cnfg.API.RequireKey = true
+
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	srv := httptest.NewServer(server.InitServer(cnfg, logger, st).Handler)
+	t.Cleanup(srv.Close)
+
+	anonymous, err := client.New(srv.URL, client.WithHTTPClient(srv.Client()))
+	require.NoError(t, err)
+	_, err = anonymous.CreateLink(ctx, client.Link{URL: "https://example.com", Alias: "abc"})
+	assert.ErrorIs(t, err, client.ErrUnauthorized)
+
+	c, err := client.New(srv.URL, client.WithHTTPClient(srv.Client()), client.WithAPIKey(key))
+	require.NoError(t, err)
+	_, err = c.CreateLink(ctx, client.Link{URL: "https://example.com", Alias: "abc"})
+	require.NoError(t, err)
+
+	// Переход по ссылке ключа не требует.
+	res, err := anonymous.Resolve(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", res.URL)
+}
+
+func TestNew(t *testing.T) {
+	for _, baseURL := range []string{"", "localhost:8080", "ftp://example.com", "http://"} {
+		_, err := client.New(baseURL)
+		assert.Error(t, err, baseURL)
+	}
+
+	_, err := client.New("https://example.com/prefix/")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,134 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Ошибки, с которыми сравнивается *APIError через errors.Is.
+var (
+	ErrBadRequest = errors.New("bad request")
+	// ErrUnauthorized - сервер требует ключ API, а он не задан или отозван.
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrNotFound     = errors.New("not found")
+	// ErrConflict - alias уже занят или запрос с тем же Idempotency-Key
+	// ещё выполняется.
+	ErrConflict = errors.New("conflict")
+	// ErrGone - ссылка истекла или исчерпала лимит переходов.
+	ErrGone = errors.New("gone")
+	// ErrPreconditionFailed - ссылку изменили после чтения указанной версии.
+	ErrPreconditionFailed = errors.New("precondition failed")
+	// ErrIdempotencyKeyReused - Idempotency-Key уже использован с другим телом.
+	ErrIdempotencyKeyReused = errors.New("idempotency key reused")
+	ErrUnavailable          = errors.New("service unavailable")
+	ErrTimeout              = errors.New("timeout")
+)
+
+// maxErrorBody - сколько байт тела ответа с ошибкой читать.
+const maxErrorBody = 64 << 10
+
+// APIError - ответ API с кодом 4xx или 5xx.
+type APIError struct {
+	StatusCode int
+	// Code - машиночитаемый код ошибки, например "alias_exists". Пустой,
+	// если тело ответа не application/problem+json.
+	Code string
+	// Message - поле detail ответа, title или текст статуса.
+	Message string
+	// Fields - поля запроса, не прошедшие проверку.
+	Fields []FieldError
+	// RetryAfter - значение заголовка Retry-After, 0 - заголовка нет.
+	RetryAfter time.Duration
+}
+
+// FieldError - поле запроса, не прошедшее проверку.
+type FieldError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+// problem - тело ответа с ошибкой (RFC 7807).
+type problem struct {
+	Title  string       `json:"title"`
+	Detail string       `json:"detail"`
+	Code   string       `json:"code"`
+	Errors []FieldError `json:"errors"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("url_shortener: %d %s", e.StatusCode, e.Message)
+}
+
+func (e *APIError) Is(target error) bool {
+	switch e.StatusCode {
+	case http.StatusBadRequest:
+		return target == ErrBadRequest
+	case http.StatusUnauthorized:
+		return target == ErrUnauthorized
+	case http.StatusNotFound:
+		return target == ErrNotFound
+	case http.StatusConflict:
+		return target == ErrConflict
+	case http.StatusGone:
+		return target == ErrGone
+	case http.StatusPreconditionFailed:
+		return target == ErrPreconditionFailed
+	case http.StatusUnprocessableEntity:
+		return target == ErrIdempotencyKeyReused
+	case http.StatusServiceUnavailable:
+		return target == ErrUnavailable
+	case http.StatusGatewayTimeout:
+		return target == ErrTimeout
+	}
+	return false
+}
+
+// newAPIError читает и закрывает тело ответа.
+func newAPIError(resp *http.Response) *APIError {
+	defer resp.Body.Close()
+
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Message:    http.StatusText(resp.StatusCode),
+		RetryAfter: retryAfter(resp.Header.Get("Retry-After")),
+	}
+
+	var body problem
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	if json.Unmarshal(raw, &body) == nil {
+		apiErr.Code = body.Code
+		apiErr.Fields = body.Errors
+		switch {
+		case body.Detail != "":
+			apiErr.Message = body.Detail
+		case body.Title != "":
+			apiErr.Message = body.Title
+		}
+	}
+
+	return apiErr
+}
+
+// retryAfter разбирает Retry-After в секундах или в виде даты.
+func retryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if at, err := http.ParseTime(value); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d
+		}
+	}
+
+	return 0
+}
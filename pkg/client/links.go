@@ -0,0 +1,251 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// HeaderIdempotencyKey - заголовок, с которым повтор POST /url не создаёт
+// вторую ссылку.
+const HeaderIdempotencyKey = "Idempotency-Key"
+
+type createResponse struct {
+	response
+	Alias string `json:"alias"`
+}
+
+type linkResponse struct {
+	response
+	Link    *Link `json:"link"`
+	Version int64 `json:"version"`
+}
+
+type listResponse struct {
+	response
+	Links []LinkInfo `json:"links"`
+}
+
+type brokenResponse struct {
+	response
+	Links []BrokenLink `json:"links"`
+}
+
+type variantsResponse struct {
+	response
+	Variants []Variant `json:"variants"`
+}
+
+type resolveResponse struct {
+	response
+	URL string `json:"url"`
+}
+
+// CreateLink создаёт ссылку и возвращает её alias. Запрос отправляется со
+// случайным Idempotency-Key, поэтому его повтор после сетевой ошибки не
+// создаст вторую ссылку.
+func (c *Client) CreateLink(ctx context.Context, link Link) (string, error) {
+	key, err := newIdempotencyKey()
+	if err != nil {
+		return "", err
+	}
+	return c.CreateLinkWithKey(ctx, link, key)
+}
+
+// CreateLinkWithKey создаёт ссылку с заданным Idempotency-Key. Повтор с тем
+// же ключом и телом вернёт тот же alias, с другим телом - ErrIdempotencyKeyReused.
+// С пустым ключом запрос отправляется без заголовка и не повторяется.
+func (c *Client) CreateLinkWithKey(ctx context.Context, link Link, key string) (string, error) {
+	req := request{
+		method: http.MethodPost,
+		path:   apiPrefix + "/url",
+		body:   link,
+	}
+	if key != "" {
+		req.header = http.Header{HeaderIdempotencyKey: {key}}
+		req.idempotent = true
+	}
+
+	var resp createResponse
+	_, err := c.do(ctx, req, &resp)
+	if err != nil {
+		return "", err
+	}
+	return resp.Alias, nil
+}
+
+// CreateLinks создаёт ссылки по одной: у API нет пакетного создания.
+// При ошибке возвращаются alias уже созданных ссылок, созданные ссылки
+// не удаляются.
+func (c *Client) CreateLinks(ctx context.Context, links []Link) ([]string, error) {
+	aliases := make([]string, 0, len(links))
+	for i, link := range links {
+		alias, err := c.CreateLink(ctx, link)
+		if err != nil {
+			return aliases, fmt.Errorf("link %d: %w", i, err)
+		}
+		aliases = append(aliases, alias)
+	}
+	return aliases, nil
+}
+
+// GetLink возвращает ссылку и её версию. Варианты A/B-теста читаются
+// через ListVariants.
+func (c *Client) GetLink(ctx context.Context, alias string) (VersionedLink, error) {
+	return c.link(ctx, request{
+		method:     http.MethodGet,
+		path:       apiPrefix + "/url/" + escape(alias),
+		idempotent: true,
+	})
+}
+
+// PatchLink применяет JSON Merge Patch к ссылке: поле со значением nil
+// удаляется. Ненулевой expectedVersion отправляется в If-Match, и при
+// изменении ссылки после чтения этой версии возвращается
+// ErrPreconditionFailed.
+func (c *Client) PatchLink(ctx context.Context, alias string, patch map[string]any, expectedVersion int64) (VersionedLink, error) {
+	return c.link(ctx, request{
+		method:     http.MethodPatch,
+		path:       apiPrefix + "/url/" + escape(alias),
+		header:     ifMatch(expectedVersion),
+		body:       patch,
+		idempotent: true,
+	})
+}
+
+// RenameLink меняет alias ссылки.
+func (c *Client) RenameLink(ctx context.Context, alias, newAlias string, expectedVersion int64) (VersionedLink, error) {
+	return c.PatchLink(ctx, alias, map[string]any{"alias": newAlias}, expectedVersion)
+}
+
+func (c *Client) link(ctx context.Context, req request) (VersionedLink, error) {
+	var resp linkResponse
+	if _, err := c.do(ctx, req, &resp); err != nil {
+		return VersionedLink{}, err
+	}
+	if resp.Link == nil {
+		return VersionedLink{}, errors.New("response has no link")
+	}
+	return VersionedLink{Link: *resp.Link, Version: resp.Version}, nil
+}
+
+// DeleteLink удаляет ссылку. Ненулевой expectedVersion работает так же,
+// как в PatchLink.
+func (c *Client) DeleteLink(ctx context.Context, alias string, expectedVersion int64) error {
+	_, err := c.do(ctx, request{
+		method:     http.MethodDelete,
+		path:       apiPrefix + "/url/" + escape(alias),
+		header:     ifMatch(expectedVersion),
+		idempotent: true,
+	}, nil)
+	return err
+}
+
+func (c *Client) ListLinks(ctx context.Context, opts ListOptions) ([]LinkInfo, error) {
+	query := url.Values{}
+	for _, tag := range opts.Tags {
+		query.Add("tag", tag)
+	}
+	if opts.Folder != "" {
+		query.Set("folder", opts.Folder)
+	}
+	if opts.Query != "" {
+		query.Set("q", opts.Query)
+	}
+	for key, value := range opts.Metadata {
+		query.Set("meta."+key, value)
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if opts.Offset > 0 {
+		query.Set("offset", strconv.Itoa(opts.Offset))
+	}
+
+	var resp listResponse
+	_, err := c.do(ctx, request{
+		method:     http.MethodGet,
+		path:       apiPrefix + "/url",
+		query:      query,
+		idempotent: true,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Links, nil
+}
+
+// ListBroken возвращает ссылки, адрес которых не прошёл проверку доступности.
+func (c *Client) ListBroken(ctx context.Context) ([]BrokenLink, error) {
+	var resp brokenResponse
+	_, err := c.do(ctx, request{
+		method:     http.MethodGet,
+		path:       apiPrefix + "/url/broken",
+		idempotent: true,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Links, nil
+}
+
+// ListVariants возвращает варианты A/B-теста ссылки с числом переходов
+// на каждый.
+func (c *Client) ListVariants(ctx context.Context, alias string) ([]Variant, error) {
+	var resp variantsResponse
+	_, err := c.do(ctx, request{
+		method:     http.MethodGet,
+		path:       apiPrefix + "/url/" + escape(alias) + "/variants",
+		idempotent: true,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Variants, nil
+}
+
+// Resolve переходит по короткой ссылке так же, как браузер, но не следует
+// перенаправлению. Переход учитывается в лимите и статистике ссылки,
+// поэтому запрос не повторяется.
+func (c *Client) Resolve(ctx context.Context, alias string) (Resolution, error) {
+	var resp resolveResponse
+	httpResp, err := c.do(ctx, request{
+		method:     http.MethodGet,
+		path:       "/" + escape(alias),
+		noRedirect: true,
+	}, &resp)
+	if err != nil {
+		return Resolution{}, err
+	}
+
+	res := Resolution{StatusCode: httpResp.StatusCode}
+	switch {
+	case httpResp.StatusCode >= http.StatusMultipleChoices:
+		res.URL = httpResp.Header.Get("Location")
+	case isJSON(httpResp):
+		res.URL = resp.URL
+	default:
+		res.Preview = true
+	}
+	return res, nil
+}
+
+func ifMatch(expectedVersion int64) http.Header {
+	if expectedVersion == 0 {
+		return nil
+	}
+	return http.Header{"If-Match": {`"` + strconv.FormatInt(expectedVersion, 10) + `"`}}
+}
+
+func newIdempotencyKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate idempotency key: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
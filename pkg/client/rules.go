@@ -0,0 +1,88 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+type ruleResponse struct {
+	response
+	Rule *Rule `json:"rule"`
+}
+
+type rulesResponse struct {
+	response
+	Rules []Rule `json:"rules"`
+}
+
+// ListRules возвращает правила умного перехода ссылки в порядке проверки.
+func (c *Client) ListRules(ctx context.Context, alias string) ([]Rule, error) {
+	return c.rules(ctx, request{
+		method:     http.MethodGet,
+		path:       apiPrefix + "/url/" + escape(alias) + "/rules",
+		idempotent: true,
+	})
+}
+
+// AddRule добавляет правило в конец списка. POST не повторяется, чтобы
+// не добавить правило дважды.
+func (c *Client) AddRule(ctx context.Context, alias string, rule Rule) (Rule, error) {
+	return c.rule(ctx, request{
+		method: http.MethodPost,
+		path:   apiPrefix + "/url/" + escape(alias) + "/rules",
+		body:   rule,
+	})
+}
+
+// ReplaceRules заменяет все правила ссылки, пустой список удаляет их.
+func (c *Client) ReplaceRules(ctx context.Context, alias string, rules []Rule) ([]Rule, error) {
+	if rules == nil {
+		rules = []Rule{}
+	}
+	return c.rules(ctx, request{
+		method:     http.MethodPut,
+		path:       apiPrefix + "/url/" + escape(alias) + "/rules",
+		body:       map[string][]Rule{"rules": rules},
+		idempotent: true,
+	})
+}
+
+// UpdateRule заменяет условия и адрес правила rule.ID.
+func (c *Client) UpdateRule(ctx context.Context, alias string, rule Rule) (Rule, error) {
+	return c.rule(ctx, request{
+		method:     http.MethodPut,
+		path:       apiPrefix + "/url/" + escape(alias) + "/rules/" + strconv.FormatInt(rule.ID, 10),
+		body:       rule,
+		idempotent: true,
+	})
+}
+
+func (c *Client) DeleteRule(ctx context.Context, alias string, ruleID int64) error {
+	_, err := c.do(ctx, request{
+		method:     http.MethodDelete,
+		path:       apiPrefix + "/url/" + escape(alias) + "/rules/" + strconv.FormatInt(ruleID, 10),
+		idempotent: true,
+	}, nil)
+	return err
+}
+
+func (c *Client) rule(ctx context.Context, req request) (Rule, error) {
+	var resp ruleResponse
+	if _, err := c.do(ctx, req, &resp); err != nil {
+		return Rule{}, err
+	}
+	if resp.Rule == nil {
+		return Rule{}, errors.New("response has no rule")
+	}
+	return *resp.Rule, nil
+}
+
+func (c *Client) rules(ctx context.Context, req request) ([]Rule, error) {
+	var resp rulesResponse
+	if _, err := c.do(ctx, req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Rules, nil
+}
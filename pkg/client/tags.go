@@ -0,0 +1,48 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+type tagsResponse struct {
+	response
+	Tags []Tag `json:"tags"`
+}
+
+// ListTags возвращает все теги с числом ссылок на каждом.
+func (c *Client) ListTags(ctx context.Context) ([]Tag, error) {
+	var resp tagsResponse
+	_, err := c.do(ctx, request{
+		method:     http.MethodGet,
+		path:       apiPrefix + "/tags",
+		idempotent: true,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Tags, nil
+}
+
+// RenameTag переименовывает тег на всех ссылках.
+func (c *Client) RenameTag(ctx context.Context, name, newName string) error {
+	_, err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   apiPrefix + "/tags/" + escape(name) + "/rename",
+		body:   map[string]string{"name": newName},
+	}, nil)
+	return err
+}
+
+// MergeTags заменяет теги sources на target на всех ссылках.
+func (c *Client) MergeTags(ctx context.Context, sources []string, target string) error {
+	_, err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   apiPrefix + "/tags/merge",
+		body: map[string]any{
+			"sources": sources,
+			"target":  target,
+		},
+	}, nil)
+	return err
+}
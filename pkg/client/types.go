@@ -0,0 +1,156 @@
+package client
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Link - ссылка в том виде, в котором её создаёт POST /url и возвращает
+// GET /url/{alias}.
+type Link struct {
+	URL       string `json:"url"`
+	Alias     string `json:"alias,omitempty"`
+	MaxClicks int64  `json:"max_clicks,omitempty"`
+
+	ActiveFrom  *time.Time `json:"active_from,omitempty"`
+	ActiveUntil *time.Time `json:"active_until,omitempty"`
+
+	Variants []Variant `json:"variants,omitempty"`
+
+	// QueryMode: drop, merge или override.
+	QueryMode   string `json:"query_mode,omitempty"`
+	UTM         *UTM   `json:"utm,omitempty"`
+	ForwardPath bool   `json:"forward_path,omitempty"`
+
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Image       string `json:"image,omitempty"`
+	Preview     bool   `json:"preview,omitempty"`
+
+	Tags     []string       `json:"tags,omitempty"`
+	Folder   string         `json:"folder,omitempty"`
+	Metadata map[string]any `json:"metadata,omitempty"`
+
+	// RedirectCode: 301, 302, 307 или 308, 0 - адрес возвращается в JSON.
+	RedirectCode int `json:"redirect_code,omitempty"`
+}
+
+type UTM struct {
+	Source   string `json:"source,omitempty"`
+	Medium   string `json:"medium,omitempty"`
+	Campaign string `json:"campaign,omitempty"`
+	Term     string `json:"term,omitempty"`
+	Content  string `json:"content,omitempty"`
+}
+
+// Variant - вариант A/B-теста. ID и Clicks заполняет сервер.
+type Variant struct {
+	ID     int64  `json:"id,omitempty"`
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+	Clicks int64  `json:"clicks,omitempty"`
+}
+
+// VersionedLink - ссылка и её версия для условных изменений.
+type VersionedLink struct {
+	Link    Link
+	Version int64
+}
+
+// LinkInfo - ссылка в списке.
+type LinkInfo struct {
+	Alias     string         `json:"alias"`
+	URL       string         `json:"url"`
+	Tags      []string       `json:"tags"`
+	Folder    string         `json:"folder,omitempty"`
+	Metadata  map[string]any `json:"metadata,omitempty"`
+	Version   int64          `json:"version"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// ListOptions - фильтры списка ссылок, пустое поле не ограничивает выборку.
+// Без Limit сервер возвращает не больше 50 ссылок.
+type ListOptions struct {
+	Tags     []string
+	Folder   string
+	Query    string
+	Metadata map[string]string
+	Limit    int
+	Offset   int
+}
+
+// Resolution - результат перехода по короткой ссылке.
+type Resolution struct {
+	// URL - адрес, на который ведёт ссылка. Пустой, если сервер вернул
+	// страницу предпросмотра.
+	URL string
+	// StatusCode - 200 для ответа в JSON и страницы предпросмотра или код
+	// перенаправления.
+	StatusCode int
+	Preview    bool
+}
+
+type Rule struct {
+	ID         int64          `json:"id,omitempty"`
+	Conditions RuleConditions `json:"conditions"`
+	TargetURL  string         `json:"target_url"`
+}
+
+type RuleConditions struct {
+	Devices   []string          `json:"devices,omitempty"`
+	OS        []string          `json:"os,omitempty"`
+	Languages []string          `json:"languages,omitempty"`
+	Countries []string          `json:"countries,omitempty"`
+	Query     map[string]string `json:"query,omitempty"`
+	// TimeFrom и TimeTo - ежедневный интервал в формате "15:04" по UTC.
+	TimeFrom string `json:"time_from,omitempty"`
+	TimeTo   string `json:"time_to,omitempty"`
+}
+
+type Tag struct {
+	Name  string `json:"name"`
+	Links int64  `json:"links"`
+}
+
+type BrokenLink struct {
+	Alias      string    `json:"alias"`
+	URL        string    `json:"url"`
+	LastStatus int       `json:"last_status"`
+	LastError  string    `json:"last_error,omitempty"`
+	LatencyMs  int64     `json:"latency_ms"`
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+// Webhook - подписка на события. Secret возвращается только при создании.
+type Webhook struct {
+	ID        int64     `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	Secret    string    `json:"-"`
+}
+
+// WebhookRequest - создание или замена подписки. Пустой Secret при
+// создании - сервер сгенерирует его сам, nil Active - подписка активна.
+type WebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret,omitempty"`
+	Active *bool    `json:"active,omitempty"`
+}
+
+type Delivery struct {
+	ID         int64           `json:"id"`
+	WebhookID  int64           `json:"webhook_id"`
+	EventID    int64           `json:"event_id"`
+	EventType  string          `json:"event_type"`
+	Payload    json.RawMessage `json:"payload"`
+	URL        string          `json:"url"`
+	State      string          `json:"state"`
+	Attempts   int             `json:"attempts"`
+	LastStatus int             `json:"last_status,omitempty"`
+	LastError  string          `json:"last_error,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
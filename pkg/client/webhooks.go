@@ -0,0 +1,110 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+type webhookResponse struct {
+	response
+	Webhook *Webhook `json:"webhook"`
+	Secret  string   `json:"secret"`
+}
+
+type webhooksResponse struct {
+	response
+	Webhooks []Webhook `json:"webhooks"`
+}
+
+type deliveriesResponse struct {
+	response
+	Deliveries []Delivery `json:"deliveries"`
+}
+
+// CreateWebhook подписывает адрес на события. Нужно указать хотя бы
+// одно событие. Secret для проверки подписи возвращается только здесь.
+func (c *Client) CreateWebhook(ctx context.Context, webhook WebhookRequest) (Webhook, error) {
+	return c.webhook(ctx, request{
+		method: http.MethodPost,
+		path:   apiPrefix + "/webhooks",
+		body:   webhook,
+	})
+}
+
+func (c *Client) ListWebhooks(ctx context.Context) ([]Webhook, error) {
+	var resp webhooksResponse
+	_, err := c.do(ctx, request{
+		method:     http.MethodGet,
+		path:       apiPrefix + "/webhooks",
+		idempotent: true,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Webhooks, nil
+}
+
+func (c *Client) GetWebhook(ctx context.Context, id int64) (Webhook, error) {
+	return c.webhook(ctx, request{
+		method:     http.MethodGet,
+		path:       apiPrefix + "/webhooks/" + strconv.FormatInt(id, 10),
+		idempotent: true,
+	})
+}
+
+// UpdateWebhook заменяет подписку целиком. Пустой Secret оставляет прежний.
+func (c *Client) UpdateWebhook(ctx context.Context, id int64, webhook WebhookRequest) (Webhook, error) {
+	return c.webhook(ctx, request{
+		method:     http.MethodPut,
+		path:       apiPrefix + "/webhooks/" + strconv.FormatInt(id, 10),
+		body:       webhook,
+		idempotent: true,
+	})
+}
+
+func (c *Client) DeleteWebhook(ctx context.Context, id int64) error {
+	_, err := c.do(ctx, request{
+		method:     http.MethodDelete,
+		path:       apiPrefix + "/webhooks/" + strconv.FormatInt(id, 10),
+		idempotent: true,
+	}, nil)
+	return err
+}
+
+// ListDeadLetters возвращает доставки, исчерпавшие попытки.
+func (c *Client) ListDeadLetters(ctx context.Context) ([]Delivery, error) {
+	var resp deliveriesResponse
+	_, err := c.do(ctx, request{
+		method:     http.MethodGet,
+		path:       apiPrefix + "/webhooks/dead-letters",
+		idempotent: true,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Deliveries, nil
+}
+
+// RetryDelivery возвращает доставку из dead letters в очередь.
+func (c *Client) RetryDelivery(ctx context.Context, id int64) error {
+	_, err := c.do(ctx, request{
+		method: http.MethodPost,
+		path:   apiPrefix + "/webhooks/dead-letters/" + strconv.FormatInt(id, 10) + "/retry",
+	}, nil)
+	return err
+}
+
+func (c *Client) webhook(ctx context.Context, req request) (Webhook, error) {
+	var resp webhookResponse
+	if _, err := c.do(ctx, req, &resp); err != nil {
+		return Webhook{}, err
+	}
+	if resp.Webhook == nil {
+		return Webhook{}, errors.New("response has no webhook")
+	}
+	webhook := *resp.Webhook
+	webhook.Secret = resp.Secret
+	return webhook, nil
+}
@@ -1,33 +1,85 @@
 package tests
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
 	"net/url"
 	"testing"
+	"time"
 
+	"github.com/RozmiDan/url_shortener/internal/app"
+	"github.com/RozmiDan/url_shortener/internal/config"
 	save_handler "github.com/RozmiDan/url_shortener/internal/http-server/handlers/save"
+	"github.com/RozmiDan/url_shortener/internal/storage/memory"
 	"github.com/RozmiDan/url_shortener/internal/usecase/random"
 	"github.com/brianvoe/gofakeit/v6"
 	"github.com/gavv/httpexpect/v2"
 	"github.com/stretchr/testify/require"
 )
 
-func Test_HappyPath(t *testing.T) {
-	u := url.URL{
-		Host:   "localhost:8080",
-		Scheme: "http",
+// startApp запускает сервис с хранилищем в памяти на свободных портах и
+// возвращает адреса публичного и служебного серверов.
+func startApp(t *testing.T) (public, admin url.URL) {
+	t.Helper()
+
+	listen := func() net.Listener {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		return ln
 	}
+	ln, adminLn := listen(), listen()
+
+	cnfg := &config.Config{Env: "local"}
+	cnfg.HttpInfo.Timeout = 5 * time.Second
+	cnfg.HttpInfo.IdleTimeout = 10 * time.Second
+	cnfg.Admin.Timeout = 5 * time.Second
+	cnfg.Health.CheckTimeout = time.Second
+
+	a, err := app.New(context.Background(), cnfg, app.Options{
+		Logger:        slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)),
+		Storage:       memory.New(),
+		Listener:      ln,
+		AdminListener: adminLn,
+	})
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		// Соединения клиентов тестов без запроса сервер считает новыми и
+		// при остановке ждёт их до 5 секунд, поэтому закрываем их заранее,
+		// а дедлайн остановки берём с запасом.
+		http.DefaultTransport.(*http.Transport).CloseIdleConnections()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+		require.NoError(t, a.Stop(ctx))
+	})
+	require.NoError(t, a.Start(context.Background()))
+
+	return url.URL{Scheme: "http", Host: ln.Addr().String()},
+		url.URL{Scheme: "http", Host: adminLn.Addr().String()}
+}
+
+func Test_HappyPath(t *testing.T) {
+	u, admin := startApp(t)
 
 	e := httpexpect.Default(t, u.String())
 
-	e.POST("/url").WithJSON(save_handler.Request{
+	e.POST("/api/v1/url").WithJSON(save_handler.Request{
 		URL:   gofakeit.URL(),
 		Alias: random.NewAliasForURL(8),
-	}).Expect().Status(200).JSON().Object().ContainsKey("alias")
+	}).Expect().Status(http.StatusCreated).JSON().Object().ContainsKey("alias")
+
+	httpexpect.Default(t, admin.String()).GET("/readyz").
+		Expect().Status(http.StatusOK).JSON().Object().Value("status").IsEqual("ok")
 }
 
 func Test_Save_Redirect(t *testing.T) {
+	u, _ := startApp(t)
+
 	testCases := []struct {
 		name  string
 		url   string
@@ -37,12 +89,12 @@ func Test_Save_Redirect(t *testing.T) {
 		{
 			name:  "valid test",
 			url:   gofakeit.URL(),
-			alias: gofakeit.Word(),
+			alias: random.NewAliasForURL(10),
 		},
 		{
 			name:  "invalid test",
 			url:   "not-url",
-			alias: gofakeit.Word(),
+			alias: random.NewAliasForURL(10),
 			error: "invalid request parameters",
 		},
 		{
@@ -53,23 +105,25 @@ func Test_Save_Redirect(t *testing.T) {
 
 	for _, tCase := range testCases {
 		t.Run(tCase.name, func(t *testing.T) {
-			u := url.URL{
-				Host:   "localhost:8080",
-				Scheme: "http",
-			}
 			e := httpexpect.Default(t, u.String())
 
-			response := e.POST("/url").WithJSON(save_handler.Request{
-				URL:   tCase.url,
-				Alias: tCase.alias,
-			}).Expect().Status(200).JSON().Object()
+			// Без redirect_code сервер отвечает 200 с адресом в JSON.
+			req := e.POST("/api/v1/url").WithJSON(save_handler.Request{
+				URL:          tCase.url,
+				Alias:        tCase.alias,
+				RedirectCode: http.StatusFound,
+			})
 
 			if tCase.error != "" {
+				response := req.Expect().Status(http.StatusBadRequest).
+					JSON(httpexpect.ContentOpts{MediaType: "application/problem+json"}).Object()
 				response.NotContainsKey("alias")
-				response.Value("error").String().IsEqual(tCase.error)
+				response.Value("detail").String().IsEqual(tCase.error)
 				return
 			}
 
+			response := req.Expect().Status(http.StatusCreated).JSON().Object()
+
 			alias := tCase.alias
 
 			if alias != "" {
@@ -79,18 +133,14 @@ func Test_Save_Redirect(t *testing.T) {
 				alias = response.Value("alias").String().Raw()
 			}
 
-			testRedirect(t, alias, tCase.url)
+			testRedirect(t, u, alias, tCase.url)
 
 		})
 	}
 }
 
-func testRedirect(t *testing.T, alias string, urlToRedirect string) {
-	u := url.URL{
-		Host:   "localhost:8080",
-		Scheme: "http",
-		Path:   alias,
-	}
+func testRedirect(t *testing.T, u url.URL, alias string, urlToRedirect string) {
+	u.Path = alias
 
 	redirToURL, err := GetRedirect(u.String())
 
@@ -109,17 +159,17 @@ func GetRedirect(url string) (string, error) {
 
 	resp, err := client.Get(url)
 	if err != nil {
-		return "", nil
-	}
-
-	if resp.StatusCode != http.StatusFound {
-		return "", fmt.Errorf("%s", "test.GetRedirect: invalid status code")
+		return "", err
 	}
 
 	defer func() {
 		_ = resp.Body.Close()
 	}()
 
+	if resp.StatusCode != http.StatusFound {
+		return "", fmt.Errorf("test.GetRedirect: invalid status code %d", resp.StatusCode)
+	}
+
 	return resp.Header.Get("Location"), nil
 
 }